@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,9 +21,11 @@ import (
 	"ragtime-backend/internal/embedding"
 	"ragtime-backend/internal/logger"
 	"ragtime-backend/internal/objectstore"
+	"ragtime-backend/internal/retrieval"
 	retrievalcache "ragtime-backend/internal/retrieval/cache"
 	retrievalhttp "ragtime-backend/internal/retrieval/http"
 	retrievalrepo "ragtime-backend/internal/retrieval/repository"
+	"ragtime-backend/internal/retrieval/rerank"
 	retrievalservice "ragtime-backend/internal/retrieval/service"
 	"ragtime-backend/internal/storage"
 	"ragtime-backend/internal/telemetry"
@@ -61,9 +64,38 @@ func main() {
 	router.Post("/v1/kb/{kbID}/documents/{documentID}/chunking", chunkingHandler.InitiateDocumentChunking)
 	router.Post("/v1/kb/{kbID}/chunks/{chunkID}/embed", chunkingHandler.EmbedChunkByID)
 	router.Post("/v1/kb/{kbID}/query", retrievalHandler.Query)
+	router.Post("/v1/kb/{kbID}/query:batch", retrievalHandler.QueryBatch)
+	router.Post("/v1/kb/{kbID}/query/explain", retrievalHandler.Explain)
 	router.Post("/v1/kb/{kbID}/hydrate", retrievalHandler.Hydrate)
+	router.Post("/v1/kb/{kbID}/context", retrievalHandler.Context)
 	router.Post("/v1/kb/{kbID}/retrieve", retrievalHandler.Retrieve)
+	router.Post("/v1/kb/{kbID}/calibration/judgments", retrievalHandler.SubmitJudgments)
+	router.Post("/v1/kb/{kbID}/calibration", retrievalHandler.Calibrate)
+	router.Post("/v1/kb/{kbID}/queries/{queryID}/feedback", retrievalHandler.SubmitFeedback)
+	router.Get("/v1/kb/{kbID}/analytics/queries/top", retrievalHandler.TopQueries)
+	router.Get("/v1/kb/{kbID}/analytics/queries/zero-result", retrievalHandler.ZeroResultQueries)
+	router.Get("/v1/kb/{kbID}/analytics/latency", retrievalHandler.LatencyPercentiles)
+	router.Get("/v1/kb/{kbID}/analytics/profiles", retrievalHandler.ProfileDistribution)
+	router.Get("/v1/kb/{kbID}/analytics/documents/top", retrievalHandler.MostRetrievedDocuments)
+	router.Get("/v1/kb/{kbID}/analytics/documents/never-retrieved", retrievalHandler.NeverRetrievedDocuments)
+	router.Get("/v1/kb/{kbID}/feedback", retrievalHandler.FeedbackSummary)
+	router.Get("/v1/kb/{kbID}/feedback/chunks", retrievalHandler.ChunkFeedback)
+	router.Get("/v1/kb/{kbID}/tuning", retrievalHandler.WeightTuning)
+	router.Delete("/v1/kb/{kbID}/tuning", retrievalHandler.ResetWeights)
+	router.Post("/v1/kb/{kbID}/tuning/run", retrievalHandler.TuneWeights)
+	router.Put("/v1/kb/{kbID}/tuning/{profile}", retrievalHandler.PinWeight)
+	router.Delete("/v1/kb/{kbID}/tuning/{profile}", retrievalHandler.ResetProfileWeight)
+	router.Post("/v1/query", retrievalHandler.FederatedQuery)
 	go services.chunking.Run(context.Background())
+	tunerMetric := strings.TrimSpace(os.Getenv("RETRIEVAL_WEIGHT_TUNER_METRIC"))
+	if tunerMetric != "" && !retrieval.IsValidTuningMetric(tunerMetric) {
+		logger.Fatal("Invalid RETRIEVAL_WEIGHT_TUNER_METRIC", "value", tunerMetric)
+	}
+	go services.retrieval.RunWeightTuner(
+		context.Background(),
+		durationEnv("RETRIEVAL_WEIGHT_TUNER_INTERVAL", retrieval.DefaultTunerInterval),
+		tunerMetric,
+	)
 
 	addr := fmt.Sprintf(":%d", *port)
 	logger.Info("Starting server", "port", *port)
@@ -97,15 +129,92 @@ func newServices(db *sql.DB, store objectstore.Client) appServices {
 	}()
 
 	chunkRepo := chunkrepo.NewPostgresStore(db)
-	chunkCache := chunkcache.NewNoopLayer(chunkRepo)
+	var chunkCache chunkcache.Layer = chunkcache.NewNoopLayer(chunkRepo)
 	retrievalRepo := retrievalrepo.NewPostgresStore(db)
-	retrievalCache := retrievalcache.NewNoopLayer(retrievalRepo)
+	var retrievalCache retrievalcache.Layer = retrievalcache.NewNoopLayer(retrievalRepo)
+	if lruCache := newRetrievalLRUCache(retrievalRepo); lruCache != nil {
+		retrievalCache = lruCache
+		chunkCache = chunkcache.NewInvalidatingLayer(chunkCache, chunkRepo, lruCache)
+	}
+	reranker, err := rerank.NewRerankerFromEnv()
+	if err != nil {
+		logger.Fatal("Reranker configuration failed", "error", err)
+	}
 
 	return appServices{
 		chunking:   chunkservice.New(chunkCache, nil, chunkingCh, store, embedService),
 		embeddings: embedService,
-		retrieval:  retrievalservice.New(retrievalCache, embedder),
+		retrieval: retrievalservice.New(
+			retrievalCache,
+			newQueryEmbedder(embedder, modelID),
+			retrievalservice.WithReranker(reranker),
+			retrievalservice.WithHighlightEmbedder(embedder),
+			retrievalservice.WithCursorTTL(durationEnv("RETRIEVAL_CURSOR_TTL", retrieval.DefaultCursorTTL)),
+			retrievalservice.WithBatchConcurrency(intEnv("RETRIEVAL_BATCH_CONCURRENCY", retrieval.DefaultBatchConcurrency)),
+		),
+	}
+}
+
+// newQueryEmbedder wraps the embedder used for query vectors with an LRU cache
+// sized by QUERY_EMBEDDING_CACHE_SIZE (0 disables it) and expiring entries
+// after QUERY_EMBEDDING_CACHE_TTL when set.
+func newQueryEmbedder(embedder embedding.TextEmbedder, modelID string) embedding.TextEmbedder {
+	size := embedding.DefaultQueryCacheSize
+	if raw := strings.TrimSpace(os.Getenv("QUERY_EMBEDDING_CACHE_SIZE")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			logger.Fatal("Invalid QUERY_EMBEDDING_CACHE_SIZE", "value", raw)
+		}
+		size = parsed
+	}
+	if size == 0 {
+		return embedder
+	}
+
+	var ttl time.Duration
+	if raw := strings.TrimSpace(os.Getenv("QUERY_EMBEDDING_CACHE_TTL")); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed < 0 {
+			logger.Fatal("Invalid QUERY_EMBEDDING_CACHE_TTL", "value", raw)
+		}
+		ttl = parsed
+	}
+	return embedding.NewCachingEmbedder(embedder, modelID, size, ttl)
+}
+
+// newRetrievalLRUCache returns the in-memory retrieval cache when
+// RETRIEVAL_CACHE=lru, or nil to keep the pass-through layer.
+func newRetrievalLRUCache(store retrievalrepo.Store) *retrievalcache.LRULayer {
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv("RETRIEVAL_CACHE")))
+	switch mode {
+	case "", "none", "noop":
+		return nil
+	case "lru":
+	default:
+		logger.Fatal("Unsupported RETRIEVAL_CACHE", "mode", mode)
 	}
+
+	cfg := retrievalcache.Config{
+		Size: retrievalcache.DefaultSize,
+		TTL:  retrievalcache.DefaultTTL,
+	}
+	if raw := strings.TrimSpace(os.Getenv("RETRIEVAL_CACHE_SIZE")); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size < 1 {
+			logger.Fatal("Invalid RETRIEVAL_CACHE_SIZE", "value", raw)
+		}
+		cfg.Size = size
+	}
+	if raw := strings.TrimSpace(os.Getenv("RETRIEVAL_CACHE_TTL")); raw != "" {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil || ttl < 0 {
+			logger.Fatal("Invalid RETRIEVAL_CACHE_TTL", "value", raw)
+		}
+		cfg.TTL = ttl
+	}
+
+	logger.Info("Retrieval cache enabled", "size", cfg.Size, "ttl", cfg.TTL.String())
+	return retrievalcache.NewLRULayer(store, cfg)
 }
 
 func mustObjectStoreClient() objectstore.Client {
@@ -141,6 +250,32 @@ func mustObjectStoreClient() objectstore.Client {
 	}
 }
 
+// durationEnv parses a Go duration from key, returning fallback when unset.
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return fallback
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil || value < 0 {
+		logger.Fatal("Invalid duration environment variable", "key", key, "value", raw)
+	}
+	return value
+}
+
+// intEnv parses a positive integer from key, returning fallback when unset.
+func intEnv(key string, fallback int) int {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return fallback
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 1 {
+		logger.Fatal("Invalid integer environment variable", "key", key, "value", raw)
+	}
+	return value
+}
+
 func requiredEnv(key string) string {
 	value := strings.TrimSpace(os.Getenv(key))
 	if value == "" {
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+
+	"ragtime-backend/internal/chunking/repository"
+	"ragtime-backend/internal/logger"
+)
+
+// Invalidator is notified when a knowledge base's searchable content changes.
+type Invalidator interface {
+	InvalidateKnowledgeBase(knowledgeBaseID string)
+}
+
+// VersionResolver maps a document version to its knowledge base so
+// version-scoped writes can be invalidated.
+type VersionResolver interface {
+	GetDocumentVersionKnowledgeBaseID(ctx context.Context, versionID string) (string, error)
+}
+
+// InvalidatingLayer wraps a Layer and notifies downstream caches after
+// writes that change which chunks are searchable.
+type InvalidatingLayer struct {
+	Layer
+	resolver    VersionResolver
+	invalidator Invalidator
+}
+
+func NewInvalidatingLayer(next Layer, resolver VersionResolver, invalidator Invalidator) *InvalidatingLayer {
+	return &InvalidatingLayer{
+		Layer:       next,
+		resolver:    resolver,
+		invalidator: invalidator,
+	}
+}
+
+func (c *InvalidatingLayer) DeleteChunksByDocumentVersion(ctx context.Context, documentVersionID string) error {
+	if err := c.Layer.DeleteChunksByDocumentVersion(ctx, documentVersionID); err != nil {
+		return err
+	}
+	c.invalidateVersion(ctx, documentVersionID)
+	return nil
+}
+
+func (c *InvalidatingLayer) DeleteChunksByDocument(ctx context.Context, knowledgeBaseID, documentID string) error {
+	if err := c.Layer.DeleteChunksByDocument(ctx, knowledgeBaseID, documentID); err != nil {
+		return err
+	}
+	c.invalidator.InvalidateKnowledgeBase(knowledgeBaseID)
+	return nil
+}
+
+func (c *InvalidatingLayer) UpdateChunkEmbedding(ctx context.Context, knowledgeBaseID, chunkID, embeddingID string) error {
+	if err := c.Layer.UpdateChunkEmbedding(ctx, knowledgeBaseID, chunkID, embeddingID); err != nil {
+		return err
+	}
+	c.invalidator.InvalidateKnowledgeBase(knowledgeBaseID)
+	return nil
+}
+
+func (c *InvalidatingLayer) ActivateDocumentVersion(ctx context.Context, versionID string) error {
+	if err := c.Layer.ActivateDocumentVersion(ctx, versionID); err != nil {
+		return err
+	}
+	c.invalidateVersion(ctx, versionID)
+	return nil
+}
+
+func (c *InvalidatingLayer) invalidateVersion(ctx context.Context, versionID string) {
+	kbID, err := c.resolver.GetDocumentVersionKnowledgeBaseID(ctx, versionID)
+	if err != nil {
+		// Entries still expire by TTL; a failed lookup should not fail the write.
+		logger.Warn("failed to resolve knowledge base for cache invalidation", "document_version_id", versionID, "error", err)
+		return
+	}
+	c.invalidator.InvalidateKnowledgeBase(kbID)
+}
+
+var (
+	_ Layer           = (*InvalidatingLayer)(nil)
+	_ VersionResolver = (*repository.PostgresStore)(nil)
+)
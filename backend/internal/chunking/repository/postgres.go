@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 
 	"ragtime-backend/internal/domain"
 	"ragtime-backend/internal/storage/sqlc"
@@ -38,6 +39,7 @@ func (r *PostgresStore) InsertChunks(ctx context.Context, chunks []domain.Chunk)
 	rollback := func() { _ = tx.Rollback() }
 
 	queries := r.queries.WithTx(tx)
+	chunkIDs := make([]string, 0, len(chunks))
 	for i := range chunks {
 		kbUUID, err := uuid.Parse(chunks[i].KBID)
 		if err != nil {
@@ -84,6 +86,12 @@ func (r *PostgresStore) InsertChunks(ctx context.Context, chunks []domain.Chunk)
 			rollback()
 			return err
 		}
+		chunkIDs = append(chunkIDs, chunkUUID.String())
+	}
+
+	if _, err := tx.ExecContext(ctx, `SELECT apply_chunk_lexical_stats($1::uuid[], 1)`, pq.Array(chunkIDs)); err != nil {
+		rollback()
+		return err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -98,8 +106,7 @@ func (r *PostgresStore) DeleteChunksByDocumentVersion(ctx context.Context, docum
 	if err != nil {
 		return err
 	}
-	_, err = r.db.ExecContext(ctx, `DELETE FROM chunks WHERE document_version_id = $1`, versionUUID)
-	return err
+	return r.deleteChunks(ctx, `document_version_id = $1`, versionUUID)
 }
 
 func (r *PostgresStore) DeleteChunksByDocument(ctx context.Context, knowledgeBaseID, documentID string) error {
@@ -112,14 +119,39 @@ func (r *PostgresStore) DeleteChunksByDocument(ctx context.Context, knowledgeBas
 		return err
 	}
 
-	_, err = r.db.ExecContext(ctx, `
-		DELETE FROM chunks c
-		USING document_versions dv
-		WHERE c.document_version_id = dv.id
-		  AND dv.kb_id = $1
-		  AND dv.document_id = $2
-	`, kbUUID, docUUID)
-	return err
+	return r.deleteChunks(ctx, `
+		document_version_id IN (
+			SELECT dv.id
+			FROM document_versions dv
+			WHERE dv.kb_id = $1
+			  AND dv.document_id = $2
+		)`, kbUUID, docUUID)
+}
+
+// deleteChunks removes the chunks matching where from the lexical statistics
+// and then deletes them, in one transaction.
+func (r *PostgresStore) deleteChunks(ctx context.Context, where string, args ...any) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	rollback := func() { _ = tx.Rollback() }
+
+	statsQuery := `SELECT apply_chunk_lexical_stats(ARRAY(SELECT id FROM chunks WHERE ` + where + `), -1)`
+	if _, err := tx.ExecContext(ctx, statsQuery, args...); err != nil {
+		rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM chunks WHERE `+where, args...); err != nil {
+		rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollback()
+		return err
+	}
+	return nil
 }
 
 func (r *PostgresStore) GetChunkByID(ctx context.Context, knowledgeBaseID, chunkID string) (*domain.Chunk, error) {
@@ -307,6 +339,25 @@ func (r *PostgresStore) GetLatestDocumentVersionForDocument(
 	}, nil
 }
 
+// GetDocumentVersionKnowledgeBaseID returns the knowledge base that owns a document version.
+func (r *PostgresStore) GetDocumentVersionKnowledgeBaseID(ctx context.Context, versionID string) (string, error) {
+	versionUUID, err := uuid.Parse(versionID)
+	if err != nil {
+		return "", err
+	}
+
+	var kbID uuid.UUID
+	err = r.db.QueryRowContext(ctx, `
+		SELECT kb_id
+		FROM document_versions
+		WHERE id = $1
+	`, versionUUID).Scan(&kbID)
+	if err != nil {
+		return "", err
+	}
+	return kbID.String(), nil
+}
+
 func encodeMetadata(metadata map[string]any) json.RawMessage {
 	if metadata == nil {
 		return json.RawMessage([]byte("{}"))
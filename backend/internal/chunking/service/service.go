@@ -295,6 +295,13 @@ func (s *Service) handle(ctx context.Context, req DocumentRequest) (int, error)
 	stored := make([]domain.Chunk, 0, len(chunks))
 	for i, ch := range chunks {
 		chunkID := uuid.NewString()
+		metadata := make(map[string]any, len(ch.Metadata)+3)
+		for key, value := range ch.Metadata {
+			metadata[key] = value
+		}
+		metadata["start_rune"] = ch.StartRune
+		metadata["end_rune"] = ch.EndRune
+		metadata["rune_length"] = ch.RuneLength
 		stored = append(stored, domain.Chunk{
 			ID:                chunkID,
 			DocumentVersionID: req.DocumentVersionID,
@@ -302,13 +309,9 @@ func (s *Service) handle(ctx context.Context, req DocumentRequest) (int, error)
 			SequenceNumber:    i + 1,
 			Content:           ch.Content,
 			ContentHash:       hashContent(ch.Content),
-			Metadata: map[string]any{
-				"start_rune":  ch.StartRune,
-				"end_rune":    ch.EndRune,
-				"rune_length": ch.RuneLength,
-			},
-			ChunkingStrategy: strategyName,
-			CreatedAt:        s.now(),
+			Metadata:          metadata,
+			ChunkingStrategy:  strategyName,
+			CreatedAt:         s.now(),
 		})
 	}
 
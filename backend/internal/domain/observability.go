@@ -23,6 +23,9 @@ type RetrievalResult struct {
 	SemanticScore      float64
 	LexicalScore       float64
 	FinalScore         float64
+	RerankScore        *float64
+	SemanticRank       *int
+	LexicalRank        *int
 	CreatedAt          time.Time
 }
 
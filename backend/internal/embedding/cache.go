@@ -0,0 +1,99 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"ragtime-backend/internal/lru"
+)
+
+const DefaultQueryCacheSize = 512
+
+type cachedVector struct {
+	vector    []float32
+	dimension int
+}
+
+// CachingEmbedder memoizes vectors per normalized text and model so repeated
+// queries skip the embedding provider. Returned vectors are shared with the
+// cache and must not be modified.
+type CachingEmbedder struct {
+	next    TextEmbedder
+	modelID string
+	cache   *lru.Cache[string, cachedVector]
+}
+
+// NewCachingEmbedder bounds the cache to size entries; a zero ttl keeps
+// entries until they are evicted.
+func NewCachingEmbedder(next TextEmbedder, modelID string, size int, ttl time.Duration) *CachingEmbedder {
+	if size <= 0 {
+		size = DefaultQueryCacheSize
+	}
+	return &CachingEmbedder{
+		next:    next,
+		modelID: strings.TrimSpace(modelID),
+		cache:   lru.New[string, cachedVector](size, ttl),
+	}
+}
+
+func (c *CachingEmbedder) EmbedTexts(ctx context.Context, texts []string) ([][]float32, int, error) {
+	vectors, dimension, _, err := c.EmbedTextsCached(ctx, texts)
+	return vectors, dimension, err
+}
+
+// EmbedTextsCached behaves like EmbedTexts and also reports, per text,
+// whether its vector was served from the cache.
+func (c *CachingEmbedder) EmbedTextsCached(ctx context.Context, texts []string) ([][]float32, int, []bool, error) {
+	if c.next == nil {
+		return nil, 0, nil, ErrNilEmbedder
+	}
+
+	vectors := make([][]float32, len(texts))
+	hits := make([]bool, len(texts))
+	dimension := 0
+	missingTexts := make([]string, 0, len(texts))
+	missingIndexes := make([]int, 0, len(texts))
+
+	for i, text := range texts {
+		if cached, ok := c.cache.Get(c.key(text)); ok {
+			vectors[i] = cached.vector
+			hits[i] = true
+			dimension = cached.dimension
+			continue
+		}
+		missingTexts = append(missingTexts, text)
+		missingIndexes = append(missingIndexes, i)
+	}
+	if len(missingTexts) == 0 {
+		return vectors, dimension, hits, nil
+	}
+
+	embedded, embeddedDimension, err := c.next.EmbedTexts(ctx, missingTexts)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	if len(embedded) != len(missingTexts) {
+		return nil, 0, nil, fmt.Errorf("embedding service returned %d vectors for %d texts", len(embedded), len(missingTexts))
+	}
+
+	for j, idx := range missingIndexes {
+		vectors[idx] = embedded[j]
+		c.cache.Add(c.key(missingTexts[j]), cachedVector{vector: embedded[j], dimension: embeddedDimension})
+	}
+	return vectors, embeddedDimension, hits, nil
+}
+
+func (c *CachingEmbedder) key(text string) string {
+	return c.modelID + "\x00" + normalizeCacheText(text)
+}
+
+// normalizeCacheText collapses whitespace so trivially different spellings of
+// the same query share an entry. Case is preserved because embeddings are
+// case-sensitive.
+func normalizeCacheText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+var _ TextEmbedder = (*CachingEmbedder)(nil)
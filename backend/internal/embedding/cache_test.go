@@ -0,0 +1,52 @@
+package embedding
+
+import (
+	"context"
+	"testing"
+)
+
+type countingEmbedder struct {
+	calls int
+	texts []string
+}
+
+func (e *countingEmbedder) EmbedTexts(_ context.Context, texts []string) ([][]float32, int, error) {
+	e.calls++
+	e.texts = append(e.texts, texts...)
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = []float32{float32(len(text)), 1}
+	}
+	return out, 2, nil
+}
+
+func TestCachingEmbedderServesRepeatQueriesFromCache(t *testing.T) {
+	next := &countingEmbedder{}
+	embedder := NewCachingEmbedder(next, "model-a", 8, 0)
+
+	if _, _, hits, err := embedder.EmbedTextsCached(context.Background(), []string{"how does  chunking work"}); err != nil || hits[0] {
+		t.Fatalf("first EmbedTextsCached() hit = %v, err = %v; want miss", hits, err)
+	}
+	vectors, dim, hits, err := embedder.EmbedTextsCached(context.Background(), []string{" how does chunking work ", "new query"})
+	if err != nil {
+		t.Fatalf("EmbedTextsCached() error = %v", err)
+	}
+	if !hits[0] || hits[1] {
+		t.Fatalf("EmbedTextsCached() hits = %v, want [true false]", hits)
+	}
+	if dim != 2 || len(vectors) != 2 || vectors[1] == nil {
+		t.Fatalf("EmbedTextsCached() = %v, %d; want two 2-dim vectors", vectors, dim)
+	}
+	if next.calls != 2 || len(next.texts) != 2 {
+		t.Fatalf("underlying embedder calls = %d texts = %v, want only misses forwarded", next.calls, next.texts)
+	}
+}
+
+func TestCachingEmbedderKeysByModel(t *testing.T) {
+	next := &countingEmbedder{}
+	a := NewCachingEmbedder(next, "model-a", 8, 0)
+	b := NewCachingEmbedder(next, "model-b", 8, 0)
+	if a.key("q") == b.key("q") {
+		t.Fatalf("cache key does not include model id")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type ChunkRecord struct {
@@ -130,6 +131,7 @@ func (r *PostgresRepository) InsertChunks(ctx context.Context, chunks []ChunkRec
 		_ = tx.Rollback()
 	}
 
+	chunkIDs := make([]string, 0, len(chunks))
 	for _, chunk := range chunks {
 		chunkUUID, err := uuid.Parse(chunk.ID)
 		if err != nil {
@@ -198,6 +200,13 @@ func (r *PostgresRepository) InsertChunks(ctx context.Context, chunks []ChunkRec
 			rollback()
 			return err
 		}
+		chunkIDs = append(chunkIDs, chunkUUID.String())
+	}
+
+	// Keep the knowledge base's BM25 term statistics in step with its chunks.
+	if _, err := tx.ExecContext(ctx, `SELECT apply_chunk_lexical_stats($1::uuid[], 1)`, pq.Array(chunkIDs)); err != nil {
+		rollback()
+		return err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -0,0 +1,127 @@
+// Package lru provides a bounded, concurrency-safe LRU cache with optional TTL expiry.
+package lru
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache evicts the least recently used entry once capacity is reached and
+// treats entries older than the TTL as absent. A zero TTL disables expiry.
+type Cache[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	now      func() time.Time
+	order    *list.List
+	entries  map[K]*list.Element
+}
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// New returns a cache holding at most capacity entries. Capacity below 1 is treated as 1.
+func New[K comparable, V any](capacity int, ttl time.Duration) *Cache[K, V] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Cache[K, V]{
+		capacity: capacity,
+		ttl:      ttl,
+		now:      time.Now,
+		order:    list.New(),
+		entries:  make(map[K]*list.Element, capacity),
+	}
+}
+
+// Get returns the cached value and marks it as recently used.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	elem, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+	item := elem.Value.(*entry[K, V])
+	if c.expired(item) {
+		c.removeElement(elem)
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return item.value, true
+}
+
+// Add inserts or replaces a value, evicting the least recently used entry when full.
+func (c *Cache[K, V]) Add(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = c.now().Add(c.ttl)
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		item := elem.Value.(*entry[K, V])
+		item.value = value
+		item.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// Remove deletes a single key.
+func (c *Cache[K, V]) Remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// RemoveFunc deletes every entry for which match returns true and reports how many were removed.
+func (c *Cache[K, V]) RemoveFunc(match func(key K, value V) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for elem := c.order.Front(); elem != nil; {
+		next := elem.Next()
+		item := elem.Value.(*entry[K, V])
+		if match(item.key, item.value) {
+			c.removeElement(elem)
+			removed++
+		}
+		elem = next
+	}
+	return removed
+}
+
+// Len reports the number of stored entries, including ones that have expired but not yet been evicted.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *Cache[K, V]) expired(item *entry[K, V]) bool {
+	return !item.expiresAt.IsZero() && !c.now().Before(item.expiresAt)
+}
+
+func (c *Cache[K, V]) removeElement(elem *list.Element) {
+	item := elem.Value.(*entry[K, V])
+	delete(c.entries, item.key)
+	c.order.Remove(elem)
+}
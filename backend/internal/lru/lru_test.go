@@ -0,0 +1,60 @@
+package lru
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := New[string, int](2, 0)
+	cache.Add("a", 1)
+	cache.Add("b", 2)
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatalf("Get(a) missed before eviction")
+	}
+	cache.Add("c", 3)
+
+	if _, ok := cache.Get("b"); ok {
+		t.Fatalf("Get(b) hit, want evicted as least recently used")
+	}
+	if value, ok := cache.Get("a"); !ok || value != 1 {
+		t.Fatalf("Get(a) = %v, %v; want 1, true", value, ok)
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", cache.Len())
+	}
+}
+
+func TestCacheExpiresEntries(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := New[string, int](4, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.Add("a", 1)
+	now = now.Add(59 * time.Second)
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatalf("Get(a) missed before TTL")
+	}
+	now = now.Add(time.Second)
+	if _, ok := cache.Get("a"); ok {
+		t.Fatalf("Get(a) hit after TTL")
+	}
+	if cache.Len() != 0 {
+		t.Fatalf("Len() = %d, want expired entry removed", cache.Len())
+	}
+}
+
+func TestCacheRemoveFunc(t *testing.T) {
+	cache := New[string, int](4, 0)
+	cache.Add("kb1|x", 1)
+	cache.Add("kb2|y", 2)
+	cache.Add("kb1|z", 3)
+
+	removed := cache.RemoveFunc(func(key string, _ int) bool { return key[:3] == "kb1" })
+	if removed != 2 {
+		t.Fatalf("RemoveFunc() removed %d, want 2", removed)
+	}
+	if _, ok := cache.Get("kb2|y"); !ok {
+		t.Fatalf("RemoveFunc() removed unrelated entry")
+	}
+}
@@ -0,0 +1,134 @@
+package retrieval
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	DefaultAnalyticsWindow = 30 * 24 * time.Hour
+	DefaultAnalyticsLimit  = 20
+	MaxAnalyticsLimit      = 200
+	AnalyticsBucketHour    = "hour"
+	AnalyticsBucketDay     = "day"
+	AnalyticsBucketWeek    = "week"
+	DefaultAnalyticsBucket = AnalyticsBucketDay
+)
+
+var (
+	ErrInvalidAnalyticsRange  = errors.New("from must be before to")
+	ErrInvalidAnalyticsLimit  = errors.New("limit must be between 1 and 200")
+	ErrInvalidAnalyticsOffset = errors.New("offset must not be negative")
+	ErrInvalidAnalyticsBucket = errors.New("bucket must be one of: hour, day, week")
+)
+
+// AnalyticsQuery selects a knowledge base's logged requests created in
+// [From, To) and a page of the aggregated rows. Bucket is only used by
+// latency percentiles.
+type AnalyticsQuery struct {
+	KnowledgeBaseID string
+	From            time.Time
+	To              time.Time
+	Limit           int
+	Offset          int
+	Bucket          string
+}
+
+// AnalyticsPage is one page of aggregated rows. NextOffset is set when more
+// rows follow.
+type AnalyticsPage[T any] struct {
+	KnowledgeBaseID string    `json:"kb_id"`
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	Limit           int       `json:"limit"`
+	Offset          int       `json:"offset"`
+	NextOffset      *int      `json:"next_offset,omitempty"`
+	Items           []T       `json:"items"`
+}
+
+// QueryStat aggregates the requests of one query text, compared after
+// trimming and lowercasing.
+type QueryStat struct {
+	Query          string    `json:"query"`
+	RequestCount   int64     `json:"request_count"`
+	EmptyCount     int64     `json:"empty_count"`
+	AvgResultCount float64   `json:"avg_result_count"`
+	AvgLatencyMS   float64   `json:"avg_latency_ms"`
+	LastSeenAt     time.Time `json:"last_seen_at"`
+}
+
+type ZeroResultQuery struct {
+	Query        string    `json:"query"`
+	RequestCount int64     `json:"request_count"`
+	EmptyReasons []string  `json:"empty_reasons"`
+	LastSeenAt   time.Time `json:"last_seen_at"`
+}
+
+// LatencyBucket holds latency percentiles of the requests created in a UTC
+// hour, day or week starting at BucketStart.
+type LatencyBucket struct {
+	BucketStart  time.Time `json:"bucket_start"`
+	RequestCount int64     `json:"request_count"`
+	P50MS        float64   `json:"p50_ms"`
+	P90MS        float64   `json:"p90_ms"`
+	P95MS        float64   `json:"p95_ms"`
+	P99MS        float64   `json:"p99_ms"`
+	MaxMS        int64     `json:"max_ms"`
+}
+
+// ProfileUsage counts requests by effective retrieval profile. Requests
+// logged before profiles were recorded have an empty Profile.
+type ProfileUsage struct {
+	Profile           string  `json:"retrieval_profile"`
+	RequestCount      int64   `json:"request_count"`
+	AvgSemanticWeight float64 `json:"avg_semantic_weight"`
+}
+
+// WeightUsage counts requests by effective semantic weight, rounded to 0.1.
+type WeightUsage struct {
+	SemanticWeight float64 `json:"semantic_weight"`
+	RequestCount   int64   `json:"request_count"`
+}
+
+type ProfileDistribution struct {
+	KnowledgeBaseID string         `json:"kb_id"`
+	From            time.Time      `json:"from"`
+	To              time.Time      `json:"to"`
+	Profiles        []ProfileUsage `json:"profiles"`
+	SemanticWeights []WeightUsage  `json:"semantic_weights"`
+}
+
+// DocumentUsage reports how often a document's chunks were returned.
+// Never-retrieved documents only carry the document fields and CreatedAt.
+type DocumentUsage struct {
+	DocumentID      string     `json:"document_id"`
+	Path            string     `json:"path"`
+	Title           *string    `json:"title,omitempty"`
+	RequestCount    int64      `json:"request_count"`
+	ResultCount     int64      `json:"result_count"`
+	AvgRank         *float64   `json:"avg_rank,omitempty"`
+	LastRetrievedAt *time.Time `json:"last_retrieved_at,omitempty"`
+	CreatedAt       *time.Time `json:"created_at,omitempty"`
+}
+
+// ValidateAnalyticsQuery checks a query after defaults are applied.
+func ValidateAnalyticsQuery(query AnalyticsQuery) error {
+	if query.KnowledgeBaseID == "" {
+		return ErrMissingKnowledgeBase
+	}
+	if !query.From.Before(query.To) {
+		return ErrInvalidAnalyticsRange
+	}
+	if query.Limit < 1 || query.Limit > MaxAnalyticsLimit {
+		return ErrInvalidAnalyticsLimit
+	}
+	if query.Offset < 0 {
+		return ErrInvalidAnalyticsOffset
+	}
+	switch query.Bucket {
+	case AnalyticsBucketHour, AnalyticsBucketDay, AnalyticsBucketWeek:
+	default:
+		return ErrInvalidAnalyticsBucket
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package retrieval
+
+import "errors"
+
+const (
+	MaxBatchQueries         = 50
+	DefaultBatchConcurrency = 4
+)
+
+var (
+	ErrMissingBatchQueries = errors.New("queries must contain at least one query")
+	ErrTooManyBatchQueries = errors.New("queries must contain at most 50 queries")
+)
+
+// BatchResult is the outcome of one query of a batch: its response, or the
+// error that failed it without failing the rest of the batch.
+type BatchResult struct {
+	Response *Response
+	Err      error
+}
+
+// ValidateBatch checks the number of queries in a batch.
+func ValidateBatch(count int) error {
+	if count == 0 {
+		return ErrMissingBatchQueries
+	}
+	if count > MaxBatchQueries {
+		return ErrTooManyBatchQueries
+	}
+	return nil
+}
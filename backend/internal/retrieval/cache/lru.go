@@ -0,0 +1,364 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"ragtime-backend/internal/logger"
+	"ragtime-backend/internal/lru"
+	"ragtime-backend/internal/retrieval"
+	"ragtime-backend/internal/retrieval/repository"
+)
+
+const (
+	DefaultSize = 1024
+	DefaultTTL  = 5 * time.Minute
+
+	searchKindSemantic = "semantic"
+	searchKindLexical  = "lexical"
+	cacheKindChunks    = "chunks"
+)
+
+// Invalidator drops cached retrieval data for a knowledge base after its
+// active content changes.
+type Invalidator interface {
+	InvalidateKnowledgeBase(knowledgeBaseID string)
+}
+
+// Config bounds the in-memory caches. Size applies to each cache separately.
+type Config struct {
+	Size int
+	TTL  time.Duration
+}
+
+// LRULayer caches search candidates per knowledge base and chunk lookups in
+// memory, delegating writes and misses to the repository. A knowledge base's
+// entries are dropped when its tuned weights are written or its settings are
+// read back changed, since both the search SQL (text_search_config) and the
+// request options derive from them.
+type LRULayer struct {
+	store    repository.Store
+	searches *lru.Cache[string, []retrieval.ScoredChunk]
+	chunks   *lru.Cache[string, retrieval.ChunkRecord]
+	settings *lru.Cache[string, string]
+	metrics  *cacheMetrics
+}
+
+func NewLRULayer(store repository.Store, cfg Config) *LRULayer {
+	if cfg.Size <= 0 {
+		cfg.Size = DefaultSize
+	}
+	if cfg.TTL < 0 {
+		cfg.TTL = DefaultTTL
+	}
+	return &LRULayer{
+		store:    store,
+		searches: lru.New[string, []retrieval.ScoredChunk](cfg.Size, cfg.TTL),
+		chunks:   lru.New[string, retrieval.ChunkRecord](cfg.Size, cfg.TTL),
+		settings: lru.New[string, string](cfg.Size, 0),
+		metrics:  newCacheMetrics(),
+	}
+}
+
+func (c *LRULayer) InsertRetrievalRequest(ctx context.Context, req retrieval.RetrievalRequestRecord) (*retrieval.RetrievalRequestRecord, error) {
+	return c.store.InsertRetrievalRequest(ctx, req)
+}
+
+func (c *LRULayer) UpdateRetrievalRequest(ctx context.Context, requestID string, resultCount int, latencyMS int64, emptyResult bool, emptyReason string) error {
+	return c.store.UpdateRetrievalRequest(ctx, requestID, resultCount, latencyMS, emptyResult, emptyReason)
+}
+
+func (c *LRULayer) InsertRetrievalResults(ctx context.Context, results []retrieval.RetrievalResultRecord) error {
+	return c.store.InsertRetrievalResults(ctx, results)
+}
+
+func (c *LRULayer) SearchSemantic(ctx context.Context, params retrieval.SearchParams) ([]retrieval.ScoredChunk, error) {
+	return c.search(ctx, searchKindSemantic, params, c.store.SearchSemantic)
+}
+
+func (c *LRULayer) SearchLexical(ctx context.Context, params retrieval.SearchParams) ([]retrieval.ScoredChunk, error) {
+	return c.search(ctx, searchKindLexical, params, c.store.SearchLexical)
+}
+
+func (c *LRULayer) ExplainChunk(ctx context.Context, params retrieval.SearchParams, chunkID string) (*retrieval.ChunkExplanation, error) {
+	return c.store.ExplainChunk(ctx, params, chunkID)
+}
+
+func (c *LRULayer) GetChunksWithDocuments(ctx context.Context, chunkIDs []string) ([]retrieval.ChunkRecord, error) {
+	return c.getChunks(ctx, "", chunkIDs, c.store.GetChunksWithDocuments)
+}
+
+func (c *LRULayer) GetChunksWithDocumentsForKB(ctx context.Context, knowledgeBaseID string, chunkIDs []string) ([]retrieval.ChunkRecord, error) {
+	return c.getChunks(ctx, knowledgeBaseID, chunkIDs, func(ctx context.Context, missing []string) ([]retrieval.ChunkRecord, error) {
+		return c.store.GetChunksWithDocumentsForKB(ctx, knowledgeBaseID, missing)
+	})
+}
+
+func (c *LRULayer) GetChunksByDocumentVersionRange(ctx context.Context, documentVersionID string, startSeq int32, endSeq int32) ([]retrieval.ChunkRecord, error) {
+	return c.store.GetChunksByDocumentVersionRange(ctx, documentVersionID, startSeq, endSeq)
+}
+
+func (c *LRULayer) GetChunkVectors(ctx context.Context, vectorDimension int, chunkIDs []string) (map[string][]float32, error) {
+	return c.store.GetChunkVectors(ctx, vectorDimension, chunkIDs)
+}
+
+func (c *LRULayer) GetChunkDocumentIDs(ctx context.Context, chunkIDs []string) (map[string]string, error) {
+	return c.store.GetChunkDocumentIDs(ctx, chunkIDs)
+}
+
+func (c *LRULayer) GetLexemes(ctx context.Context, knowledgeBaseID string, words []string) (map[string][]string, error) {
+	return c.store.GetLexemes(ctx, knowledgeBaseID, words)
+}
+
+func (c *LRULayer) SaveRetrievalRanking(ctx context.Context, requestID string, candidates []retrieval.RankedCandidate) error {
+	return c.store.SaveRetrievalRanking(ctx, requestID, candidates)
+}
+
+func (c *LRULayer) GetRetrievalRanking(ctx context.Context, knowledgeBaseID string, requestID string) (*retrieval.RetrievalRanking, error) {
+	return c.store.GetRetrievalRanking(ctx, knowledgeBaseID, requestID)
+}
+
+// GetKnowledgeBaseMetadata invalidates the knowledge base when its metadata
+// differs from the last read, which catches settings updated by other
+// writers.
+func (c *LRULayer) GetKnowledgeBaseMetadata(ctx context.Context, knowledgeBaseID string) (map[string]any, error) {
+	metadata, err := c.store.GetKnowledgeBaseMetadata(ctx, knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return metadata, nil
+	}
+	sum := sha256.Sum256(encoded)
+	fingerprint := hex.EncodeToString(sum[:])
+	if previous, ok := c.settings.Get(knowledgeBaseID); ok && previous != fingerprint {
+		c.InvalidateKnowledgeBase(knowledgeBaseID)
+	}
+	c.settings.Add(knowledgeBaseID, fingerprint)
+	return metadata, nil
+}
+
+func (c *LRULayer) InsertCalibrationJudgments(ctx context.Context, knowledgeBaseID string, judgments []retrieval.RelevanceJudgment) (int, error) {
+	return c.store.InsertCalibrationJudgments(ctx, knowledgeBaseID, judgments)
+}
+
+func (c *LRULayer) GetCalibrationSamples(ctx context.Context, knowledgeBaseID string, fusionMethod string) ([]retrieval.CalibrationSample, error) {
+	return c.store.GetCalibrationSamples(ctx, knowledgeBaseID, fusionMethod)
+}
+
+func (c *LRULayer) SaveScoreCalibration(ctx context.Context, calibration retrieval.ScoreCalibration) error {
+	return c.store.SaveScoreCalibration(ctx, calibration)
+}
+
+func (c *LRULayer) GetScoreCalibration(ctx context.Context, knowledgeBaseID string, fusionMethod string) (*retrieval.ScoreCalibration, error) {
+	return c.store.GetScoreCalibration(ctx, knowledgeBaseID, fusionMethod)
+}
+
+func (c *LRULayer) ListTopQueries(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.QueryStat, error) {
+	return c.store.ListTopQueries(ctx, query)
+}
+
+func (c *LRULayer) ListZeroResultQueries(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.ZeroResultQuery, error) {
+	return c.store.ListZeroResultQueries(ctx, query)
+}
+
+func (c *LRULayer) ListLatencyPercentiles(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.LatencyBucket, error) {
+	return c.store.ListLatencyPercentiles(ctx, query)
+}
+
+func (c *LRULayer) ListProfileDistribution(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.ProfileUsage, []retrieval.WeightUsage, error) {
+	return c.store.ListProfileDistribution(ctx, query)
+}
+
+func (c *LRULayer) ListMostRetrievedDocuments(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.DocumentUsage, error) {
+	return c.store.ListMostRetrievedDocuments(ctx, query)
+}
+
+func (c *LRULayer) ListNeverRetrievedDocuments(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.DocumentUsage, error) {
+	return c.store.ListNeverRetrievedDocuments(ctx, query)
+}
+
+func (c *LRULayer) InsertFeedback(ctx context.Context, submission retrieval.FeedbackSubmission) (int, error) {
+	return c.store.InsertFeedback(ctx, submission)
+}
+
+func (c *LRULayer) GetFeedbackSummary(ctx context.Context, query retrieval.AnalyticsQuery) (*retrieval.FeedbackSummary, error) {
+	return c.store.GetFeedbackSummary(ctx, query)
+}
+
+func (c *LRULayer) ListChunkFeedback(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.ChunkFeedback, error) {
+	return c.store.ListChunkFeedback(ctx, query)
+}
+
+func (c *LRULayer) ListKnowledgeBaseIDs(ctx context.Context) ([]string, error) {
+	return c.store.ListKnowledgeBaseIDs(ctx)
+}
+
+func (c *LRULayer) ListTuningQueries(ctx context.Context, knowledgeBaseID string, since time.Time) ([]retrieval.TuningQuery, error) {
+	return c.store.ListTuningQueries(ctx, knowledgeBaseID, since)
+}
+
+func (c *LRULayer) SaveTunedWeight(ctx context.Context, knowledgeBaseID string, profile string, tuned retrieval.TunedWeight, skipPinned bool) (bool, error) {
+	stored, err := c.store.SaveTunedWeight(ctx, knowledgeBaseID, profile, tuned, skipPinned)
+	if err != nil {
+		return false, err
+	}
+	if stored {
+		c.InvalidateKnowledgeBase(knowledgeBaseID)
+	}
+	return stored, nil
+}
+
+func (c *LRULayer) DeleteTunedWeights(ctx context.Context, knowledgeBaseID string, profile string) error {
+	if err := c.store.DeleteTunedWeights(ctx, knowledgeBaseID, profile); err != nil {
+		return err
+	}
+	c.InvalidateKnowledgeBase(knowledgeBaseID)
+	return nil
+}
+
+// InvalidateKnowledgeBase removes every cached search and chunk belonging to the knowledge base.
+func (c *LRULayer) InvalidateKnowledgeBase(knowledgeBaseID string) {
+	prefix := knowledgeBaseID + "|"
+	searches := c.searches.RemoveFunc(func(key string, _ []retrieval.ScoredChunk) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+	chunks := c.chunks.RemoveFunc(func(_ string, chunk retrieval.ChunkRecord) bool {
+		return chunk.KnowledgeBaseID == knowledgeBaseID
+	})
+	logger.Info("retrieval cache invalidated", "knowledge_base_id", knowledgeBaseID, "searches", searches, "chunks", chunks)
+}
+
+func (c *LRULayer) search(
+	ctx context.Context,
+	kind string,
+	params retrieval.SearchParams,
+	load func(context.Context, retrieval.SearchParams) ([]retrieval.ScoredChunk, error),
+) ([]retrieval.ScoredChunk, error) {
+	key, ok := searchKey(kind, params)
+	if !ok {
+		return load(ctx, params)
+	}
+
+	if cached, hit := c.searches.Get(key); hit {
+		c.metrics.record(ctx, kind, true)
+		return append([]retrieval.ScoredChunk(nil), cached...), nil
+	}
+	c.metrics.record(ctx, kind, false)
+
+	results, err := load(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	c.searches.Add(key, append([]retrieval.ScoredChunk(nil), results...))
+	return results, nil
+}
+
+func (c *LRULayer) getChunks(
+	ctx context.Context,
+	knowledgeBaseID string,
+	chunkIDs []string,
+	load func(context.Context, []string) ([]retrieval.ChunkRecord, error),
+) ([]retrieval.ChunkRecord, error) {
+	results := make([]retrieval.ChunkRecord, 0, len(chunkIDs))
+	missing := make([]string, 0, len(chunkIDs))
+	for _, id := range chunkIDs {
+		chunk, hit := c.chunks.Get(id)
+		if hit && (knowledgeBaseID == "" || chunk.KnowledgeBaseID == knowledgeBaseID) {
+			results = append(results, chunk)
+			continue
+		}
+		missing = append(missing, id)
+	}
+	if hits := len(results); hits > 0 {
+		c.metrics.add(ctx, cacheKindChunks, true, int64(hits))
+	}
+	if len(missing) == 0 {
+		return results, nil
+	}
+	c.metrics.add(ctx, cacheKindChunks, false, int64(len(missing)))
+
+	loaded, err := load(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, chunk := range loaded {
+		c.chunks.Add(chunk.ChunkID, chunk)
+	}
+	return append(results, loaded...), nil
+}
+
+// searchKey scopes the hash of the search parameters by knowledge base so a
+// whole knowledge base can be invalidated by key prefix.
+func searchKey(kind string, params retrieval.SearchParams) (string, bool) {
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(encoded)
+	return params.KnowledgeBaseID + "|" + kind + "|" + hex.EncodeToString(sum[:]), true
+}
+
+type cacheMetrics struct {
+	hits   metric.Int64Counter
+	misses metric.Int64Counter
+}
+
+func (m *cacheMetrics) record(ctx context.Context, kind string, hit bool) {
+	m.add(ctx, kind, hit, 1)
+}
+
+func (m *cacheMetrics) add(ctx context.Context, kind string, hit bool, count int64) {
+	if m == nil {
+		return
+	}
+	attrs := metric.WithAttributes(attribute.String("retrieval.cache", kind))
+	if hit {
+		m.hits.Add(ctx, count, attrs)
+		return
+	}
+	m.misses.Add(ctx, count, attrs)
+}
+
+func newCacheMetrics() *cacheMetrics {
+	meter := otel.Meter("ragtime-backend/retrieval/cache")
+
+	hits, err := meter.Int64Counter(
+		"ragtime.retrieval.cache.hits",
+		metric.WithDescription("Retrieval cache lookups served from memory."),
+		metric.WithUnit("{lookup}"),
+	)
+	if err != nil {
+		logger.Warn("failed to initialize retrieval cache hits metric", "error", err)
+		return nil
+	}
+
+	misses, err := meter.Int64Counter(
+		"ragtime.retrieval.cache.misses",
+		metric.WithDescription("Retrieval cache lookups delegated to the repository."),
+		metric.WithUnit("{lookup}"),
+	)
+	if err != nil {
+		logger.Warn("failed to initialize retrieval cache misses metric", "error", err)
+		return nil
+	}
+
+	return &cacheMetrics{
+		hits:   hits,
+		misses: misses,
+	}
+}
+
+var (
+	_ Layer       = (*LRULayer)(nil)
+	_ Invalidator = (*LRULayer)(nil)
+)
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"ragtime-backend/internal/retrieval"
+	"ragtime-backend/internal/retrieval/repository"
+)
+
+type countingStore struct {
+	repository.Store
+	searches int
+	metadata map[string]any
+}
+
+func (s *countingStore) GetKnowledgeBaseMetadata(context.Context, string) (map[string]any, error) {
+	return s.metadata, nil
+}
+
+func (s *countingStore) SaveTunedWeight(context.Context, string, string, retrieval.TunedWeight, bool) (bool, error) {
+	return true, nil
+}
+
+func (s *countingStore) SearchLexical(context.Context, retrieval.SearchParams) ([]retrieval.ScoredChunk, error) {
+	s.searches++
+	return []retrieval.ScoredChunk{{ChunkID: "c1", Score: 1}}, nil
+}
+
+func TestLRULayerCachesSearchUntilInvalidated(t *testing.T) {
+	store := &countingStore{}
+	layer := NewLRULayer(store, Config{Size: 8})
+	params := retrieval.SearchParams{KnowledgeBaseID: "kb-1", Query: "alpha", Limit: 10}
+
+	for i := 0; i < 2; i++ {
+		if _, err := layer.SearchLexical(context.Background(), params); err != nil {
+			t.Fatalf("SearchLexical() error = %v", err)
+		}
+	}
+	if store.searches != 1 {
+		t.Fatalf("store searches = %d, want 1 after cache hit", store.searches)
+	}
+
+	layer.InvalidateKnowledgeBase("kb-2")
+	if _, err := layer.SearchLexical(context.Background(), params); err != nil {
+		t.Fatalf("SearchLexical() error = %v", err)
+	}
+	if store.searches != 1 {
+		t.Fatalf("store searches = %d, want other KB invalidation to keep entry", store.searches)
+	}
+
+	layer.InvalidateKnowledgeBase("kb-1")
+	if _, err := layer.SearchLexical(context.Background(), params); err != nil {
+		t.Fatalf("SearchLexical() error = %v", err)
+	}
+	if store.searches != 2 {
+		t.Fatalf("store searches = %d, want reload after invalidation", store.searches)
+	}
+}
+
+func TestLRULayerInvalidatesOnKnowledgeBaseSettingsChange(t *testing.T) {
+	ctx := context.Background()
+	store := &countingStore{metadata: map[string]any{"text_search_config": "english"}}
+	layer := NewLRULayer(store, Config{Size: 8})
+	params := retrieval.SearchParams{KnowledgeBaseID: "kb-1", Query: "alpha", Limit: 10}
+	search := func() {
+		t.Helper()
+		if _, err := layer.GetKnowledgeBaseMetadata(ctx, "kb-1"); err != nil {
+			t.Fatalf("GetKnowledgeBaseMetadata() error = %v", err)
+		}
+		if _, err := layer.SearchLexical(ctx, params); err != nil {
+			t.Fatalf("SearchLexical() error = %v", err)
+		}
+	}
+
+	search()
+	search()
+	if store.searches != 1 {
+		t.Fatalf("store searches = %d, want 1 while settings are unchanged", store.searches)
+	}
+
+	store.metadata = map[string]any{"text_search_config": "german"}
+	search()
+	if store.searches != 2 {
+		t.Fatalf("store searches = %d, want reload after a settings change", store.searches)
+	}
+
+	if _, err := layer.SaveTunedWeight(ctx, "kb-1", "code", retrieval.TunedWeight{}, false); err != nil {
+		t.Fatalf("SaveTunedWeight() error = %v", err)
+	}
+	search()
+	if store.searches != 3 {
+		t.Fatalf("store searches = %d, want reload after saving a tuned weight", store.searches)
+	}
+}
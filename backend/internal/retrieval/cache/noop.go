@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"time"
 
 	"ragtime-backend/internal/retrieval"
 	"ragtime-backend/internal/retrieval/repository"
@@ -20,8 +21,8 @@ func (c *NoopLayer) InsertRetrievalRequest(ctx context.Context, req retrieval.Re
 	return c.store.InsertRetrievalRequest(ctx, req)
 }
 
-func (c *NoopLayer) UpdateRetrievalRequest(ctx context.Context, requestID string, resultCount int, latencyMS int64, emptyResult bool) error {
-	return c.store.UpdateRetrievalRequest(ctx, requestID, resultCount, latencyMS, emptyResult)
+func (c *NoopLayer) UpdateRetrievalRequest(ctx context.Context, requestID string, resultCount int, latencyMS int64, emptyResult bool, emptyReason string) error {
+	return c.store.UpdateRetrievalRequest(ctx, requestID, resultCount, latencyMS, emptyResult, emptyReason)
 }
 
 func (c *NoopLayer) InsertRetrievalResults(ctx context.Context, results []retrieval.RetrievalResultRecord) error {
@@ -36,6 +37,10 @@ func (c *NoopLayer) SearchLexical(ctx context.Context, params retrieval.SearchPa
 	return c.store.SearchLexical(ctx, params)
 }
 
+func (c *NoopLayer) ExplainChunk(ctx context.Context, params retrieval.SearchParams, chunkID string) (*retrieval.ChunkExplanation, error) {
+	return c.store.ExplainChunk(ctx, params, chunkID)
+}
+
 func (c *NoopLayer) GetChunksWithDocuments(ctx context.Context, chunkIDs []string) ([]retrieval.ChunkRecord, error) {
 	return c.store.GetChunksWithDocuments(ctx, chunkIDs)
 }
@@ -48,4 +53,96 @@ func (c *NoopLayer) GetChunksByDocumentVersionRange(ctx context.Context, documen
 	return c.store.GetChunksByDocumentVersionRange(ctx, documentVersionID, startSeq, endSeq)
 }
 
+func (c *NoopLayer) GetChunkVectors(ctx context.Context, vectorDimension int, chunkIDs []string) (map[string][]float32, error) {
+	return c.store.GetChunkVectors(ctx, vectorDimension, chunkIDs)
+}
+
+func (c *NoopLayer) GetChunkDocumentIDs(ctx context.Context, chunkIDs []string) (map[string]string, error) {
+	return c.store.GetChunkDocumentIDs(ctx, chunkIDs)
+}
+
+func (c *NoopLayer) GetLexemes(ctx context.Context, knowledgeBaseID string, words []string) (map[string][]string, error) {
+	return c.store.GetLexemes(ctx, knowledgeBaseID, words)
+}
+
+func (c *NoopLayer) SaveRetrievalRanking(ctx context.Context, requestID string, candidates []retrieval.RankedCandidate) error {
+	return c.store.SaveRetrievalRanking(ctx, requestID, candidates)
+}
+
+func (c *NoopLayer) GetRetrievalRanking(ctx context.Context, knowledgeBaseID string, requestID string) (*retrieval.RetrievalRanking, error) {
+	return c.store.GetRetrievalRanking(ctx, knowledgeBaseID, requestID)
+}
+
+func (c *NoopLayer) GetKnowledgeBaseMetadata(ctx context.Context, knowledgeBaseID string) (map[string]any, error) {
+	return c.store.GetKnowledgeBaseMetadata(ctx, knowledgeBaseID)
+}
+
+func (c *NoopLayer) InsertCalibrationJudgments(ctx context.Context, knowledgeBaseID string, judgments []retrieval.RelevanceJudgment) (int, error) {
+	return c.store.InsertCalibrationJudgments(ctx, knowledgeBaseID, judgments)
+}
+
+func (c *NoopLayer) GetCalibrationSamples(ctx context.Context, knowledgeBaseID string, fusionMethod string) ([]retrieval.CalibrationSample, error) {
+	return c.store.GetCalibrationSamples(ctx, knowledgeBaseID, fusionMethod)
+}
+
+func (c *NoopLayer) SaveScoreCalibration(ctx context.Context, calibration retrieval.ScoreCalibration) error {
+	return c.store.SaveScoreCalibration(ctx, calibration)
+}
+
+func (c *NoopLayer) GetScoreCalibration(ctx context.Context, knowledgeBaseID string, fusionMethod string) (*retrieval.ScoreCalibration, error) {
+	return c.store.GetScoreCalibration(ctx, knowledgeBaseID, fusionMethod)
+}
+
+func (c *NoopLayer) ListTopQueries(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.QueryStat, error) {
+	return c.store.ListTopQueries(ctx, query)
+}
+
+func (c *NoopLayer) ListZeroResultQueries(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.ZeroResultQuery, error) {
+	return c.store.ListZeroResultQueries(ctx, query)
+}
+
+func (c *NoopLayer) ListLatencyPercentiles(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.LatencyBucket, error) {
+	return c.store.ListLatencyPercentiles(ctx, query)
+}
+
+func (c *NoopLayer) ListProfileDistribution(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.ProfileUsage, []retrieval.WeightUsage, error) {
+	return c.store.ListProfileDistribution(ctx, query)
+}
+
+func (c *NoopLayer) ListMostRetrievedDocuments(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.DocumentUsage, error) {
+	return c.store.ListMostRetrievedDocuments(ctx, query)
+}
+
+func (c *NoopLayer) ListNeverRetrievedDocuments(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.DocumentUsage, error) {
+	return c.store.ListNeverRetrievedDocuments(ctx, query)
+}
+
+func (c *NoopLayer) InsertFeedback(ctx context.Context, submission retrieval.FeedbackSubmission) (int, error) {
+	return c.store.InsertFeedback(ctx, submission)
+}
+
+func (c *NoopLayer) GetFeedbackSummary(ctx context.Context, query retrieval.AnalyticsQuery) (*retrieval.FeedbackSummary, error) {
+	return c.store.GetFeedbackSummary(ctx, query)
+}
+
+func (c *NoopLayer) ListChunkFeedback(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.ChunkFeedback, error) {
+	return c.store.ListChunkFeedback(ctx, query)
+}
+
+func (c *NoopLayer) ListKnowledgeBaseIDs(ctx context.Context) ([]string, error) {
+	return c.store.ListKnowledgeBaseIDs(ctx)
+}
+
+func (c *NoopLayer) ListTuningQueries(ctx context.Context, knowledgeBaseID string, since time.Time) ([]retrieval.TuningQuery, error) {
+	return c.store.ListTuningQueries(ctx, knowledgeBaseID, since)
+}
+
+func (c *NoopLayer) SaveTunedWeight(ctx context.Context, knowledgeBaseID string, profile string, tuned retrieval.TunedWeight, skipPinned bool) (bool, error) {
+	return c.store.SaveTunedWeight(ctx, knowledgeBaseID, profile, tuned, skipPinned)
+}
+
+func (c *NoopLayer) DeleteTunedWeights(ctx context.Context, knowledgeBaseID string, profile string) error {
+	return c.store.DeleteTunedWeights(ctx, knowledgeBaseID, profile)
+}
+
 var _ Layer = (*NoopLayer)(nil)
@@ -0,0 +1,137 @@
+package retrieval
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+const (
+	// MinCalibrationSamples is the fewest judged results a calibration is fitted from.
+	MinCalibrationSamples = 10
+	MaxJudgmentsPerUpload = 1000
+	calibrationIterations = 100
+)
+
+var (
+	ErrMissingJudgments      = errors.New("judgments is required")
+	ErrTooManyJudgments      = errors.New("judgments exceeds maximum of 1000")
+	ErrInvalidJudgment       = errors.New("each judgment requires query_id and chunk_id")
+	ErrInsufficientJudgments = errors.New("calibration needs at least 10 judged results including relevant and irrelevant ones")
+)
+
+// RelevanceJudgment labels one logged result of a query as relevant or not.
+type RelevanceJudgment struct {
+	QueryID  string `json:"query_id"`
+	ChunkID  string `json:"chunk_id"`
+	Relevant bool   `json:"relevant"`
+}
+
+// CalibrationSample is the logged final score of a judged result.
+type CalibrationSample struct {
+	Score    float64
+	Relevant bool
+}
+
+// ScoreCalibration maps a knowledge base's fused final scores to a 0-1
+// confidence with Platt scaling: 1 / (1 + exp(-(Slope*score + Intercept))).
+// Scores of linear and rrf fusion differ in scale, so each method has its own.
+type ScoreCalibration struct {
+	KnowledgeBaseID string    `json:"knowledgebase_id"`
+	FusionMethod    string    `json:"fusion_method"`
+	Slope           float64   `json:"slope"`
+	Intercept       float64   `json:"intercept"`
+	SampleCount     int       `json:"sample_count"`
+	FittedAt        time.Time `json:"fitted_at"`
+}
+
+// Confidence returns the calibrated probability that a result with the given
+// final score is relevant.
+func (c ScoreCalibration) Confidence(score float64) float64 {
+	return sigmoid(c.Slope*score + c.Intercept)
+}
+
+// FitCalibration fits Platt scaling parameters to judged samples with Newton's
+// method, using Platt's smoothed targets so a small sample set does not
+// produce an overconfident fit.
+func FitCalibration(samples []CalibrationSample) (slope float64, intercept float64, err error) {
+	var positives, negatives float64
+	for _, sample := range samples {
+		if sample.Relevant {
+			positives++
+		} else {
+			negatives++
+		}
+	}
+	if len(samples) < MinCalibrationSamples || positives == 0 || negatives == 0 {
+		return 0, 0, ErrInsufficientJudgments
+	}
+
+	highTarget := (positives + 1) / (positives + 2)
+	lowTarget := 1 / (negatives + 2)
+	targets := make([]float64, len(samples))
+	for i, sample := range samples {
+		targets[i] = lowTarget
+		if sample.Relevant {
+			targets[i] = highTarget
+		}
+	}
+
+	intercept = math.Log((positives + 1) / (negatives + 1))
+	loss := calibrationLoss(samples, targets, slope, intercept)
+	for range calibrationIterations {
+		// Gradient and Hessian of the log loss in (slope, intercept), with a
+		// small ridge term keeping the Hessian invertible.
+		var gA, gB float64
+		hAA, hAB, hBB := 1e-12, 0.0, 1e-12
+		for i, sample := range samples {
+			p := sigmoid(slope*sample.Score + intercept)
+			d := p - targets[i]
+			w := p * (1 - p)
+			gA += d * sample.Score
+			gB += d
+			hAA += w * sample.Score * sample.Score
+			hAB += w * sample.Score
+			hBB += w
+		}
+		det := hAA*hBB - hAB*hAB
+		if det <= 0 {
+			break
+		}
+		stepA := (hBB*gA - hAB*gB) / det
+		stepB := (hAA*gB - hAB*gA) / det
+
+		// Halve the Newton step until the loss stops increasing.
+		improved := false
+		for scale := 1.0; scale >= 1e-8; scale /= 2 {
+			nextSlope := slope - scale*stepA
+			nextIntercept := intercept - scale*stepB
+			if nextLoss := calibrationLoss(samples, targets, nextSlope, nextIntercept); nextLoss <= loss {
+				slope, intercept, loss = nextSlope, nextIntercept, nextLoss
+				improved = true
+				break
+			}
+		}
+		if !improved || (math.Abs(stepA) < 1e-10 && math.Abs(stepB) < 1e-10) {
+			break
+		}
+	}
+	if math.IsNaN(slope) || math.IsNaN(intercept) || math.IsInf(slope, 0) || math.IsInf(intercept, 0) {
+		return 0, 0, ErrInsufficientJudgments
+	}
+	return slope, intercept, nil
+}
+
+func calibrationLoss(samples []CalibrationSample, targets []float64, slope float64, intercept float64) float64 {
+	var loss float64
+	for i, sample := range samples {
+		z := slope*sample.Score + intercept
+		// log(1+exp(z)) - t*z, written to stay finite for large |z|.
+		loss += math.Max(z, 0) + math.Log1p(math.Exp(-math.Abs(z))) - targets[i]*z
+	}
+	return loss
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
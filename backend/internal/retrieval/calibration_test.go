@@ -0,0 +1,40 @@
+package retrieval
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFitCalibration_HigherScoresMoreConfident(t *testing.T) {
+	var samples []CalibrationSample
+	for i := 0; i < 10; i++ {
+		score := float64(i) / 10
+		samples = append(samples,
+			CalibrationSample{Score: score, Relevant: i >= 6},
+			CalibrationSample{Score: score + 0.05, Relevant: i >= 4},
+		)
+	}
+
+	slope, intercept, err := FitCalibration(samples)
+	if err != nil {
+		t.Fatalf("FitCalibration() error = %v", err)
+	}
+	calibration := ScoreCalibration{Slope: slope, Intercept: intercept}
+	low, mid, high := calibration.Confidence(0.1), calibration.Confidence(0.5), calibration.Confidence(0.9)
+	if !(low < 0.2 && high > 0.8 && low < mid && mid < high) {
+		t.Fatalf("confidences = %v, %v, %v; want increasing from low to high", low, mid, high)
+	}
+}
+
+func TestFitCalibration_RequiresBothClasses(t *testing.T) {
+	samples := make([]CalibrationSample, MinCalibrationSamples)
+	for i := range samples {
+		samples[i] = CalibrationSample{Score: float64(i) / 10, Relevant: true}
+	}
+	if _, _, err := FitCalibration(samples); !errors.Is(err, ErrInsufficientJudgments) {
+		t.Fatalf("FitCalibration() error = %v, want ErrInsufficientJudgments", err)
+	}
+	if _, _, err := FitCalibration(samples[:3]); !errors.Is(err, ErrInsufficientJudgments) {
+		t.Fatalf("FitCalibration() error = %v, want ErrInsufficientJudgments", err)
+	}
+}
@@ -0,0 +1,70 @@
+package retrieval
+
+import "errors"
+
+const (
+	DefaultContextTokenBudget = 2000
+	MaxContextTokenBudget     = 32000
+)
+
+var ErrInvalidTokenBudget = errors.New("token_budget must be between 1 and 32000")
+
+// ContextRequest retrieves results with the options of Request and packs
+// them, with AdjacentBefore and AdjacentAfter neighbouring chunks of each, into
+// one context of at most TokenBudget estimated tokens.
+type ContextRequest struct {
+	Request
+	TokenBudget    int
+	AdjacentBefore int
+	AdjacentAfter  int
+}
+
+// ContextSource is a document quoted in a packed context. Index is the number
+// in the "[n]" header that precedes its text.
+type ContextSource struct {
+	Index             int     `json:"index"`
+	DocumentID        string  `json:"document_id"`
+	DocumentVersionID string  `json:"document_version_id"`
+	DocumentPath      string  `json:"document_path"`
+	DocumentTitle     *string `json:"document_title,omitempty"`
+}
+
+// ContextCitation maps the rune span [StartRune, EndRune) of the packed
+// context to the chunk it came from. Spans of merged overlapping chunks
+// overlap too. Retrieved is false for neighbours added only for context.
+type ContextCitation struct {
+	StartRune     int    `json:"start_rune"`
+	EndRune       int    `json:"end_rune"`
+	ChunkID       string `json:"chunk_id"`
+	ChunkSequence int32  `json:"chunk_sequence"`
+	Source        int    `json:"source"`
+	Retrieved     bool   `json:"retrieved"`
+}
+
+// ContextResponse is a packed context. Truncated reports that results or
+// neighbours were left out to stay within TokenBudget.
+type ContextResponse struct {
+	RequestID       string            `json:"request_id"`
+	QueryID         string            `json:"query_id"`
+	KnowledgeBaseID string            `json:"kb_id"`
+	Query           string            `json:"query"`
+	TokenBudget     int               `json:"token_budget"`
+	TokenCount      int               `json:"token_count"`
+	ResultCount     int               `json:"result_count"`
+	PackedResults   int               `json:"packed_results"`
+	Truncated       bool              `json:"truncated"`
+	Context         string            `json:"context"`
+	Sources         []ContextSource   `json:"sources"`
+	Citations       []ContextCitation `json:"citations"`
+	EmptyReason     string            `json:"empty_reason,omitempty"`
+}
+
+func ValidateContextRequest(req ContextRequest) error {
+	if req.TokenBudget < 1 || req.TokenBudget > MaxContextTokenBudget {
+		return ErrInvalidTokenBudget
+	}
+	if req.AdjacentBefore < 0 || req.AdjacentBefore > 10 || req.AdjacentAfter < 0 || req.AdjacentAfter > 10 {
+		return ErrInvalidAdjacentRange
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package retrieval
 
 import (
 	"errors"
+	"math"
 	"strings"
 	"time"
 )
@@ -16,20 +17,82 @@ const (
 	RetrievalProfileSemantic = "semantic"
 	MaxTopK                  = 50
 	MaxHydrateChunkIDs       = 100
+	FusionLinear             = "linear"
+	FusionRRF                = "rrf"
+	DefaultFusion            = FusionLinear
+	DefaultRRFK              = 60
+	MaxRRFK                  = 1000
+	DefaultRerankTopN        = 20
+	MaxRerankTopN            = 100
+	MaxPaginatedCandidates   = 500
+	MaxFederatedKBs          = 10
+	DefaultKBWeight          = 1.0
+	MaxKBWeight              = 10.0
+	DefaultCursorTTL         = 15 * time.Minute
+	LexicalScorerTSRank      = "ts_rank"
+	LexicalScorerBM25        = "bm25"
+	DefaultLexicalScorer     = LexicalScorerTSRank
+	DefaultBM25K1            = 1.2
+	DefaultBM25B             = 0.75
+	MaxBM25K1                = 3.0
+	CollapseDocument         = "document"
+	HighlightLexical         = "lexical"
+	HighlightSemantic        = "semantic"
+	MaxHighlightSnippets     = 3
+	HighlightSnippetRunes    = 200
+	MaxMinLexicalHits        = 20
+	MaxHNSWEfSearch          = 1000
+	MaxIVFFlatProbes         = 32768
+)
+
+// Reasons recorded when a request returns no results: nothing matched the
+// query, or the named threshold dropped the last candidate.
+const (
+	EmptyReasonNoCandidates     = "no_candidates"
+	EmptyReasonMinSemanticScore = "min_semantic_score"
+	EmptyReasonMinLexicalHits   = "min_lexical_hits"
+	EmptyReasonMinFinalScore    = "min_final_score"
+	// EmptyReasonOutranked marks a federated query's knowledge base whose
+	// candidates all ranked below other knowledge bases' results.
+	EmptyReasonOutranked = "outranked"
 )
 
 var (
-	ErrNilRepository        = errors.New("repository is required")
-	ErrNilEmbedder          = errors.New("embedding client is required")
-	ErrMissingKnowledgeBase = errors.New("knowledgebase_id is required")
-	ErrMissingQuery         = errors.New("query is required")
-	ErrInvalidTopK          = errors.New("top_k must be between 1 and 50")
-	ErrInvalidHybridWeight  = errors.New("hybrid_weight must be between 0 and 1")
-	ErrInvalidProfile       = errors.New("retrieval_profile must be one of: auto, exact, balanced, semantic")
-	ErrInvalidCreatedAfter  = errors.New("created_after must be before created_before")
-	ErrMissingChunkIDs      = errors.New("chunk_ids is required")
-	ErrTooManyChunkIDs      = errors.New("chunk_ids exceeds maximum of 100")
-	ErrInvalidAdjacentRange = errors.New("adjacent_before and adjacent_after must be between 0 and 10")
+	ErrNilRepository         = errors.New("repository is required")
+	ErrNilEmbedder           = errors.New("embedding client is required")
+	ErrMissingKnowledgeBase  = errors.New("knowledgebase_id is required")
+	ErrMissingQuery          = errors.New("query is required")
+	ErrInvalidTopK           = errors.New("top_k must be between 1 and 50")
+	ErrInvalidHybridWeight   = errors.New("hybrid_weight must be between 0 and 1")
+	ErrInvalidProfile        = errors.New("retrieval_profile must be one of: auto, exact, balanced, semantic")
+	ErrInvalidCreatedAfter   = errors.New("created_after must be before created_before")
+	ErrMissingChunkIDs       = errors.New("chunk_ids is required")
+	ErrTooManyChunkIDs       = errors.New("chunk_ids exceeds maximum of 100")
+	ErrInvalidAdjacentRange  = errors.New("adjacent_before and adjacent_after must be between 0 and 10")
+	ErrInvalidFusion         = errors.New("fusion must be one of: linear, rrf")
+	ErrInvalidRRFK           = errors.New("rrf_k must be between 1 and 1000")
+	ErrInvalidRerankTopN     = errors.New("rerank_top_n must be between 1 and 100")
+	ErrRerankerUnavailable   = errors.New("rerank requested but no reranker is configured")
+	ErrInvalidDiversity      = errors.New("diversity must be between 0 and 1")
+	ErrInvalidPageSize       = errors.New("page_size must be between 1 and 50")
+	ErrInvalidCursor         = errors.New("cursor is invalid")
+	ErrCursorExpired         = errors.New("cursor has expired")
+	ErrMissingKBIDs          = errors.New("kb_ids is required")
+	ErrTooManyKBIDs          = errors.New("kb_ids exceeds maximum of 10")
+	ErrDuplicateKBID         = errors.New("kb_ids must be unique")
+	ErrInvalidKBWeight       = errors.New("kb_weights must be greater than 0 and at most 10")
+	ErrUnknownKBWeight       = errors.New("kb_weights references a kb not listed in kb_ids")
+	ErrInvalidLexicalScorer  = errors.New("lexical_scorer must be one of: ts_rank, bm25")
+	ErrInvalidBM25K1         = errors.New("bm25.k1 must be between 0 and 3")
+	ErrInvalidBM25B          = errors.New("bm25.b must be between 0 and 1")
+	ErrInvalidCollapse       = errors.New("collapse must be: document")
+	ErrInvalidMaxPerDocument = errors.New("max_per_document must be between 1 and 50")
+	ErrInvalidMinSemantic    = errors.New("min_semantic_score must be between 0 and 1")
+	ErrInvalidMinFinal       = errors.New("min_final_score must be between 0 and 1")
+	ErrInvalidMinLexicalHits = errors.New("min_lexical_hits must be between 0 and 20")
+	ErrInvalidHNSWEfSearch   = errors.New("ann.hnsw_ef_search must be between 1 and 1000")
+	ErrInvalidIVFFlatProbes  = errors.New("ann.ivfflat_probes must be between 1 and 32768")
+	ErrConflictingANN        = errors.New("ann.exact cannot be combined with hnsw_ef_search or ivfflat_probes")
 )
 
 type Filters struct {
@@ -39,6 +102,23 @@ type Filters struct {
 	Tags          []string
 	CreatedAfter  *time.Time
 	CreatedBefore *time.Time
+	// SectionPathPrefix, Frontmatter and BreadcrumbContains read markdown
+	// metadata that chunking only stores in chunks.metadata for versions
+	// chunked after migration 016; older chunks have neither key and match
+	// none of them until their documents are uploaded again.
+	//
+	// SectionPathPrefix matches chunks whose markdown breadcrumb equals the
+	// given heading path or is nested beneath it ("Guide > Install").
+	SectionPathPrefix *string
+	// Frontmatter matches chunks whose markdown frontmatter has every key
+	// equal to the given value, or listing it when the key holds a list.
+	// Values are compared as text, as the chunker stores them.
+	Frontmatter map[string]any
+	// BreadcrumbContains matches chunks whose breadcrumb contains the given
+	// text, case-insensitively.
+	BreadcrumbContains *string
+	// Expr is the optional boolean metadata filter, ANDed with the fields above.
+	Expr *FilterExpr
 }
 
 type Request struct {
@@ -52,12 +132,77 @@ type Request struct {
 	SemanticWeightSet bool
 	Debug             bool
 	Filters           Filters
+	Fusion            string
+	RRFK              int
+	RRFKSet           bool
+	Rerank            bool
+	RerankTopN        int
+	// Diversity is the MMR lambda: 1 ranks purely by relevance, 0 purely by novelty.
+	Diversity    float64
+	DiversitySet bool
+	// PageSize enables cursor pagination; Cursor continues a previous request's ranking.
+	PageSize int
+	Cursor   string
+	// LexicalScorer picks ts_rank or bm25; unset values fall back to the
+	// knowledge base settings and then to the defaults.
+	LexicalScorer string
+	BM25K1        float64
+	BM25K1Set     bool
+	BM25B         float64
+	BM25BSet      bool
+	// FieldWeights weigh title, breadcrumb and path matches against content;
+	// a zero value falls back to the knowledge base settings.
+	FieldWeights FieldWeights
+	// Collapse "document" folds a document's lower-ranked chunks into its
+	// best one; MaxPerDocument caps how many chunks of a document are ranked.
+	Collapse       string
+	MaxPerDocument int
+	// Highlight adds snippets with matched-term offsets to each result.
+	Highlight bool
+	// MinSemanticScore drops candidates whose raw cosine similarity is lower;
+	// candidates found only by lexical search have none and are dropped too.
+	MinSemanticScore    float64
+	MinSemanticScoreSet bool
+	// MinFinalScore drops candidates whose fused final score is lower.
+	MinFinalScore    float64
+	MinFinalScoreSet bool
+	// MinLexicalHits drops candidates whose content matches fewer distinct
+	// query lexemes.
+	MinLexicalHits int
+	// ANN tunes or bypasses the vector index for the semantic search.
+	ANN ANNOptions
+	// Freshness boosts newer document versions; a zero value falls back to
+	// the knowledge base settings.
+	Freshness FreshnessOptions
+	// TunedWeights are the knowledge base's semantic weights per auto-profile
+	// class; the auto profile uses them in place of the defaults. They are
+	// fitted on linear fusion and only attached to linear fusion requests.
+	TunedWeights map[string]float64
+}
+
+// ANNOptions trade recall against latency in the semantic search. Exact
+// scans every embedding instead of using a vector index; HNSWEfSearch and
+// IVFFlatProbes set hnsw.ef_search and ivfflat.probes for the search and
+// apply to whichever of the HNSW and ivfflat indexes the planner uses. Zero
+// values leave the server settings in place.
+type ANNOptions struct {
+	Exact         bool `json:"exact,omitempty"`
+	HNSWEfSearch  int  `json:"hnsw_ef_search,omitempty"`
+	IVFFlatProbes int  `json:"ivfflat_probes,omitempty"`
+}
+
+// IsZero reports whether no option is set.
+func (o ANNOptions) IsZero() bool {
+	return o == ANNOptions{}
 }
 
 type Score struct {
-	Semantic float64 `json:"semantic"`
-	Lexical  float64 `json:"lexical"`
-	Final    float64 `json:"final"`
+	Semantic float64  `json:"semantic"`
+	Lexical  float64  `json:"lexical"`
+	Rerank   *float64 `json:"rerank,omitempty"`
+	// Freshness is the decay boost included in Final.
+	Freshness *float64 `json:"freshness,omitempty"`
+	Final     float64  `json:"final"`
 }
 
 type Citation struct {
@@ -74,6 +219,7 @@ type Citation struct {
 
 type Result struct {
 	ChunkID           string         `json:"chunk_id"`
+	KnowledgeBaseID   string         `json:"kb_id,omitempty"`
 	DocumentID        string         `json:"document_id"`
 	DocumentVersionID string         `json:"document_version_id"`
 	DocumentPath      string         `json:"document_path"`
@@ -90,6 +236,37 @@ type Result struct {
 	Score             float64        `json:"score"`
 	ScoreDetail       Score          `json:"score_detail"`
 	Offsets           *Offsets       `json:"offsets,omitempty"`
+	Siblings          []Sibling      `json:"siblings,omitempty"`
+	Highlights        []Highlight    `json:"highlights,omitempty"`
+	// Confidence is the calibrated probability that the result is relevant,
+	// set once the knowledge base has a calibration for the fusion method.
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+// Highlight is a snippet of a result's Content explaining why it matched.
+// Lexical snippets carry the matched terms; semantic snippets are the
+// sentence window closest to the query. All offsets are rune offsets into
+// Content.
+type Highlight struct {
+	Text      string      `json:"text"`
+	StartRune int         `json:"start_rune"`
+	EndRune   int         `json:"end_rune"`
+	Source    string      `json:"source"`
+	Score     *float64    `json:"score,omitempty"`
+	Matches   []TermMatch `json:"matches,omitempty"`
+}
+
+// TermMatch is one word of the content that matched a query lexeme.
+type TermMatch struct {
+	Lexeme    string `json:"lexeme"`
+	StartRune int    `json:"start_rune"`
+	EndRune   int    `json:"end_rune"`
+}
+
+// Sibling is a chunk folded into a collapsed result from the same document.
+type Sibling struct {
+	ChunkID string  `json:"chunk_id"`
+	Score   float64 `json:"score"`
 }
 
 type Response struct {
@@ -104,6 +281,8 @@ type Response struct {
 	LatencyMS       int64          `json:"latency_ms"`
 	Results         []Result       `json:"results"`
 	Passages        []Result       `json:"passages"`
+	NextCursor      string         `json:"next_cursor,omitempty"`
+	EmptyReason     string         `json:"empty_reason,omitempty"`
 	Debug           *DebugMetadata `json:"debug,omitempty"`
 }
 
@@ -114,13 +293,65 @@ type Offsets struct {
 }
 
 type DebugMetadata struct {
-	RetrievalProfileEffective string         `json:"retrieval_profile_effective"`
-	SemanticWeightEffective   float64        `json:"semantic_weight_effective"`
-	AutoSignalsDetected       []string       `json:"auto_signals_detected,omitempty"`
-	LexicalCandidates         int            `json:"lexical_candidates"`
-	SemanticCandidates        int            `json:"semantic_candidates"`
-	RerankerApplied           bool           `json:"reranker_applied"`
-	FiltersApplied            map[string]any `json:"filters_applied,omitempty"`
+	RetrievalProfileEffective string          `json:"retrieval_profile_effective"`
+	SemanticWeightEffective   float64         `json:"semantic_weight_effective"`
+	FusionMethod              string          `json:"fusion_method"`
+	RRFK                      int             `json:"rrf_k,omitempty"`
+	AutoSignalsDetected       []string        `json:"auto_signals_detected,omitempty"`
+	LexicalCandidates         int             `json:"lexical_candidates"`
+	SemanticCandidates        int             `json:"semantic_candidates"`
+	QueryEmbeddingCached      bool            `json:"query_embedding_cached"`
+	RerankerApplied           bool            `json:"reranker_applied"`
+	RerankCandidates          int             `json:"rerank_candidates,omitempty"`
+	DiversityLambda           *float64        `json:"diversity_lambda,omitempty"`
+	DiversityDisplaced        *int            `json:"diversity_displaced,omitempty"`
+	FiltersApplied            map[string]any  `json:"filters_applied,omitempty"`
+	ParsedQuery               *ParsedQuery    `json:"parsed_query,omitempty"`
+	CollapsedChunks           *int            `json:"collapsed_chunks,omitempty"`
+	LexicalScorer             string          `json:"lexical_scorer,omitempty"`
+	BM25K1                    *float64        `json:"bm25_k1,omitempty"`
+	BM25B                     *float64        `json:"bm25_b,omitempty"`
+	ThresholdDropped          map[string]int  `json:"threshold_dropped,omitempty"`
+	CalibrationApplied        bool            `json:"calibration_applied,omitempty"`
+	ANN                       *ANNOptions     `json:"ann,omitempty"`
+	Freshness                 *FreshnessDebug `json:"freshness,omitempty"`
+	FieldWeights              *FieldWeights   `json:"field_weights,omitempty"`
+}
+
+// FederatedTarget is one knowledge base searched by a federated query.
+type FederatedTarget struct {
+	KnowledgeBaseID string
+	Weight          float64
+}
+
+// FederatedRequest runs one query across several knowledge bases. Request
+// carries the shared query options; its KnowledgeBaseID is ignored, and so
+// are the rerank, diversity, score threshold, collapse and freshness options,
+// which only single knowledge base retrievals apply.
+type FederatedRequest struct {
+	Request
+	Targets []FederatedTarget
+}
+
+// FederatedKnowledgeBase summarises one knowledge base's share of a federated query.
+type FederatedKnowledgeBase struct {
+	KnowledgeBaseID string         `json:"kb_id"`
+	RequestID       string         `json:"request_id"`
+	Weight          float64        `json:"weight"`
+	ResultCount     int            `json:"result_count"`
+	Debug           *DebugMetadata `json:"debug,omitempty"`
+}
+
+type FederatedResponse struct {
+	FederatedRequestID string                   `json:"federated_request_id"`
+	Query              string                   `json:"query"`
+	TopK               int                      `json:"top_k"`
+	HybridWeight       float64                  `json:"hybrid_weight"`
+	ResultCount        int                      `json:"result_count"`
+	LatencyMS          int64                    `json:"latency_ms"`
+	KnowledgeBases     []FederatedKnowledgeBase `json:"knowledge_bases"`
+	Results            []Result                 `json:"results"`
+	Passages           []Result                 `json:"passages"`
 }
 
 type HydrateRequest struct {
@@ -128,19 +359,41 @@ type HydrateRequest struct {
 	ChunkIDs        []string
 	AdjacentBefore  int
 	AdjacentAfter   int
+	// Merge stitches contiguous chunks of a document version into Spans.
+	Merge bool
 }
 
 type HydrateResponse struct {
-	KnowledgeBaseID string   `json:"kb_id"`
-	ChunkCount      int      `json:"chunk_count"`
-	Chunks          []Result `json:"chunks"`
+	KnowledgeBaseID string       `json:"kb_id"`
+	ChunkCount      int          `json:"chunk_count"`
+	Chunks          []Result     `json:"chunks"`
+	SpanCount       *int         `json:"span_count,omitempty"`
+	Spans           []MergedSpan `json:"spans,omitempty"`
+}
+
+// MergedSpan is a run of contiguous chunks of one document version whose
+// overlapping text appears once. StartRune and EndRune are the span's offsets
+// in the document, unset when its chunks lack document offsets; a span of
+// such chunks holds a single chunk.
+type MergedSpan struct {
+	DocumentID        string   `json:"document_id"`
+	DocumentVersionID string   `json:"document_version_id"`
+	DocumentPath      string   `json:"document_path"`
+	DocumentTitle     *string  `json:"document_title,omitempty"`
+	ChunkIDs          []string `json:"chunk_ids"`
+	StartSequence     int32    `json:"start_sequence"`
+	EndSequence       int32    `json:"end_sequence"`
+	Content           string   `json:"content"`
+	StartRune         *int     `json:"start_rune,omitempty"`
+	EndRune           *int     `json:"end_rune,omitempty"`
+	RuneLength        int      `json:"rune_length"`
 }
 
 func ValidateRequest(req Request) error {
 	if req.KnowledgeBaseID == "" {
 		return ErrMissingKnowledgeBase
 	}
-	if req.Query == "" {
+	if req.Query == "" && req.Cursor == "" {
 		return ErrMissingQuery
 	}
 	if req.TopK < 1 || req.TopK > MaxTopK {
@@ -157,9 +410,99 @@ func ValidateRequest(req Request) error {
 			return ErrInvalidCreatedAfter
 		}
 	}
+	if err := ValidateFilter(req.Filters.Expr); err != nil {
+		return err
+	}
+	if err := ValidateFrontmatterFilter(req.Filters.Frontmatter); err != nil {
+		return err
+	}
+	if req.Fusion != "" && !IsValidFusion(req.Fusion) {
+		return ErrInvalidFusion
+	}
+	if req.RRFK < 1 || req.RRFK > MaxRRFK {
+		return ErrInvalidRRFK
+	}
+	if req.RerankTopN < 0 || req.RerankTopN > MaxRerankTopN {
+		return ErrInvalidRerankTopN
+	}
+	if req.DiversitySet && (req.Diversity < 0 || req.Diversity > 1) {
+		return ErrInvalidDiversity
+	}
+	if req.PageSize < 0 || req.PageSize > MaxTopK {
+		return ErrInvalidPageSize
+	}
+	if req.LexicalScorer != "" && !IsValidLexicalScorer(req.LexicalScorer) {
+		return ErrInvalidLexicalScorer
+	}
+	if req.BM25K1Set && (req.BM25K1 < 0 || req.BM25K1 > MaxBM25K1) {
+		return ErrInvalidBM25K1
+	}
+	if req.BM25BSet && (req.BM25B < 0 || req.BM25B > 1) {
+		return ErrInvalidBM25B
+	}
+	if req.Collapse != "" && req.Collapse != CollapseDocument {
+		return ErrInvalidCollapse
+	}
+	if req.MaxPerDocument < 0 || req.MaxPerDocument > MaxTopK {
+		return ErrInvalidMaxPerDocument
+	}
+	if req.MinSemanticScoreSet && (req.MinSemanticScore < 0 || req.MinSemanticScore > 1) {
+		return ErrInvalidMinSemantic
+	}
+	if req.MinFinalScoreSet && (req.MinFinalScore < 0 || req.MinFinalScore > 1) {
+		return ErrInvalidMinFinal
+	}
+	if req.MinLexicalHits < 0 || req.MinLexicalHits > MaxMinLexicalHits {
+		return ErrInvalidMinLexicalHits
+	}
+	if req.ANN.HNSWEfSearch < 0 || req.ANN.HNSWEfSearch > MaxHNSWEfSearch {
+		return ErrInvalidHNSWEfSearch
+	}
+	if req.ANN.IVFFlatProbes < 0 || req.ANN.IVFFlatProbes > MaxIVFFlatProbes {
+		return ErrInvalidIVFFlatProbes
+	}
+	if req.ANN.Exact && (req.ANN.HNSWEfSearch > 0 || req.ANN.IVFFlatProbes > 0) {
+		return ErrConflictingANN
+	}
+	if !req.FieldWeights.IsZero() {
+		if err := ValidateFieldWeights(req.FieldWeights); err != nil {
+			return err
+		}
+	}
+	if !req.Freshness.IsZero() {
+		if err := ValidateFreshness(NormalizeFreshness(req.Freshness)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+func ValidateFederatedRequest(req FederatedRequest) error {
+	if len(req.Targets) == 0 {
+		return ErrMissingKBIDs
+	}
+	if len(req.Targets) > MaxFederatedKBs {
+		return ErrTooManyKBIDs
+	}
+	seen := make(map[string]struct{}, len(req.Targets))
+	for _, target := range req.Targets {
+		if target.KnowledgeBaseID == "" {
+			return ErrMissingKnowledgeBase
+		}
+		if _, ok := seen[target.KnowledgeBaseID]; ok {
+			return ErrDuplicateKBID
+		}
+		seen[target.KnowledgeBaseID] = struct{}{}
+		if target.Weight <= 0 || target.Weight > MaxKBWeight || math.IsNaN(target.Weight) {
+			return ErrInvalidKBWeight
+		}
+	}
+
+	shared := req.Request
+	shared.KnowledgeBaseID = req.Targets[0].KnowledgeBaseID
+	return ValidateRequest(shared)
+}
+
 func ValidateHydrateRequest(req HydrateRequest) error {
 	if req.KnowledgeBaseID == "" {
 		return ErrMissingKnowledgeBase
@@ -184,3 +527,21 @@ func IsValidRetrievalProfile(profile string) bool {
 		return false
 	}
 }
+
+func IsValidFusion(fusion string) bool {
+	switch strings.ToLower(strings.TrimSpace(fusion)) {
+	case FusionLinear, FusionRRF:
+		return true
+	default:
+		return false
+	}
+}
+
+func IsValidLexicalScorer(scorer string) bool {
+	switch strings.ToLower(strings.TrimSpace(scorer)) {
+	case LexicalScorerTSRank, LexicalScorerBM25:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,39 @@
+package retrieval
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+type cursorPayload struct {
+	RequestID string `json:"rid"`
+	Offset    int    `json:"off"`
+}
+
+// EncodeCursor returns an opaque page token pointing at a stored retrieval
+// request ranking and the offset of the next page.
+func EncodeCursor(requestID string, offset int) string {
+	encoded, err := json.Marshal(cursorPayload{RequestID: requestID, Offset: offset})
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// DecodeCursor reverses EncodeCursor, returning ErrInvalidCursor for tokens
+// that were not produced by it.
+func DecodeCursor(cursor string) (string, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(cursor))
+	if err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+	if payload.RequestID == "" || payload.Offset < 0 {
+		return "", 0, ErrInvalidCursor
+	}
+	return payload.RequestID, payload.Offset, nil
+}
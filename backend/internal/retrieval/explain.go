@@ -0,0 +1,75 @@
+package retrieval
+
+import "errors"
+
+var ErrMissingChunkID = errors.New("chunk_id is required")
+
+// Reasons a chunk is missing from one candidate list of an explained query.
+const (
+	NotCandidateNotFound        = "not_found"
+	NotCandidateOtherKB         = "other_knowledge_base"
+	NotCandidateInactiveVersion = "inactive_version"
+	NotCandidateFilteredOut     = "filtered_out"
+	NotCandidateNoEmbedding     = "no_embedding"
+	NotCandidateNoLexicalMatch  = "no_lexical_match"
+	NotCandidateBeyondLimit     = "beyond_limit"
+)
+
+// ExplainRequest asks how one chunk scores for a query run with the options
+// of Request.
+type ExplainRequest struct {
+	Request
+	ChunkID string
+}
+
+// ListExplanation is a chunk's place in one candidate list. Score is the raw
+// list score and NormalizedScore the value fused after dividing by
+// NormalizationDenominator, the list's top score.
+type ListExplanation struct {
+	Candidates               int      `json:"candidates"`
+	Limit                    int      `json:"limit"`
+	Rank                     *int     `json:"rank,omitempty"`
+	Score                    *float64 `json:"score,omitempty"`
+	NormalizationDenominator float64  `json:"normalization_denominator"`
+	NormalizedScore          *float64 `json:"normalized_score,omitempty"`
+	NotCandidateReason       string   `json:"not_candidate_reason,omitempty"`
+}
+
+type SemanticExplanation struct {
+	ListExplanation
+	CosineDistance *float64 `json:"cosine_distance,omitempty"`
+}
+
+// LexicalExplanation reports ts_rank whichever scorer ranked the list, so
+// BM25 scores can be compared with it.
+type LexicalExplanation struct {
+	ListExplanation
+	Scorer         string        `json:"scorer"`
+	BM25K1         *float64      `json:"bm25_k1,omitempty"`
+	BM25B          *float64      `json:"bm25_b,omitempty"`
+	FieldWeights   *FieldWeights `json:"field_weights,omitempty"`
+	TSRank         *float64      `json:"ts_rank,omitempty"`
+	MatchedLexemes []string      `json:"matched_lexemes"`
+}
+
+// Explanation breaks down how a chunk scored for a query. FusedRank and Score
+// describe the fused candidate list, before reranking, diversity, collapsing
+// and thresholds reorder or trim it.
+type Explanation struct {
+	KnowledgeBaseID           string              `json:"kb_id"`
+	ChunkID                   string              `json:"chunk_id"`
+	Query                     string              `json:"query"`
+	ParsedQuery               ParsedQuery         `json:"parsed_query"`
+	RetrievalProfileEffective string              `json:"retrieval_profile_effective"`
+	AutoSignalsDetected       []string            `json:"auto_signals_detected,omitempty"`
+	SemanticWeight            float64             `json:"semantic_weight"`
+	LexicalWeight             float64             `json:"lexical_weight"`
+	FusionMethod              string              `json:"fusion_method"`
+	RRFK                      int                 `json:"rrf_k,omitempty"`
+	FiltersApplied            map[string]any      `json:"filters_applied,omitempty"`
+	Semantic                  SemanticExplanation `json:"semantic"`
+	Lexical                   LexicalExplanation  `json:"lexical"`
+	Candidate                 bool                `json:"candidate"`
+	FusedRank                 *int                `json:"fused_rank,omitempty"`
+	Score                     *Score              `json:"score,omitempty"`
+}
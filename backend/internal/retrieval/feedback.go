@@ -0,0 +1,109 @@
+package retrieval
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	MaxFeedbackLabels  = 100
+	MaxFeedbackGrade   = 3
+	FeedbackThumbsUp   = "up"
+	FeedbackThumbsDown = "down"
+)
+
+var (
+	ErrMissingQueryID      = errors.New("query_id is required")
+	ErrMissingFeedback     = errors.New("labels is required")
+	ErrTooManyFeedback     = errors.New("labels exceeds maximum of 100")
+	ErrInvalidFeedback     = errors.New("each label requires chunk_id and at least one of thumbs, grade, clicked, cited")
+	ErrInvalidThumbs       = errors.New("thumbs must be one of: up, down")
+	ErrInvalidGrade        = errors.New("grade must be between 0 and 3")
+	ErrInvalidFeedbackUser = errors.New("user_id must be at most 256 characters")
+	ErrInvalidQueryID      = errors.New("query_id must be a UUID")
+	ErrUnknownQueryResult  = errors.New("no labelled chunk was returned by a logged query with this query_id in the knowledge base")
+)
+
+// FeedbackLabel records how useful one returned chunk was: a thumbs up or
+// down, a graded relevance from 0 (irrelevant) to 3 (perfect), and whether it
+// was clicked or cited.
+type FeedbackLabel struct {
+	ChunkID string `json:"chunk_id"`
+	Thumbs  string `json:"thumbs,omitempty"`
+	Grade   *int   `json:"grade,omitempty"`
+	Clicked bool   `json:"clicked,omitempty"`
+	Cited   bool   `json:"cited,omitempty"`
+}
+
+// FeedbackSubmission labels results of the query whose Response.QueryID is
+// QueryID. UserID optionally identifies who gave the labels.
+type FeedbackSubmission struct {
+	KnowledgeBaseID string
+	QueryID         string
+	UserID          string
+	Labels          []FeedbackLabel
+}
+
+// FeedbackSummary totals a knowledge base's labels created in [From, To).
+// AvgGrade is nil when no label was graded.
+type FeedbackSummary struct {
+	KnowledgeBaseID string    `json:"kb_id"`
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	LabelCount      int64     `json:"label_count"`
+	QueryCount      int64     `json:"query_count"`
+	ThumbsUp        int64     `json:"thumbs_up"`
+	ThumbsDown      int64     `json:"thumbs_down"`
+	GradedCount     int64     `json:"graded_count"`
+	AvgGrade        *float64  `json:"avg_grade,omitempty"`
+	ClickedCount    int64     `json:"clicked_count"`
+	CitedCount      int64     `json:"cited_count"`
+}
+
+// ChunkFeedback totals the labels of one chunk; AvgRank is the mean rank of
+// the labelled results.
+type ChunkFeedback struct {
+	ChunkID      string   `json:"chunk_id"`
+	DocumentID   string   `json:"document_id"`
+	DocumentPath string   `json:"document_path"`
+	LabelCount   int64    `json:"label_count"`
+	ThumbsUp     int64    `json:"thumbs_up"`
+	ThumbsDown   int64    `json:"thumbs_down"`
+	GradedCount  int64    `json:"graded_count"`
+	AvgGrade     *float64 `json:"avg_grade,omitempty"`
+	ClickedCount int64    `json:"clicked_count"`
+	CitedCount   int64    `json:"cited_count"`
+	AvgRank      float64  `json:"avg_rank"`
+}
+
+// ValidateFeedback checks a submission whose thumbs values are already
+// lowercased.
+func ValidateFeedback(submission FeedbackSubmission) error {
+	if submission.KnowledgeBaseID == "" {
+		return ErrMissingKnowledgeBase
+	}
+	if submission.QueryID == "" {
+		return ErrMissingQueryID
+	}
+	if len(submission.UserID) > 256 {
+		return ErrInvalidFeedbackUser
+	}
+	if len(submission.Labels) == 0 {
+		return ErrMissingFeedback
+	}
+	if len(submission.Labels) > MaxFeedbackLabels {
+		return ErrTooManyFeedback
+	}
+	for _, label := range submission.Labels {
+		if label.ChunkID == "" || (label.Thumbs == "" && label.Grade == nil && !label.Clicked && !label.Cited) {
+			return ErrInvalidFeedback
+		}
+		if label.Thumbs != "" && label.Thumbs != FeedbackThumbsUp && label.Thumbs != FeedbackThumbsDown {
+			return ErrInvalidThumbs
+		}
+		if label.Grade != nil && (*label.Grade < 0 || *label.Grade > MaxFeedbackGrade) {
+			return ErrInvalidGrade
+		}
+	}
+	return nil
+}
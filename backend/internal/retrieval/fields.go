@@ -0,0 +1,49 @@
+package retrieval
+
+import (
+	"errors"
+	"math"
+)
+
+var ErrInvalidFieldWeights = errors.New("field_weights must be between 0 and 1 with a content weight above 0")
+
+// FieldWeights weigh lexical matches by the field they occur in: the
+// document title, the chunk's markdown breadcrumb or section title, the
+// document path and the chunk content. With ts_rank they are the weights of
+// the A, B, C and D labels of the chunk's search vector. BM25 scores the
+// content as usual and adds a saturating field term in which each title,
+// breadcrumb or path occurrence counts its field's weight divided by Content.
+// A zero value, or weights that leave every field but content at 0, ranks
+// content alone.
+type FieldWeights struct {
+	Title      float64 `json:"title"`
+	Breadcrumb float64 `json:"breadcrumb"`
+	Path       float64 `json:"path"`
+	Content    float64 `json:"content"`
+}
+
+// DefaultFieldWeights are Postgres' default ts_rank label weights, filling
+// the fields a request or knowledge base leaves out.
+var DefaultFieldWeights = FieldWeights{Title: 1, Breadcrumb: 0.4, Path: 0.2, Content: 0.1}
+
+// IsZero reports whether no weight is set.
+func (w FieldWeights) IsZero() bool {
+	return w == FieldWeights{}
+}
+
+// Enabled reports whether any field besides content is weighted.
+func (w FieldWeights) Enabled() bool {
+	return w.Title > 0 || w.Breadcrumb > 0 || w.Path > 0
+}
+
+func ValidateFieldWeights(w FieldWeights) error {
+	for _, weight := range []float64{w.Title, w.Breadcrumb, w.Path, w.Content} {
+		if weight < 0 || weight > 1 || math.IsNaN(weight) {
+			return ErrInvalidFieldWeights
+		}
+	}
+	if w.Content <= 0 {
+		return ErrInvalidFieldWeights
+	}
+	return nil
+}
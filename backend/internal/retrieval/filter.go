@@ -0,0 +1,334 @@
+package retrieval
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	FilterScopeSourceMetadata = "source_metadata"
+	FilterScopeMetadata       = "metadata"
+
+	MaxFilterDepth    = 8
+	MaxFilterClauses  = 64
+	MaxFilterInValues = 100
+)
+
+var (
+	ErrInvalidFilter = errors.New("invalid filter")
+
+	filterKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_\-]+$`)
+)
+
+// FilterError reports an invalid filter clause together with a JSON pointer
+// into the request payload, e.g. "/filter/and/1/range".
+type FilterError struct {
+	Pointer string
+	Reason  string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("filter %s: %s", e.Pointer, e.Reason)
+}
+
+func (e *FilterError) Unwrap() error {
+	return ErrInvalidFilter
+}
+
+// FilterExpr is one node of a boolean metadata filter. Exactly one field is set.
+type FilterExpr struct {
+	And    []FilterExpr `json:"and,omitempty"`
+	Or     []FilterExpr `json:"or,omitempty"`
+	Not    *FilterExpr  `json:"not,omitempty"`
+	Eq     *FilterEq    `json:"eq,omitempty"`
+	In     *FilterIn    `json:"in,omitempty"`
+	Range  *FilterRange `json:"range,omitempty"`
+	Exists *FilterField `json:"exists,omitempty"`
+	Prefix *FilterEq    `json:"prefix,omitempty"`
+}
+
+// FilterField names a metadata key as "<scope>.<key>[.<nested>...]" where
+// scope is source_metadata (document) or metadata (chunk).
+type FilterField struct {
+	Field string `json:"field"`
+}
+
+type FilterEq struct {
+	Field string `json:"field"`
+	Value any    `json:"value"`
+}
+
+type FilterIn struct {
+	Field  string `json:"field"`
+	Values []any  `json:"values"`
+}
+
+type FilterRange struct {
+	Field string `json:"field"`
+	Gt    any    `json:"gt,omitempty"`
+	Gte   any    `json:"gte,omitempty"`
+	Lt    any    `json:"lt,omitempty"`
+	Lte   any    `json:"lte,omitempty"`
+}
+
+// SplitFilterField returns the scope and key path of a validated field.
+func SplitFilterField(field string) (string, []string) {
+	parts := strings.Split(field, ".")
+	return parts[0], parts[1:]
+}
+
+// ParseFilter decodes a filter expression, reporting the first invalid clause
+// with a JSON pointer rooted at /filter.
+func ParseFilter(raw json.RawMessage) (*FilterExpr, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	expr, err := parseFilterNode(raw, "/filter")
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateFilter(expr); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+func parseFilterNode(raw json.RawMessage, pointer string) (*FilterExpr, error) {
+	var node map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &node); err != nil {
+		return nil, &FilterError{Pointer: pointer, Reason: "clause must be an object"}
+	}
+	if len(node) != 1 {
+		return nil, &FilterError{Pointer: pointer, Reason: "clause must have exactly one operator"}
+	}
+
+	expr := &FilterExpr{}
+	for op, body := range node {
+		opPointer := pointer + "/" + op
+		switch op {
+		case "and", "or":
+			var items []json.RawMessage
+			if err := json.Unmarshal(body, &items); err != nil {
+				return nil, &FilterError{Pointer: opPointer, Reason: "must be an array of clauses"}
+			}
+			children := make([]FilterExpr, 0, len(items))
+			for i, item := range items {
+				child, err := parseFilterNode(item, opPointer+"/"+strconv.Itoa(i))
+				if err != nil {
+					return nil, err
+				}
+				children = append(children, *child)
+			}
+			if op == "and" {
+				expr.And = children
+			} else {
+				expr.Or = children
+			}
+		case "not":
+			child, err := parseFilterNode(body, opPointer)
+			if err != nil {
+				return nil, err
+			}
+			expr.Not = child
+		case "eq", "prefix":
+			var eq FilterEq
+			if err := decodeStrict(body, &eq); err != nil {
+				return nil, &FilterError{Pointer: opPointer, Reason: "expects {\"field\", \"value\"}"}
+			}
+			if op == "eq" {
+				expr.Eq = &eq
+			} else {
+				expr.Prefix = &eq
+			}
+		case "in":
+			var in FilterIn
+			if err := decodeStrict(body, &in); err != nil {
+				return nil, &FilterError{Pointer: opPointer, Reason: "expects {\"field\", \"values\"}"}
+			}
+			expr.In = &in
+		case "range":
+			var rng FilterRange
+			if err := decodeStrict(body, &rng); err != nil {
+				return nil, &FilterError{Pointer: opPointer, Reason: "expects {\"field\", \"gt\"|\"gte\"|\"lt\"|\"lte\"}"}
+			}
+			expr.Range = &rng
+		case "exists":
+			var field FilterField
+			if err := decodeStrict(body, &field); err != nil {
+				return nil, &FilterError{Pointer: opPointer, Reason: "expects {\"field\"}"}
+			}
+			expr.Exists = &field
+		default:
+			return nil, &FilterError{Pointer: opPointer, Reason: "unknown operator; expected and, or, not, eq, in, range, exists, prefix"}
+		}
+	}
+	return expr, nil
+}
+
+func decodeStrict(raw json.RawMessage, dest any) error {
+	decoder := json.NewDecoder(strings.NewReader(string(raw)))
+	decoder.DisallowUnknownFields()
+	decoder.UseNumber()
+	return decoder.Decode(dest)
+}
+
+// ValidateFilter checks operator arity, field names, value types and size
+// limits so the expression can be compiled to SQL safely.
+func ValidateFilter(expr *FilterExpr) error {
+	if expr == nil {
+		return nil
+	}
+	clauses := 0
+	return validateFilterNode(expr, "/filter", 1, &clauses)
+}
+
+func validateFilterNode(expr *FilterExpr, pointer string, depth int, clauses *int) error {
+	if depth > MaxFilterDepth {
+		return &FilterError{Pointer: pointer, Reason: fmt.Sprintf("nesting exceeds maximum depth of %d", MaxFilterDepth)}
+	}
+	*clauses++
+	if *clauses > MaxFilterClauses {
+		return &FilterError{Pointer: pointer, Reason: fmt.Sprintf("filter exceeds maximum of %d clauses", MaxFilterClauses)}
+	}
+
+	set := 0
+	for _, present := range []bool{
+		expr.And != nil, expr.Or != nil, expr.Not != nil, expr.Eq != nil,
+		expr.In != nil, expr.Range != nil, expr.Exists != nil, expr.Prefix != nil,
+	} {
+		if present {
+			set++
+		}
+	}
+	if set != 1 {
+		return &FilterError{Pointer: pointer, Reason: "clause must have exactly one operator"}
+	}
+
+	switch {
+	case expr.And != nil || expr.Or != nil:
+		op, children := "and", expr.And
+		if expr.Or != nil {
+			op, children = "or", expr.Or
+		}
+		if len(children) == 0 {
+			return &FilterError{Pointer: pointer + "/" + op, Reason: "must contain at least one clause"}
+		}
+		for i := range children {
+			if err := validateFilterNode(&children[i], fmt.Sprintf("%s/%s/%d", pointer, op, i), depth+1, clauses); err != nil {
+				return err
+			}
+		}
+	case expr.Not != nil:
+		return validateFilterNode(expr.Not, pointer+"/not", depth+1, clauses)
+	case expr.Eq != nil:
+		if err := validateFilterField(expr.Eq.Field, pointer+"/eq"); err != nil {
+			return err
+		}
+		if !isFilterScalar(expr.Eq.Value) {
+			return &FilterError{Pointer: pointer + "/eq/value", Reason: "must be a string, number or boolean"}
+		}
+	case expr.In != nil:
+		if err := validateFilterField(expr.In.Field, pointer+"/in"); err != nil {
+			return err
+		}
+		if len(expr.In.Values) == 0 || len(expr.In.Values) > MaxFilterInValues {
+			return &FilterError{Pointer: pointer + "/in/values", Reason: fmt.Sprintf("must contain between 1 and %d values", MaxFilterInValues)}
+		}
+		for i, value := range expr.In.Values {
+			if !isFilterScalar(value) {
+				return &FilterError{Pointer: fmt.Sprintf("%s/in/values/%d", pointer, i), Reason: "must be a string, number or boolean"}
+			}
+		}
+	case expr.Range != nil:
+		return validateFilterRange(expr.Range, pointer+"/range")
+	case expr.Exists != nil:
+		return validateFilterField(expr.Exists.Field, pointer+"/exists")
+	case expr.Prefix != nil:
+		if err := validateFilterField(expr.Prefix.Field, pointer+"/prefix"); err != nil {
+			return err
+		}
+		value, ok := expr.Prefix.Value.(string)
+		if !ok || value == "" {
+			return &FilterError{Pointer: pointer + "/prefix/value", Reason: "must be a non-empty string"}
+		}
+	}
+	return nil
+}
+
+func validateFilterRange(rng *FilterRange, pointer string) error {
+	if err := validateFilterField(rng.Field, pointer); err != nil {
+		return err
+	}
+	bounds := map[string]any{"gt": rng.Gt, "gte": rng.Gte, "lt": rng.Lt, "lte": rng.Lte}
+	kind := ""
+	for _, name := range []string{"gt", "gte", "lt", "lte"} {
+		value := bounds[name]
+		if value == nil {
+			continue
+		}
+		boundKind := ""
+		switch value.(type) {
+		case string:
+			boundKind = "string"
+		case json.Number, float64, int, int64:
+			boundKind = "number"
+		default:
+			return &FilterError{Pointer: pointer + "/" + name, Reason: "must be a number or string"}
+		}
+		if kind != "" && kind != boundKind {
+			return &FilterError{Pointer: pointer + "/" + name, Reason: "range bounds must all be numbers or all be strings"}
+		}
+		kind = boundKind
+	}
+	if kind == "" {
+		return &FilterError{Pointer: pointer, Reason: "requires at least one of gt, gte, lt, lte"}
+	}
+	return nil
+}
+
+func validateFilterField(field string, pointer string) error {
+	parts := strings.Split(field, ".")
+	if len(parts) < 2 {
+		return &FilterError{Pointer: pointer + "/field", Reason: "must be source_metadata.<key> or metadata.<key>"}
+	}
+	if parts[0] != FilterScopeSourceMetadata && parts[0] != FilterScopeMetadata {
+		return &FilterError{Pointer: pointer + "/field", Reason: "must start with source_metadata. or metadata."}
+	}
+	for _, key := range parts[1:] {
+		if !filterKeyPattern.MatchString(key) {
+			return &FilterError{Pointer: pointer + "/field", Reason: "keys may only contain letters, digits, '_' and '-'"}
+		}
+	}
+	return nil
+}
+
+// ValidateFrontmatterFilter checks the keys and values of a frontmatter
+// equality filter. Errors point into /filters/frontmatter.
+func ValidateFrontmatterFilter(frontmatter map[string]any) error {
+	if len(frontmatter) > MaxFilterClauses {
+		return &FilterError{Pointer: "/filters/frontmatter", Reason: fmt.Sprintf("at most %d keys are allowed", MaxFilterClauses)}
+	}
+	for key, value := range frontmatter {
+		pointer := "/filters/frontmatter/" + key
+		if !filterKeyPattern.MatchString(key) {
+			return &FilterError{Pointer: pointer, Reason: "keys may only contain letters, digits, '_' and '-'"}
+		}
+		if !isFilterScalar(value) {
+			return &FilterError{Pointer: pointer, Reason: "value must be a string, number or boolean"}
+		}
+	}
+	return nil
+}
+
+func isFilterScalar(value any) bool {
+	switch value.(type) {
+	case string, bool, json.Number, float64, int, int64:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,66 @@
+package retrieval
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseFilter_Valid(t *testing.T) {
+	expr, err := ParseFilter([]byte(`{"and":[
+		{"eq":{"field":"source_metadata.team","value":"infra"}},
+		{"not":{"exists":{"field":"metadata.draft"}}},
+		{"or":[
+			{"in":{"field":"metadata.lang","values":["go","sql"]}},
+			{"range":{"field":"source_metadata.priority","gte":2,"lt":5}},
+			{"prefix":{"field":"metadata.frontmatter.owner","value":"platform-"}}
+		]}
+	]}`))
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+	if len(expr.And) != 3 || expr.And[1].Not == nil || len(expr.And[2].Or) != 3 {
+		t.Fatalf("ParseFilter() = %+v, want nested and/not/or structure", expr)
+	}
+}
+
+func TestParseFilter_ReportsPointer(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		pointer string
+	}{
+		{name: "unknown operator", raw: `{"and":[{"eq":{"field":"metadata.a","value":1}},{"like":{}}]}`, pointer: "/filter/and/1/like"},
+		{name: "bad scope", raw: `{"or":[{"exists":{"field":"title"}}]}`, pointer: "/filter/or/0/exists/field"},
+		{name: "mixed range", raw: `{"not":{"range":{"field":"metadata.v","gt":1,"lt":"z"}}}`, pointer: "/filter/not/range/lt"},
+		{name: "two operators", raw: `{"eq":{"field":"metadata.a","value":1},"exists":{"field":"metadata.a"}}`, pointer: "/filter"},
+		{name: "injection key", raw: `{"eq":{"field":"metadata.a'); drop","value":1}}`, pointer: "/filter/eq/field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFilter([]byte(tt.raw))
+			var filterErr *FilterError
+			if !errors.As(err, &filterErr) {
+				t.Fatalf("ParseFilter() error = %v, want FilterError", err)
+			}
+			if filterErr.Pointer != tt.pointer {
+				t.Fatalf("ParseFilter() pointer = %q, want %q", filterErr.Pointer, tt.pointer)
+			}
+			if !errors.Is(err, ErrInvalidFilter) {
+				t.Fatalf("ParseFilter() error does not wrap ErrInvalidFilter")
+			}
+		})
+	}
+}
+
+func TestValidateFrontmatterFilter(t *testing.T) {
+	if err := ValidateFrontmatterFilter(map[string]any{"owner": "platform", "draft": false}); err != nil {
+		t.Fatalf("ValidateFrontmatterFilter() error = %v", err)
+	}
+
+	err := ValidateFrontmatterFilter(map[string]any{"tags": []any{"a"}})
+	var filterErr *FilterError
+	if !errors.As(err, &filterErr) || filterErr.Pointer != "/filters/frontmatter/tags" {
+		t.Fatalf("ValidateFrontmatterFilter() error = %v, want pointer /filters/frontmatter/tags", err)
+	}
+}
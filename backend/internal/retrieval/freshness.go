@@ -0,0 +1,129 @@
+package retrieval
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	FreshnessDecayExponential = "exponential"
+	FreshnessDecayLinear      = "linear"
+	// FreshnessDecayNone turns off a boost the knowledge base settings enable.
+	FreshnessDecayNone           = "none"
+	DefaultFreshnessHalfLifeDays = 30.0
+	MaxFreshnessHalfLifeDays     = 3650.0
+	DefaultFreshnessWeight       = 0.1
+	MaxFreshnessDateFieldLength  = 128
+)
+
+var (
+	ErrInvalidFreshnessDecay     = errors.New("freshness.decay must be exponential, linear, or none")
+	ErrInvalidFreshnessHalfLife  = errors.New("freshness.half_life_days must be greater than 0 and at most 3650")
+	ErrInvalidFreshnessWeight    = errors.New("freshness.weight must be greater than 0 and at most 1")
+	ErrInvalidFreshnessDateField = errors.New("freshness.date_field must be at most 128 characters")
+)
+
+// FreshnessOptions add a boost to each candidate's final score that decays
+// with the age of its document version. The boost is Weight for a version
+// created now and Weight/2 at HalfLifeDays; exponential decay keeps halving
+// after that while linear decay reaches 0 at twice the half-life. DateField
+// names a source_metadata date used in place of the version's created_at
+// when present. Weight is on the scale of the fused final score, so RRF
+// fusion needs far smaller weights than linear fusion. A zero value leaves the
+// knowledge base settings in place.
+type FreshnessOptions struct {
+	Decay        string  `json:"decay"`
+	HalfLifeDays float64 `json:"half_life_days"`
+	Weight       float64 `json:"weight"`
+	DateField    string  `json:"date_field,omitempty"`
+}
+
+// FreshnessDebug reports the freshness boost a retrieval applied: the
+// effective options, how many candidates were boosted and how many of those
+// were dated by DateField rather than created_at.
+type FreshnessDebug struct {
+	FreshnessOptions
+	Boosted      int `json:"boosted"`
+	DatedByField int `json:"dated_by_field"`
+}
+
+// IsZero reports whether no option is set.
+func (o FreshnessOptions) IsZero() bool {
+	return o == FreshnessOptions{}
+}
+
+// Enabled reports whether the options add a boost.
+func (o FreshnessOptions) Enabled() bool {
+	return o.Decay == FreshnessDecayExponential || o.Decay == FreshnessDecayLinear
+}
+
+func IsValidFreshnessDecay(decay string) bool {
+	switch strings.ToLower(strings.TrimSpace(decay)) {
+	case FreshnessDecayExponential, FreshnessDecayLinear, FreshnessDecayNone:
+		return true
+	default:
+		return false
+	}
+}
+
+// NormalizeFreshness lower-cases the decay and fills an unset half-life and
+// weight with the defaults.
+func NormalizeFreshness(opts FreshnessOptions) FreshnessOptions {
+	opts.Decay = strings.ToLower(strings.TrimSpace(opts.Decay))
+	opts.DateField = strings.TrimSpace(opts.DateField)
+	if opts.HalfLifeDays == 0 {
+		opts.HalfLifeDays = DefaultFreshnessHalfLifeDays
+	}
+	if opts.Weight == 0 {
+		opts.Weight = DefaultFreshnessWeight
+	}
+	return opts
+}
+
+func ValidateFreshness(opts FreshnessOptions) error {
+	if !IsValidFreshnessDecay(opts.Decay) {
+		return ErrInvalidFreshnessDecay
+	}
+	if opts.HalfLifeDays <= 0 || opts.HalfLifeDays > MaxFreshnessHalfLifeDays || math.IsNaN(opts.HalfLifeDays) {
+		return ErrInvalidFreshnessHalfLife
+	}
+	if opts.Weight <= 0 || opts.Weight > 1 || math.IsNaN(opts.Weight) {
+		return ErrInvalidFreshnessWeight
+	}
+	if len(opts.DateField) > MaxFreshnessDateFieldLength {
+		return ErrInvalidFreshnessDateField
+	}
+	return nil
+}
+
+// FreshnessBoost is the boost for a document of the given age. Dates in the
+// future count as new.
+func FreshnessBoost(opts FreshnessOptions, age time.Duration) float64 {
+	if !opts.Enabled() || opts.HalfLifeDays <= 0 {
+		return 0
+	}
+	halfLives := max(age.Hours(), 0) / 24 / opts.HalfLifeDays
+	switch opts.Decay {
+	case FreshnessDecayLinear:
+		return opts.Weight * max(1-halfLives/2, 0)
+	default:
+		return opts.Weight * math.Pow(0.5, halfLives)
+	}
+}
+
+// ParseFreshnessDate reads an RFC 3339 timestamp or a YYYY-MM-DD date.
+func ParseFreshnessDate(value any) (time.Time, bool) {
+	text, ok := value.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	text = strings.TrimSpace(text)
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
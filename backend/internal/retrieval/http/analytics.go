@@ -0,0 +1,161 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+// TopQueries lists a knowledge base's most frequent queries.
+func (h *Handler) TopQueries(w http.ResponseWriter, r *http.Request) {
+	h.serveAnalytics(w, r, "/v1/kb/{kbID}/analytics/queries/top", func(ctx context.Context, query retrieval.AnalyticsQuery) (any, error) {
+		return h.service.TopQueries(ctx, query)
+	})
+}
+
+// ZeroResultQueries lists the most frequent queries that returned nothing.
+func (h *Handler) ZeroResultQueries(w http.ResponseWriter, r *http.Request) {
+	h.serveAnalytics(w, r, "/v1/kb/{kbID}/analytics/queries/zero-result", func(ctx context.Context, query retrieval.AnalyticsQuery) (any, error) {
+		return h.service.ZeroResultQueries(ctx, query)
+	})
+}
+
+// LatencyPercentiles reports query latency percentiles per time bucket.
+func (h *Handler) LatencyPercentiles(w http.ResponseWriter, r *http.Request) {
+	h.serveAnalytics(w, r, "/v1/kb/{kbID}/analytics/latency", func(ctx context.Context, query retrieval.AnalyticsQuery) (any, error) {
+		return h.service.LatencyPercentiles(ctx, query)
+	})
+}
+
+// ProfileDistribution counts queries by retrieval profile and semantic weight.
+func (h *Handler) ProfileDistribution(w http.ResponseWriter, r *http.Request) {
+	h.serveAnalytics(w, r, "/v1/kb/{kbID}/analytics/profiles", func(ctx context.Context, query retrieval.AnalyticsQuery) (any, error) {
+		return h.service.ProfileDistribution(ctx, query)
+	})
+}
+
+// MostRetrievedDocuments lists the documents whose chunks were returned most.
+func (h *Handler) MostRetrievedDocuments(w http.ResponseWriter, r *http.Request) {
+	h.serveAnalytics(w, r, "/v1/kb/{kbID}/analytics/documents/top", func(ctx context.Context, query retrieval.AnalyticsQuery) (any, error) {
+		return h.service.MostRetrievedDocuments(ctx, query)
+	})
+}
+
+// NeverRetrievedDocuments lists active documents no query returned.
+func (h *Handler) NeverRetrievedDocuments(w http.ResponseWriter, r *http.Request) {
+	h.serveAnalytics(w, r, "/v1/kb/{kbID}/analytics/documents/never-retrieved", func(ctx context.Context, query retrieval.AnalyticsQuery) (any, error) {
+		return h.service.NeverRetrievedDocuments(ctx, query)
+	})
+}
+
+// FeedbackSummary totals the knowledge base's result feedback.
+func (h *Handler) FeedbackSummary(w http.ResponseWriter, r *http.Request) {
+	h.serveAnalytics(w, r, "/v1/kb/{kbID}/feedback", func(ctx context.Context, query retrieval.AnalyticsQuery) (any, error) {
+		return h.service.FeedbackSummary(ctx, query)
+	})
+}
+
+// ChunkFeedback lists per-chunk feedback totals.
+func (h *Handler) ChunkFeedback(w http.ResponseWriter, r *http.Request) {
+	h.serveAnalytics(w, r, "/v1/kb/{kbID}/feedback/chunks", func(ctx context.Context, query retrieval.AnalyticsQuery) (any, error) {
+		return h.service.ChunkFeedback(ctx, query)
+	})
+}
+
+// serveAnalytics parses the shared analytics query parameters and writes the
+// report load returns.
+func (h *Handler) serveAnalytics(
+	w http.ResponseWriter,
+	r *http.Request,
+	route string,
+	load func(context.Context, retrieval.AnalyticsQuery) (any, error),
+) {
+	start := time.Now()
+	statusCode := http.StatusOK
+	outcome := "success"
+	defer func() {
+		h.recordMetrics(r, route, start, statusCode, outcome, 0)
+	}()
+
+	kbID := strings.TrimSpace(chi.URLParam(r, "kbID"))
+	if kbID == "" {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, "kbID is required")
+		return
+	}
+
+	query, err := parseAnalyticsQuery(r, kbID)
+	if err != nil {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := load(r.Context(), query)
+	if err != nil {
+		if isRetrievalClientError(err) {
+			statusCode = http.StatusBadRequest
+			outcome = "client_error"
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		statusCode = http.StatusInternalServerError
+		outcome = "server_error"
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	statusCode = http.StatusOK
+	writeJSON(w, http.StatusOK, report)
+}
+
+// parseAnalyticsQuery reads from and to as RFC 3339 timestamps, limit and
+// offset as integers, and bucket. Absent parameters are left for the service
+// to default.
+func parseAnalyticsQuery(r *http.Request, kbID string) (retrieval.AnalyticsQuery, error) {
+	values := r.URL.Query()
+	query := retrieval.AnalyticsQuery{
+		KnowledgeBaseID: kbID,
+		Bucket:          values.Get("bucket"),
+	}
+
+	for _, param := range []struct {
+		name   string
+		target *time.Time
+	}{{"from", &query.From}, {"to", &query.To}} {
+		raw := strings.TrimSpace(values.Get(param.name))
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return query, errors.New(param.name + " must be an RFC 3339 timestamp")
+		}
+		*param.target = parsed
+	}
+
+	if raw := strings.TrimSpace(values.Get("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return query, retrieval.ErrInvalidAnalyticsLimit
+		}
+		query.Limit = limit
+	}
+	if raw := strings.TrimSpace(values.Get("offset")); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return query, retrieval.ErrInvalidAnalyticsOffset
+		}
+		query.Offset = offset
+	}
+	return query, nil
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"strings"
 	"time"
@@ -71,7 +72,7 @@ func (h *Handler) Query(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		statusCode = http.StatusBadRequest
 		outcome = "client_error"
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeClientError(w, err)
 		return
 	}
 
@@ -80,7 +81,281 @@ func (h *Handler) Query(w http.ResponseWriter, r *http.Request) {
 		if isRetrievalClientError(err) {
 			statusCode = http.StatusBadRequest
 			outcome = "client_error"
-			writeError(w, http.StatusBadRequest, err.Error())
+			writeClientError(w, err)
+			return
+		}
+		statusCode = http.StatusInternalServerError
+		outcome = "server_error"
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	statusCode = http.StatusOK
+	resultCount = int64(res.ResultCount)
+	writeJSON(w, http.StatusOK, res)
+}
+
+// Explain reports how one chunk scores for a query, or why it is not a
+// candidate.
+func (h *Handler) Explain(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	statusCode := http.StatusOK
+	outcome := "success"
+	defer func() {
+		h.recordMetrics(r, "/v1/kb/{kbID}/query/explain", start, statusCode, outcome, 0)
+	}()
+
+	kbID := strings.TrimSpace(chi.URLParam(r, "kbID"))
+	if kbID == "" {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, "kbID is required")
+		return
+	}
+
+	var payload explainRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	req, err := buildRetrievalRequest(kbID, payload.queryRequest)
+	if err != nil {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeClientError(w, err)
+		return
+	}
+
+	explanation, err := h.service.Explain(r.Context(), retrieval.ExplainRequest{Request: req, ChunkID: payload.ChunkID})
+	if err != nil {
+		if isRetrievalClientError(err) {
+			statusCode = http.StatusBadRequest
+			outcome = "client_error"
+			writeClientError(w, err)
+			return
+		}
+		statusCode = http.StatusInternalServerError
+		outcome = "server_error"
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	statusCode = http.StatusOK
+	writeJSON(w, http.StatusOK, explanation)
+}
+
+// QueryBatch runs up to retrieval.MaxBatchQueries queries against the
+// knowledge base in one request. Each query reports its own status, response
+// or error, so one bad query does not fail the others.
+func (h *Handler) QueryBatch(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	statusCode := http.StatusOK
+	outcome := "success"
+	resultCount := int64(0)
+	defer func() {
+		h.recordMetrics(r, "/v1/kb/{kbID}/query:batch", start, statusCode, outcome, resultCount)
+	}()
+
+	kbID := strings.TrimSpace(chi.URLParam(r, "kbID"))
+	if kbID == "" {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, "kbID is required")
+		return
+	}
+
+	var payload batchQueryRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := retrieval.ValidateBatch(len(payload.Queries)); err != nil {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeClientError(w, err)
+		return
+	}
+
+	response := batchQueryResponse{
+		KnowledgeBaseID: kbID,
+		Results:         make([]batchQueryResult, len(payload.Queries)),
+	}
+	requests := make([]retrieval.Request, 0, len(payload.Queries))
+	indexes := make([]int, 0, len(payload.Queries))
+	for i, query := range payload.Queries {
+		response.Results[i].Index = i
+		req, err := buildRetrievalRequest(kbID, query)
+		if err != nil {
+			response.Results[i].setError(http.StatusBadRequest, err)
+			continue
+		}
+		requests = append(requests, req)
+		indexes = append(indexes, i)
+	}
+
+	if len(requests) > 0 {
+		results, err := h.service.RetrieveBatch(r.Context(), kbID, requests)
+		if err != nil {
+			if isRetrievalClientError(err) {
+				statusCode = http.StatusBadRequest
+				outcome = "client_error"
+				writeClientError(w, err)
+				return
+			}
+			statusCode = http.StatusInternalServerError
+			outcome = "server_error"
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for j, result := range results {
+			item := &response.Results[indexes[j]]
+			if result.Err != nil {
+				status := http.StatusInternalServerError
+				if isRetrievalClientError(result.Err) {
+					status = http.StatusBadRequest
+				}
+				item.setError(status, result.Err)
+				continue
+			}
+			item.Status = http.StatusOK
+			item.Response = result.Response
+			resultCount += int64(result.Response.ResultCount)
+		}
+	}
+
+	for _, item := range response.Results {
+		if item.Status == http.StatusOK {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+	if response.Succeeded == 0 {
+		outcome = "client_error"
+		for _, item := range response.Results {
+			if item.Status >= http.StatusInternalServerError {
+				outcome = "server_error"
+			}
+		}
+	}
+
+	statusCode = http.StatusOK
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Context retrieves results and packs them into one token-budgeted context.
+func (h *Handler) Context(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	statusCode := http.StatusOK
+	outcome := "success"
+	defer func() {
+		h.recordMetrics(r, "/v1/kb/{kbID}/context", start, statusCode, outcome, 0)
+	}()
+
+	kbID := strings.TrimSpace(chi.URLParam(r, "kbID"))
+	if kbID == "" {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, "kbID is required")
+		return
+	}
+
+	var payload contextRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	req, err := buildRetrievalRequest(kbID, payload.queryRequest)
+	if err != nil {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeClientError(w, err)
+		return
+	}
+
+	tokenBudget := 0
+	if payload.TokenBudget != nil {
+		tokenBudget = *payload.TokenBudget
+		if tokenBudget == 0 {
+			statusCode = http.StatusBadRequest
+			outcome = "client_error"
+			writeClientError(w, retrieval.ErrInvalidTokenBudget)
+			return
+		}
+	}
+
+	packed, err := h.service.BuildContext(r.Context(), retrieval.ContextRequest{
+		Request:        req,
+		TokenBudget:    tokenBudget,
+		AdjacentBefore: payload.AdjacentBefore,
+		AdjacentAfter:  payload.AdjacentAfter,
+	})
+	if err != nil {
+		if isRetrievalClientError(err) {
+			statusCode = http.StatusBadRequest
+			outcome = "client_error"
+			writeClientError(w, err)
+			return
+		}
+		statusCode = http.StatusInternalServerError
+		outcome = "server_error"
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	statusCode = http.StatusOK
+	writeJSON(w, http.StatusOK, packed)
+}
+
+// FederatedQuery searches several knowledge bases in one call.
+func (h *Handler) FederatedQuery(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	statusCode := http.StatusOK
+	outcome := "success"
+	resultCount := int64(0)
+	defer func() {
+		h.recordMetrics(r, "/v1/query", start, statusCode, outcome, resultCount)
+	}()
+
+	var payload federatedQueryRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	req, err := buildFederatedRequest(payload)
+	if err != nil {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeClientError(w, err)
+		return
+	}
+
+	res, err := h.service.RetrieveFederated(r.Context(), req)
+	if err != nil {
+		if isRetrievalClientError(err) {
+			statusCode = http.StatusBadRequest
+			outcome = "client_error"
+			writeClientError(w, err)
 			return
 		}
 		statusCode = http.StatusInternalServerError
@@ -126,6 +401,7 @@ func (h *Handler) Hydrate(w http.ResponseWriter, r *http.Request) {
 		ChunkIDs:        payload.ChunkIDs,
 		AdjacentBefore:  payload.AdjacentBefore,
 		AdjacentAfter:   payload.AdjacentAfter,
+		Merge:           payload.Merge,
 	}
 
 	res, err := h.service.Hydrate(r.Context(), req)
@@ -147,6 +423,166 @@ func (h *Handler) Hydrate(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, res)
 }
 
+// SubmitJudgments stores labelled relevance of logged results for calibration.
+func (h *Handler) SubmitJudgments(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	statusCode := http.StatusOK
+	outcome := "success"
+	resultCount := int64(0)
+	defer func() {
+		h.recordMetrics(r, "/v1/kb/{kbID}/calibration/judgments", start, statusCode, outcome, resultCount)
+	}()
+
+	kbID := strings.TrimSpace(chi.URLParam(r, "kbID"))
+	if kbID == "" {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, "kbID is required")
+		return
+	}
+
+	var payload judgmentsRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	stored, err := h.service.SubmitJudgments(r.Context(), kbID, payload.Judgments)
+	if err != nil {
+		if isRetrievalClientError(err) {
+			statusCode = http.StatusBadRequest
+			outcome = "client_error"
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		statusCode = http.StatusInternalServerError
+		outcome = "server_error"
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	statusCode = http.StatusOK
+	resultCount = int64(stored)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"kb_id":     kbID,
+		"submitted": len(payload.Judgments),
+		"stored":    stored,
+	})
+}
+
+// SubmitFeedback stores relevance labels for results of a logged query.
+func (h *Handler) SubmitFeedback(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	statusCode := http.StatusOK
+	outcome := "success"
+	resultCount := int64(0)
+	defer func() {
+		h.recordMetrics(r, "/v1/kb/{kbID}/queries/{queryID}/feedback", start, statusCode, outcome, resultCount)
+	}()
+
+	kbID := strings.TrimSpace(chi.URLParam(r, "kbID"))
+	if kbID == "" {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, "kbID is required")
+		return
+	}
+	queryID := strings.TrimSpace(chi.URLParam(r, "queryID"))
+
+	var payload feedbackRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	stored, err := h.service.SubmitFeedback(r.Context(), retrieval.FeedbackSubmission{
+		KnowledgeBaseID: kbID,
+		QueryID:         queryID,
+		UserID:          payload.UserID,
+		Labels:          payload.Labels,
+	})
+	if err != nil {
+		if errors.Is(err, retrieval.ErrUnknownQueryResult) {
+			statusCode = http.StatusNotFound
+			outcome = "client_error"
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if isRetrievalClientError(err) {
+			statusCode = http.StatusBadRequest
+			outcome = "client_error"
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		statusCode = http.StatusInternalServerError
+		outcome = "server_error"
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	statusCode = http.StatusOK
+	resultCount = int64(stored)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"kb_id":     kbID,
+		"query_id":  queryID,
+		"submitted": len(payload.Labels),
+		"stored":    stored,
+	})
+}
+
+// Calibrate fits the knowledge base's score calibration from its judgments.
+func (h *Handler) Calibrate(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	statusCode := http.StatusOK
+	outcome := "success"
+	defer func() {
+		h.recordMetrics(r, "/v1/kb/{kbID}/calibration", start, statusCode, outcome, 0)
+	}()
+
+	kbID := strings.TrimSpace(chi.URLParam(r, "kbID"))
+	if kbID == "" {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, "kbID is required")
+		return
+	}
+
+	var payload calibrateRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	calibration, err := h.service.Calibrate(r.Context(), kbID, payload.Fusion)
+	if err != nil {
+		if isRetrievalClientError(err) {
+			statusCode = http.StatusBadRequest
+			outcome = "client_error"
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		statusCode = http.StatusInternalServerError
+		outcome = "server_error"
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	statusCode = http.StatusOK
+	writeJSON(w, http.StatusOK, calibration)
+}
+
 func (h *Handler) recordMetrics(r *http.Request, route string, startedAt time.Time, statusCode int, outcome string, resultCount int64) {
 	if h.metrics == nil {
 		return
@@ -202,19 +638,143 @@ func newRetrievalMetrics() *retrievalMetrics {
 }
 
 type queryRequest struct {
-	Query            string       `json:"query"`
-	TopK             *int         `json:"top_k"`
-	HybridWeight     *float64     `json:"hybrid_weight"`
-	RetrievalProfile *string      `json:"retrieval_profile"`
-	SemanticWeight   *float64     `json:"semantic_weight"`
-	Debug            bool         `json:"debug"`
-	Filters          *filtersJSON `json:"filters"`
+	Query            string            `json:"query"`
+	TopK             *int              `json:"top_k"`
+	HybridWeight     *float64          `json:"hybrid_weight"`
+	RetrievalProfile *string           `json:"retrieval_profile"`
+	SemanticWeight   *float64          `json:"semantic_weight"`
+	Debug            bool              `json:"debug"`
+	Filters          *filtersJSON      `json:"filters"`
+	Filter           json.RawMessage   `json:"filter"`
+	Fusion           *string           `json:"fusion"`
+	RRFK             *int              `json:"rrf_k"`
+	Rerank           bool              `json:"rerank"`
+	RerankTopN       *int              `json:"rerank_top_n"`
+	Diversity        *float64          `json:"diversity"`
+	PageSize         *int              `json:"page_size"`
+	Cursor           string            `json:"cursor"`
+	LexicalScorer    *string           `json:"lexical_scorer"`
+	BM25             *bm25JSON         `json:"bm25"`
+	Collapse         *string           `json:"collapse"`
+	MaxPerDocument   *int              `json:"max_per_document"`
+	Highlight        bool              `json:"highlight"`
+	MinSemanticScore *float64          `json:"min_semantic_score"`
+	MinFinalScore    *float64          `json:"min_final_score"`
+	MinLexicalHits   *int              `json:"min_lexical_hits"`
+	ANN              *annJSON          `json:"ann"`
+	FieldWeights     *fieldWeightsJSON `json:"field_weights"`
+	Freshness        *freshnessJSON    `json:"freshness"`
+}
+
+type batchQueryRequest struct {
+	Queries []queryRequest `json:"queries"`
+}
+
+type batchQueryResponse struct {
+	KnowledgeBaseID string             `json:"kb_id"`
+	Succeeded       int                `json:"succeeded"`
+	Failed          int                `json:"failed"`
+	Results         []batchQueryResult `json:"results"`
+}
+
+// batchQueryResult is one query's outcome, in the order of the request. Status
+// is the HTTP status the query would have had on its own.
+type batchQueryResult struct {
+	Index    int                 `json:"index"`
+	Status   int                 `json:"status"`
+	Response *retrieval.Response `json:"response,omitempty"`
+	Error    string              `json:"error,omitempty"`
+	Pointer  string              `json:"pointer,omitempty"`
+}
+
+// setError records err the way the single query endpoint would write it.
+func (b *batchQueryResult) setError(status int, err error) {
+	b.Status = status
+	if status >= http.StatusInternalServerError {
+		b.Error = "internal server error"
+		return
+	}
+	b.Error = err.Error()
+	var filterErr *retrieval.FilterError
+	if errors.As(err, &filterErr) {
+		b.Pointer = filterErr.Pointer
+	}
+}
+
+type explainRequest struct {
+	queryRequest
+	ChunkID string `json:"chunk_id"`
+}
+
+type contextRequest struct {
+	queryRequest
+	TokenBudget    *int `json:"token_budget"`
+	AdjacentBefore int  `json:"adjacent_before"`
+	AdjacentAfter  int  `json:"adjacent_after"`
+}
+
+type federatedQueryRequest struct {
+	KBIDs            []string           `json:"kb_ids"`
+	KBWeights        map[string]float64 `json:"kb_weights"`
+	Query            string             `json:"query"`
+	TopK             *int               `json:"top_k"`
+	HybridWeight     *float64           `json:"hybrid_weight"`
+	RetrievalProfile *string            `json:"retrieval_profile"`
+	SemanticWeight   *float64           `json:"semantic_weight"`
+	Debug            bool               `json:"debug"`
+	Filters          *filtersJSON       `json:"filters"`
+	Filter           json.RawMessage    `json:"filter"`
+	Fusion           *string            `json:"fusion"`
+	RRFK             *int               `json:"rrf_k"`
+	LexicalScorer    *string            `json:"lexical_scorer"`
+	BM25             *bm25JSON          `json:"bm25"`
+	FieldWeights     *fieldWeightsJSON  `json:"field_weights"`
+	ANN              *annJSON           `json:"ann"`
+}
+
+type annJSON struct {
+	Exact         bool `json:"exact"`
+	HNSWEfSearch  *int `json:"hnsw_ef_search"`
+	IVFFlatProbes *int `json:"ivfflat_probes"`
+}
+
+type freshnessJSON struct {
+	Decay        string   `json:"decay"`
+	HalfLifeDays *float64 `json:"half_life_days"`
+	Weight       *float64 `json:"weight"`
+	DateField    string   `json:"date_field"`
+}
+
+type fieldWeightsJSON struct {
+	Title      *float64 `json:"title"`
+	Breadcrumb *float64 `json:"breadcrumb"`
+	Path       *float64 `json:"path"`
+	Content    *float64 `json:"content"`
+}
+
+type bm25JSON struct {
+	K1 *float64 `json:"k1"`
+	B  *float64 `json:"b"`
+}
+
+type judgmentsRequest struct {
+	Judgments []retrieval.RelevanceJudgment `json:"judgments"`
+}
+
+type feedbackRequest struct {
+	UserID string                    `json:"user_id"`
+	Labels []retrieval.FeedbackLabel `json:"labels"`
+}
+
+type calibrateRequest struct {
+	Fusion string `json:"fusion"`
 }
 
 type hydrateRequest struct {
 	ChunkIDs       []string `json:"chunk_ids"`
 	AdjacentBefore int      `json:"adjacent_before"`
 	AdjacentAfter  int      `json:"adjacent_after"`
+	Merge          bool     `json:"merge"`
 }
 
 type filtersJSON struct {
@@ -225,6 +785,10 @@ type filtersJSON struct {
 	CreatedAfter  *string  `json:"created_after"`
 	CreatedBefore *string  `json:"created_before"`
 	UpdatedAfter  *string  `json:"updated_after"`
+
+	SectionPathPrefix  *string        `json:"section_path_prefix"`
+	Frontmatter        map[string]any `json:"frontmatter"`
+	BreadcrumbContains *string        `json:"breadcrumb_contains"`
 }
 
 func buildRetrievalRequest(kbID string, payload queryRequest) (retrieval.Request, error) {
@@ -232,6 +796,9 @@ func buildRetrievalRequest(kbID string, payload queryRequest) (retrieval.Request
 		KnowledgeBaseID: kbID,
 		Query:           strings.TrimSpace(payload.Query),
 		Debug:           payload.Debug,
+		Rerank:          payload.Rerank,
+		Highlight:       payload.Highlight,
+		Cursor:          strings.TrimSpace(payload.Cursor),
 	}
 
 	if payload.TopK != nil {
@@ -250,6 +817,113 @@ func buildRetrievalRequest(kbID string, payload queryRequest) (retrieval.Request
 		req.HybridWeight = *payload.SemanticWeight
 		req.HybridWeightSet = true
 	}
+	if payload.Fusion != nil {
+		req.Fusion = strings.TrimSpace(*payload.Fusion)
+	}
+	if payload.RRFK != nil {
+		req.RRFK = *payload.RRFK
+		req.RRFKSet = true
+	}
+	if payload.RerankTopN != nil {
+		if *payload.RerankTopN < 1 {
+			return req, retrieval.ErrInvalidRerankTopN
+		}
+		req.RerankTopN = *payload.RerankTopN
+	}
+	if payload.PageSize != nil {
+		if *payload.PageSize < 1 {
+			return req, retrieval.ErrInvalidPageSize
+		}
+		req.PageSize = *payload.PageSize
+	}
+	if payload.Diversity != nil {
+		req.Diversity = *payload.Diversity
+		req.DiversitySet = true
+	}
+	if payload.Collapse != nil {
+		req.Collapse = strings.ToLower(strings.TrimSpace(*payload.Collapse))
+	}
+	if payload.MaxPerDocument != nil {
+		if *payload.MaxPerDocument < 1 {
+			return req, retrieval.ErrInvalidMaxPerDocument
+		}
+		req.MaxPerDocument = *payload.MaxPerDocument
+	}
+	if payload.MinSemanticScore != nil {
+		req.MinSemanticScore = *payload.MinSemanticScore
+		req.MinSemanticScoreSet = true
+	}
+	if payload.MinFinalScore != nil {
+		req.MinFinalScore = *payload.MinFinalScore
+		req.MinFinalScoreSet = true
+	}
+	if payload.MinLexicalHits != nil {
+		req.MinLexicalHits = *payload.MinLexicalHits
+	}
+	if payload.LexicalScorer != nil {
+		req.LexicalScorer = strings.TrimSpace(*payload.LexicalScorer)
+	}
+	if payload.BM25 != nil && payload.BM25.K1 != nil {
+		req.BM25K1 = *payload.BM25.K1
+		req.BM25K1Set = true
+	}
+	if payload.BM25 != nil && payload.BM25.B != nil {
+		req.BM25B = *payload.BM25.B
+		req.BM25BSet = true
+	}
+	if payload.FieldWeights != nil {
+		// Fields left out keep their default weight.
+		req.FieldWeights = retrieval.DefaultFieldWeights
+		if value := payload.FieldWeights.Title; value != nil {
+			req.FieldWeights.Title = *value
+		}
+		if value := payload.FieldWeights.Breadcrumb; value != nil {
+			req.FieldWeights.Breadcrumb = *value
+		}
+		if value := payload.FieldWeights.Path; value != nil {
+			req.FieldWeights.Path = *value
+		}
+		if value := payload.FieldWeights.Content; value != nil {
+			req.FieldWeights.Content = *value
+		}
+	}
+	if payload.ANN != nil {
+		req.ANN.Exact = payload.ANN.Exact
+		if value := payload.ANN.HNSWEfSearch; value != nil {
+			if *value < 1 {
+				return req, retrieval.ErrInvalidHNSWEfSearch
+			}
+			req.ANN.HNSWEfSearch = *value
+		}
+		if value := payload.ANN.IVFFlatProbes; value != nil {
+			if *value < 1 {
+				return req, retrieval.ErrInvalidIVFFlatProbes
+			}
+			req.ANN.IVFFlatProbes = *value
+		}
+	}
+	if payload.Freshness != nil {
+		req.Freshness.Decay = payload.Freshness.Decay
+		req.Freshness.DateField = payload.Freshness.DateField
+		if value := payload.Freshness.HalfLifeDays; value != nil {
+			if *value <= 0 {
+				return req, retrieval.ErrInvalidFreshnessHalfLife
+			}
+			req.Freshness.HalfLifeDays = *value
+		}
+		if value := payload.Freshness.Weight; value != nil {
+			if *value <= 0 {
+				return req, retrieval.ErrInvalidFreshnessWeight
+			}
+			req.Freshness.Weight = *value
+		}
+	}
+
+	expr, err := retrieval.ParseFilter(payload.Filter)
+	if err != nil {
+		return req, err
+	}
+	req.Filters.Expr = expr
 
 	if payload.Filters == nil {
 		return req, nil
@@ -260,6 +934,11 @@ func buildRetrievalRequest(kbID string, payload queryRequest) (retrieval.Request
 		DocumentType: payload.Filters.DocumentType,
 		Source:       payload.Filters.Source,
 		Tags:         payload.Filters.Tags,
+		Expr:         expr,
+
+		SectionPathPrefix:  payload.Filters.SectionPathPrefix,
+		Frontmatter:        payload.Filters.Frontmatter,
+		BreadcrumbContains: payload.Filters.BreadcrumbContains,
 	}
 
 	createdAfter := payload.Filters.CreatedAfter
@@ -286,6 +965,50 @@ func buildRetrievalRequest(kbID string, payload queryRequest) (retrieval.Request
 	return req, nil
 }
 
+func buildFederatedRequest(payload federatedQueryRequest) (retrieval.FederatedRequest, error) {
+	shared, err := buildRetrievalRequest("", queryRequest{
+		Query:            payload.Query,
+		TopK:             payload.TopK,
+		HybridWeight:     payload.HybridWeight,
+		RetrievalProfile: payload.RetrievalProfile,
+		SemanticWeight:   payload.SemanticWeight,
+		Debug:            payload.Debug,
+		Filters:          payload.Filters,
+		Filter:           payload.Filter,
+		Fusion:           payload.Fusion,
+		RRFK:             payload.RRFK,
+		LexicalScorer:    payload.LexicalScorer,
+		BM25:             payload.BM25,
+		FieldWeights:     payload.FieldWeights,
+		ANN:              payload.ANN,
+	})
+	req := retrieval.FederatedRequest{Request: shared}
+	if err != nil {
+		return req, err
+	}
+
+	weights := make(map[string]float64, len(payload.KBWeights))
+	for rawID, weight := range payload.KBWeights {
+		weights[strings.TrimSpace(rawID)] = weight
+	}
+	listed := make(map[string]struct{}, len(payload.KBIDs))
+	for _, rawID := range payload.KBIDs {
+		kbID := strings.TrimSpace(rawID)
+		listed[kbID] = struct{}{}
+		target := retrieval.FederatedTarget{KnowledgeBaseID: kbID, Weight: retrieval.DefaultKBWeight}
+		if weight, ok := weights[kbID]; ok {
+			target.Weight = weight
+		}
+		req.Targets = append(req.Targets, target)
+	}
+	for kbID := range weights {
+		if _, ok := listed[kbID]; !ok {
+			return req, retrieval.ErrUnknownKBWeight
+		}
+	}
+	return req, nil
+}
+
 func isRetrievalClientError(err error) bool {
 	return errors.Is(err, retrieval.ErrMissingKnowledgeBase) ||
 		errors.Is(err, retrieval.ErrMissingQuery) ||
@@ -295,7 +1018,61 @@ func isRetrievalClientError(err error) bool {
 		errors.Is(err, retrieval.ErrInvalidCreatedAfter) ||
 		errors.Is(err, retrieval.ErrMissingChunkIDs) ||
 		errors.Is(err, retrieval.ErrTooManyChunkIDs) ||
-		errors.Is(err, retrieval.ErrInvalidAdjacentRange)
+		errors.Is(err, retrieval.ErrInvalidAdjacentRange) ||
+		errors.Is(err, retrieval.ErrInvalidFusion) ||
+		errors.Is(err, retrieval.ErrInvalidRRFK) ||
+		errors.Is(err, retrieval.ErrInvalidRerankTopN) ||
+		errors.Is(err, retrieval.ErrRerankerUnavailable) ||
+		errors.Is(err, retrieval.ErrInvalidDiversity) ||
+		errors.Is(err, retrieval.ErrInvalidPageSize) ||
+		errors.Is(err, retrieval.ErrInvalidCursor) ||
+		errors.Is(err, retrieval.ErrCursorExpired) ||
+		errors.Is(err, retrieval.ErrMissingKBIDs) ||
+		errors.Is(err, retrieval.ErrTooManyKBIDs) ||
+		errors.Is(err, retrieval.ErrDuplicateKBID) ||
+		errors.Is(err, retrieval.ErrInvalidKBWeight) ||
+		errors.Is(err, retrieval.ErrUnknownKBWeight) ||
+		errors.Is(err, retrieval.ErrInvalidFilter) ||
+		errors.Is(err, retrieval.ErrInvalidLexicalScorer) ||
+		errors.Is(err, retrieval.ErrInvalidBM25K1) ||
+		errors.Is(err, retrieval.ErrInvalidBM25B) ||
+		errors.Is(err, retrieval.ErrInvalidCollapse) ||
+		errors.Is(err, retrieval.ErrInvalidMaxPerDocument) ||
+		errors.Is(err, retrieval.ErrInvalidMinSemantic) ||
+		errors.Is(err, retrieval.ErrInvalidMinFinal) ||
+		errors.Is(err, retrieval.ErrInvalidMinLexicalHits) ||
+		errors.Is(err, retrieval.ErrMissingChunkID) ||
+		errors.Is(err, retrieval.ErrMissingJudgments) ||
+		errors.Is(err, retrieval.ErrTooManyJudgments) ||
+		errors.Is(err, retrieval.ErrInvalidJudgment) ||
+		errors.Is(err, retrieval.ErrInsufficientJudgments) ||
+		errors.Is(err, retrieval.ErrInvalidAnalyticsRange) ||
+		errors.Is(err, retrieval.ErrInvalidAnalyticsLimit) ||
+		errors.Is(err, retrieval.ErrInvalidAnalyticsOffset) ||
+		errors.Is(err, retrieval.ErrInvalidAnalyticsBucket) ||
+		errors.Is(err, retrieval.ErrMissingQueryID) ||
+		errors.Is(err, retrieval.ErrMissingFeedback) ||
+		errors.Is(err, retrieval.ErrTooManyFeedback) ||
+		errors.Is(err, retrieval.ErrInvalidFeedback) ||
+		errors.Is(err, retrieval.ErrInvalidThumbs) ||
+		errors.Is(err, retrieval.ErrInvalidGrade) ||
+		errors.Is(err, retrieval.ErrInvalidFeedbackUser) ||
+		errors.Is(err, retrieval.ErrInvalidQueryID) ||
+		errors.Is(err, retrieval.ErrUnknownQueryResult) ||
+		errors.Is(err, retrieval.ErrInvalidTuningMetric) ||
+		errors.Is(err, retrieval.ErrInvalidTuningProfile) ||
+		errors.Is(err, retrieval.ErrInvalidTunedWeight) ||
+		errors.Is(err, retrieval.ErrInvalidTokenBudget) ||
+		errors.Is(err, retrieval.ErrInvalidHNSWEfSearch) ||
+		errors.Is(err, retrieval.ErrInvalidIVFFlatProbes) ||
+		errors.Is(err, retrieval.ErrConflictingANN) ||
+		errors.Is(err, retrieval.ErrMissingBatchQueries) ||
+		errors.Is(err, retrieval.ErrTooManyBatchQueries) ||
+		errors.Is(err, retrieval.ErrInvalidFieldWeights) ||
+		errors.Is(err, retrieval.ErrInvalidFreshnessDecay) ||
+		errors.Is(err, retrieval.ErrInvalidFreshnessHalfLife) ||
+		errors.Is(err, retrieval.ErrInvalidFreshnessWeight) ||
+		errors.Is(err, retrieval.ErrInvalidFreshnessDateField)
 }
 
 func writeJSON(w http.ResponseWriter, status int, payload any) {
@@ -304,6 +1081,19 @@ func writeJSON(w http.ResponseWriter, status int, payload any) {
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
+// writeClientError writes a 400, adding a JSON pointer for filter errors.
+func writeClientError(w http.ResponseWriter, err error) {
+	var filterErr *retrieval.FilterError
+	if errors.As(err, &filterErr) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error":   err.Error(),
+			"pointer": filterErr.Pointer,
+		})
+		return
+	}
+	writeError(w, http.StatusBadRequest, err.Error())
+}
+
 func writeError(w http.ResponseWriter, status int, message string) {
 	if status >= 500 {
 		message = "internal server error"
@@ -12,7 +12,27 @@ func NewRouter(service *retrievalservice.Service) http.Handler {
 	r := chi.NewRouter()
 	h := NewHandler(service)
 	r.Post("/v1/kb/{kbID}/query", h.Query)
+	r.Post("/v1/kb/{kbID}/query:batch", h.QueryBatch)
+	r.Post("/v1/kb/{kbID}/query/explain", h.Explain)
 	r.Post("/v1/kb/{kbID}/hydrate", h.Hydrate)
+	r.Post("/v1/kb/{kbID}/context", h.Context)
 	r.Post("/v1/kb/{kbID}/retrieve", h.Retrieve)
+	r.Post("/v1/kb/{kbID}/calibration/judgments", h.SubmitJudgments)
+	r.Post("/v1/kb/{kbID}/calibration", h.Calibrate)
+	r.Post("/v1/kb/{kbID}/queries/{queryID}/feedback", h.SubmitFeedback)
+	r.Get("/v1/kb/{kbID}/analytics/queries/top", h.TopQueries)
+	r.Get("/v1/kb/{kbID}/analytics/queries/zero-result", h.ZeroResultQueries)
+	r.Get("/v1/kb/{kbID}/analytics/latency", h.LatencyPercentiles)
+	r.Get("/v1/kb/{kbID}/analytics/profiles", h.ProfileDistribution)
+	r.Get("/v1/kb/{kbID}/analytics/documents/top", h.MostRetrievedDocuments)
+	r.Get("/v1/kb/{kbID}/analytics/documents/never-retrieved", h.NeverRetrievedDocuments)
+	r.Get("/v1/kb/{kbID}/feedback", h.FeedbackSummary)
+	r.Get("/v1/kb/{kbID}/feedback/chunks", h.ChunkFeedback)
+	r.Get("/v1/kb/{kbID}/tuning", h.WeightTuning)
+	r.Delete("/v1/kb/{kbID}/tuning", h.ResetWeights)
+	r.Post("/v1/kb/{kbID}/tuning/run", h.TuneWeights)
+	r.Put("/v1/kb/{kbID}/tuning/{profile}", h.PinWeight)
+	r.Delete("/v1/kb/{kbID}/tuning/{profile}", h.ResetProfileWeight)
+	r.Post("/v1/query", h.FederatedQuery)
 	return r
 }
@@ -0,0 +1,131 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type pinWeightRequest struct {
+	SemanticWeight *float64 `json:"semantic_weight"`
+}
+
+// WeightTuning shows the knowledge base's default and tuned semantic weights.
+func (h *Handler) WeightTuning(w http.ResponseWriter, r *http.Request) {
+	h.serveTuning(w, r, "/v1/kb/{kbID}/tuning", func(ctx context.Context, kbID string) (any, error) {
+		return h.service.WeightTuning(ctx, kbID)
+	})
+}
+
+// ResetWeights removes every tuned or pinned weight of the knowledge base.
+func (h *Handler) ResetWeights(w http.ResponseWriter, r *http.Request) {
+	h.serveTuning(w, r, "/v1/kb/{kbID}/tuning", func(ctx context.Context, kbID string) (any, error) {
+		return h.service.ResetWeights(ctx, kbID, "")
+	})
+}
+
+// ResetProfileWeight removes the tuned or pinned weight of one class.
+func (h *Handler) ResetProfileWeight(w http.ResponseWriter, r *http.Request) {
+	h.serveTuning(w, r, "/v1/kb/{kbID}/tuning/{profile}", func(ctx context.Context, kbID string) (any, error) {
+		return h.service.ResetWeights(ctx, kbID, chi.URLParam(r, "profile"))
+	})
+}
+
+// TuneWeights refits the knowledge base's weights now; the metric query
+// parameter picks ndcg or mrr.
+func (h *Handler) TuneWeights(w http.ResponseWriter, r *http.Request) {
+	h.serveTuning(w, r, "/v1/kb/{kbID}/tuning/run", func(ctx context.Context, kbID string) (any, error) {
+		return h.service.TuneWeights(ctx, kbID, r.URL.Query().Get("metric"))
+	})
+}
+
+// PinWeight pins one class's semantic weight so the tuner keeps it.
+func (h *Handler) PinWeight(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	statusCode := http.StatusOK
+	outcome := "success"
+	defer func() {
+		h.recordMetrics(r, "/v1/kb/{kbID}/tuning/{profile}", start, statusCode, outcome, 0)
+	}()
+
+	kbID := strings.TrimSpace(chi.URLParam(r, "kbID"))
+	if kbID == "" {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, "kbID is required")
+		return
+	}
+
+	var payload pinWeightRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	tuning, err := h.service.PinWeight(r.Context(), kbID, chi.URLParam(r, "profile"), payload.SemanticWeight)
+	if err != nil {
+		if isRetrievalClientError(err) {
+			statusCode = http.StatusBadRequest
+			outcome = "client_error"
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		statusCode = http.StatusInternalServerError
+		outcome = "server_error"
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	statusCode = http.StatusOK
+	writeJSON(w, http.StatusOK, tuning)
+}
+
+// serveTuning writes the result of run for the knowledge base in the path.
+func (h *Handler) serveTuning(
+	w http.ResponseWriter,
+	r *http.Request,
+	route string,
+	run func(context.Context, string) (any, error),
+) {
+	start := time.Now()
+	statusCode := http.StatusOK
+	outcome := "success"
+	defer func() {
+		h.recordMetrics(r, route, start, statusCode, outcome, 0)
+	}()
+
+	kbID := strings.TrimSpace(chi.URLParam(r, "kbID"))
+	if kbID == "" {
+		statusCode = http.StatusBadRequest
+		outcome = "client_error"
+		writeError(w, http.StatusBadRequest, "kbID is required")
+		return
+	}
+
+	result, err := run(r.Context(), kbID)
+	if err != nil {
+		if isRetrievalClientError(err) {
+			statusCode = http.StatusBadRequest
+			outcome = "client_error"
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		statusCode = http.StatusInternalServerError
+		outcome = "server_error"
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	statusCode = http.StatusOK
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,168 @@
+package retrieval
+
+import (
+	"strings"
+	"unicode"
+)
+
+// QueryTerm is one word, prefix or quoted phrase of a parsed query.
+type QueryTerm struct {
+	Text    string `json:"text"`
+	Phrase  bool   `json:"phrase,omitempty"`
+	Prefix  bool   `json:"prefix,omitempty"`
+	Negated bool   `json:"negated,omitempty"`
+}
+
+// QueryClause matches when any of its terms match.
+type QueryClause struct {
+	Any []QueryTerm `json:"any"`
+}
+
+// ParsedQuery is the structured form of a web-search style query: every
+// clause must match. Terms are separated by whitespace, "double quotes" mark
+// phrases, OR joins its neighbours into one clause, a leading - negates a
+// term and a trailing * matches a prefix.
+type ParsedQuery struct {
+	All []QueryClause `json:"all"`
+}
+
+// ParseQuery parses web-search syntax. It never fails: stray operators are
+// dropped and an unterminated quote runs to the end of the query.
+func ParseQuery(query string) ParsedQuery {
+	var parsed ParsedQuery
+	pendingOr := false
+	for _, token := range tokenizeQuery(query) {
+		if token.text == "OR" && !token.quoted && !token.negated {
+			pendingOr = len(parsed.All) > 0
+			continue
+		}
+
+		term, ok := newQueryTerm(token)
+		if !ok {
+			continue
+		}
+		if pendingOr {
+			last := &parsed.All[len(parsed.All)-1]
+			last.Any = append(last.Any, term)
+			pendingOr = false
+			continue
+		}
+		parsed.All = append(parsed.All, QueryClause{Any: []QueryTerm{term}})
+	}
+	return parsed
+}
+
+// IsEmpty reports whether the query had no searchable terms.
+func (q ParsedQuery) IsEmpty() bool {
+	return len(q.All) == 0
+}
+
+// HasPhrase reports whether any term is a quoted phrase.
+func (q ParsedQuery) HasPhrase() bool {
+	return q.any(func(term QueryTerm) bool { return term.Phrase })
+}
+
+// HasOperators reports whether the query uses OR, negation or prefix matching.
+func (q ParsedQuery) HasOperators() bool {
+	for _, clause := range q.All {
+		if len(clause.Any) > 1 {
+			return true
+		}
+	}
+	return q.any(func(term QueryTerm) bool { return term.Negated || term.Prefix })
+}
+
+// PositiveText joins the text of every term that is not negated, for uses
+// such as embedding that take plain text.
+func (q ParsedQuery) PositiveText() string {
+	parts := make([]string, 0, len(q.All))
+	for _, clause := range q.All {
+		for _, term := range clause.Any {
+			if !term.Negated {
+				parts = append(parts, term.Text)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func (q ParsedQuery) any(match func(QueryTerm) bool) bool {
+	for _, clause := range q.All {
+		for _, term := range clause.Any {
+			if match(term) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type queryToken struct {
+	text    string
+	quoted  bool
+	negated bool
+}
+
+func tokenizeQuery(query string) []queryToken {
+	var tokens []queryToken
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		token := queryToken{}
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			token.negated = true
+			i++
+		}
+		if runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			token.text = string(runes[i+1 : end])
+			token.quoted = true
+			i = end + 1
+		} else {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) {
+				end++
+			}
+			token.text = string(runes[i:end])
+			i = end
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func newQueryTerm(token queryToken) (QueryTerm, bool) {
+	term := QueryTerm{Negated: token.negated}
+	if token.quoted {
+		term.Text = strings.Join(strings.Fields(token.text), " ")
+		term.Phrase = true
+		return term, term.Text != ""
+	}
+
+	text := token.text
+	if strings.HasSuffix(text, "*") {
+		// Prefix terms are matched as a single lexeme, so keep only the
+		// letters and digits of the word.
+		word := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, strings.TrimRight(text, "*"))
+		if word != "" {
+			term.Text = word
+			term.Prefix = true
+			return term, true
+		}
+		text = strings.TrimRight(text, "*")
+	}
+	term.Text = text
+	return term, term.Text != "" && term.Text != "-"
+}
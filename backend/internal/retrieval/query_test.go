@@ -0,0 +1,75 @@
+package retrieval
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []QueryClause
+	}{
+		{
+			name:  "plain words",
+			query: "connection  pool",
+			want: []QueryClause{
+				{Any: []QueryTerm{{Text: "connection"}}},
+				{Any: []QueryTerm{{Text: "pool"}}},
+			},
+		},
+		{
+			name:  "phrase, negation and prefix",
+			query: `"retry budget" -deprecated timeout*`,
+			want: []QueryClause{
+				{Any: []QueryTerm{{Text: "retry budget", Phrase: true}}},
+				{Any: []QueryTerm{{Text: "deprecated", Negated: true}}},
+				{Any: []QueryTerm{{Text: "timeout", Prefix: true}}},
+			},
+		},
+		{
+			name:  "or joins neighbours",
+			query: `postgres OR "pg bouncer" OR -mysql tuning`,
+			want: []QueryClause{
+				{Any: []QueryTerm{{Text: "postgres"}, {Text: "pg bouncer", Phrase: true}, {Text: "mysql", Negated: true}}},
+				{Any: []QueryTerm{{Text: "tuning"}}},
+			},
+		},
+		{
+			name:  "stray operators are dropped",
+			query: `OR cache - ** "unterminated phrase`,
+			want: []QueryClause{
+				{Any: []QueryTerm{{Text: "cache"}}},
+				{Any: []QueryTerm{{Text: "unterminated phrase", Phrase: true}}},
+			},
+		},
+		{
+			name:  "prefix keeps letters and digits",
+			query: "v1.2*",
+			want:  []QueryClause{{Any: []QueryTerm{{Text: "v12", Prefix: true}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseQuery(tt.query)
+			if !reflect.DeepEqual(got.All, tt.want) {
+				t.Fatalf("ParseQuery(%q) = %+v, want %+v", tt.query, got.All, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsedQuery_PositiveTextAndOperators(t *testing.T) {
+	parsed := ParseQuery(`"retry budget" -deprecated timeout*`)
+	if got := parsed.PositiveText(); got != "retry budget timeout" {
+		t.Fatalf("PositiveText() = %q", got)
+	}
+	if !parsed.HasPhrase() || !parsed.HasOperators() {
+		t.Fatalf("HasPhrase/HasOperators = %v/%v, want true/true", parsed.HasPhrase(), parsed.HasOperators())
+	}
+	if ParseQuery("plain words only").HasOperators() {
+		t.Fatalf("HasOperators() = true for a plain query")
+	}
+}
@@ -7,13 +7,37 @@ import (
 
 type Repository interface {
 	InsertRetrievalRequest(ctx context.Context, req RetrievalRequestRecord) (*RetrievalRequestRecord, error)
-	UpdateRetrievalRequest(ctx context.Context, requestID string, resultCount int, latencyMS int64, emptyResult bool) error
+	UpdateRetrievalRequest(ctx context.Context, requestID string, resultCount int, latencyMS int64, emptyResult bool, emptyReason string) error
 	InsertRetrievalResults(ctx context.Context, results []RetrievalResultRecord) error
 	SearchSemantic(ctx context.Context, params SearchParams) ([]ScoredChunk, error)
 	SearchLexical(ctx context.Context, params SearchParams) ([]ScoredChunk, error)
+	ExplainChunk(ctx context.Context, params SearchParams, chunkID string) (*ChunkExplanation, error)
 	GetChunksWithDocuments(ctx context.Context, chunkIDs []string) ([]ChunkRecord, error)
 	GetChunksWithDocumentsForKB(ctx context.Context, knowledgeBaseID string, chunkIDs []string) ([]ChunkRecord, error)
 	GetChunksByDocumentVersionRange(ctx context.Context, documentVersionID string, startSeq int32, endSeq int32) ([]ChunkRecord, error)
+	GetChunkVectors(ctx context.Context, vectorDimension int, chunkIDs []string) (map[string][]float32, error)
+	GetChunkDocumentIDs(ctx context.Context, chunkIDs []string) (map[string]string, error)
+	GetLexemes(ctx context.Context, knowledgeBaseID string, words []string) (map[string][]string, error)
+	SaveRetrievalRanking(ctx context.Context, requestID string, candidates []RankedCandidate) error
+	GetRetrievalRanking(ctx context.Context, knowledgeBaseID string, requestID string) (*RetrievalRanking, error)
+	GetKnowledgeBaseMetadata(ctx context.Context, knowledgeBaseID string) (map[string]any, error)
+	InsertCalibrationJudgments(ctx context.Context, knowledgeBaseID string, judgments []RelevanceJudgment) (int, error)
+	GetCalibrationSamples(ctx context.Context, knowledgeBaseID string, fusionMethod string) ([]CalibrationSample, error)
+	SaveScoreCalibration(ctx context.Context, calibration ScoreCalibration) error
+	GetScoreCalibration(ctx context.Context, knowledgeBaseID string, fusionMethod string) (*ScoreCalibration, error)
+	ListTopQueries(ctx context.Context, query AnalyticsQuery) ([]QueryStat, error)
+	ListZeroResultQueries(ctx context.Context, query AnalyticsQuery) ([]ZeroResultQuery, error)
+	ListLatencyPercentiles(ctx context.Context, query AnalyticsQuery) ([]LatencyBucket, error)
+	ListProfileDistribution(ctx context.Context, query AnalyticsQuery) ([]ProfileUsage, []WeightUsage, error)
+	ListMostRetrievedDocuments(ctx context.Context, query AnalyticsQuery) ([]DocumentUsage, error)
+	ListNeverRetrievedDocuments(ctx context.Context, query AnalyticsQuery) ([]DocumentUsage, error)
+	InsertFeedback(ctx context.Context, submission FeedbackSubmission) (int, error)
+	GetFeedbackSummary(ctx context.Context, query AnalyticsQuery) (*FeedbackSummary, error)
+	ListChunkFeedback(ctx context.Context, query AnalyticsQuery) ([]ChunkFeedback, error)
+	ListKnowledgeBaseIDs(ctx context.Context) ([]string, error)
+	ListTuningQueries(ctx context.Context, knowledgeBaseID string, since time.Time) ([]TuningQuery, error)
+	SaveTunedWeight(ctx context.Context, knowledgeBaseID string, profile string, tuned TunedWeight, skipPinned bool) (bool, error)
+	DeleteTunedWeights(ctx context.Context, knowledgeBaseID string, profile string) error
 }
 
 type RetrievalRequestRecord struct {
@@ -27,6 +51,10 @@ type RetrievalRequestRecord struct {
 	LatencyMS     int64
 	EmptyResult   bool
 	CreatedAt     time.Time
+	// FederatedRequestID links the per-KB rows of one federated query.
+	FederatedRequestID *string
+	FusionMethod       string
+	RetrievalProfile   string
 }
 
 type RetrievalResultRecord struct {
@@ -37,9 +65,38 @@ type RetrievalResultRecord struct {
 	SemanticScore      float64
 	LexicalScore       float64
 	FinalScore         float64
+	RerankScore        *float64
+	SemanticRank       *int
+	LexicalRank        *int
 	CreatedAt          time.Time
 }
 
+// RankedCandidate is one entry of a stored fused ranking, kept so later pages
+// can be served without recomputing the search.
+type RankedCandidate struct {
+	ChunkID      string    `json:"chunk_id"`
+	Semantic     float64   `json:"semantic"`
+	Lexical      float64   `json:"lexical"`
+	Rerank       *float64  `json:"rerank,omitempty"`
+	Freshness    *float64  `json:"freshness,omitempty"`
+	Final        float64   `json:"final"`
+	SemanticRank *int      `json:"semantic_rank,omitempty"`
+	LexicalRank  *int      `json:"lexical_rank,omitempty"`
+	Siblings     []Sibling `json:"siblings,omitempty"`
+	Confidence   *float64  `json:"confidence,omitempty"`
+}
+
+// RetrievalRanking is the stored ranking of a retrieval request.
+type RetrievalRanking struct {
+	RequestID       string
+	KnowledgeBaseID string
+	Query           string
+	TopK            int
+	HybridWeight    float64
+	CreatedAt       time.Time
+	Candidates      []RankedCandidate
+}
+
 type SearchParams struct {
 	KnowledgeBaseID string
 	Query           string
@@ -51,7 +108,40 @@ type SearchParams struct {
 	TagsFilter      map[string]any
 	CreatedAfter    *time.Time
 	CreatedBefore   *time.Time
-	Limit           int
+	// SectionPathPrefix is a normalized breadcrumb such as "Guide > Install".
+	SectionPathPrefix  *string
+	Frontmatter        map[string]any
+	BreadcrumbContains *string
+	Filter             *FilterExpr
+	// ParsedQuery is Query in web-search syntax; when set, SearchLexical
+	// matches it instead of treating Query as plain text.
+	ParsedQuery *ParsedQuery
+	// LexicalScorer selects ts_rank or bm25 for SearchLexical; BM25K1 and
+	// BM25B only apply to bm25.
+	LexicalScorer string
+	BM25K1        float64
+	BM25B         float64
+	// FieldWeights rank title, breadcrumb and path matches alongside content
+	// when Enabled.
+	FieldWeights FieldWeights
+	Limit        int
+	// ANN applies only to SearchSemantic.
+	ANN ANNOptions
+}
+
+// ChunkExplanation is how one chunk relates to a search, computed whether or
+// not the chunk was a candidate.
+type ChunkExplanation struct {
+	ChunkID         string
+	InKnowledgeBase bool
+	ActiveVersion   bool
+	PassesFilters   bool
+	// CosineDistance is nil when the chunk has no embedding of the query's dimension.
+	CosineDistance *float64
+	LexicalMatch   bool
+	TSRank         float64
+	// MatchedLexemes are the chunk's lexemes matching a positive query term.
+	MatchedLexemes []string
 }
 
 type ScoredChunk struct {
@@ -61,6 +151,7 @@ type ScoredChunk struct {
 
 type ChunkRecord struct {
 	ChunkID           string
+	KnowledgeBaseID   string
 	DocumentID        string
 	DocumentVersionID string
 	DocumentPath      string
@@ -71,4 +162,5 @@ type ChunkRecord struct {
 	VersionNumber     int32
 	SequenceNumber    int32
 	SourceMetadata    map[string]any
+	VersionCreatedAt  time.Time
 }
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"ragtime-backend/internal/retrieval"
+	"ragtime-backend/internal/storage/sqlc"
+)
+
+func (r *PostgresStore) ListTopQueries(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.QueryStat, error) {
+	kbID, err := uuid.Parse(query.KnowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.queries.ListTopQueries(ctx, sqlc.ListTopQueriesParams{
+		KbID:      kbID,
+		StartTime: query.From,
+		EndTime:   query.To,
+		Limit:     int32(query.Limit),
+		Offset:    int32(query.Offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]retrieval.QueryStat, 0, len(rows))
+	for _, row := range rows {
+		stats = append(stats, retrieval.QueryStat{
+			Query:          row.NormalizedQuery,
+			RequestCount:   row.RequestCount,
+			EmptyCount:     row.EmptyCount,
+			AvgResultCount: row.AvgResultCount,
+			AvgLatencyMS:   row.AvgLatencyMs,
+			LastSeenAt:     row.LastSeenAt,
+		})
+	}
+	return stats, nil
+}
+
+func (r *PostgresStore) ListZeroResultQueries(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.ZeroResultQuery, error) {
+	kbID, err := uuid.Parse(query.KnowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.queries.ListZeroResultQueries(ctx, sqlc.ListZeroResultQueriesParams{
+		KbID:      kbID,
+		StartTime: query.From,
+		EndTime:   query.To,
+		Limit:     int32(query.Limit),
+		Offset:    int32(query.Offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]retrieval.ZeroResultQuery, 0, len(rows))
+	for _, row := range rows {
+		reasons := row.EmptyReasons
+		if reasons == nil {
+			reasons = []string{}
+		}
+		stats = append(stats, retrieval.ZeroResultQuery{
+			Query:        row.NormalizedQuery,
+			RequestCount: row.RequestCount,
+			EmptyReasons: reasons,
+			LastSeenAt:   row.LastSeenAt,
+		})
+	}
+	return stats, nil
+}
+
+func (r *PostgresStore) ListLatencyPercentiles(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.LatencyBucket, error) {
+	kbID, err := uuid.Parse(query.KnowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.queries.ListLatencyPercentiles(ctx, sqlc.ListLatencyPercentilesParams{
+		Bucket:    query.Bucket,
+		KbID:      kbID,
+		StartTime: query.From,
+		EndTime:   query.To,
+		Limit:     int32(query.Limit),
+		Offset:    int32(query.Offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]retrieval.LatencyBucket, 0, len(rows))
+	for _, row := range rows {
+		buckets = append(buckets, retrieval.LatencyBucket{
+			BucketStart:  row.BucketStart.UTC(),
+			RequestCount: row.RequestCount,
+			P50MS:        row.P50Ms,
+			P90MS:        row.P90Ms,
+			P95MS:        row.P95Ms,
+			P99MS:        row.P99Ms,
+			MaxMS:        row.MaxMs,
+		})
+	}
+	return buckets, nil
+}
+
+func (r *PostgresStore) ListProfileDistribution(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.ProfileUsage, []retrieval.WeightUsage, error) {
+	kbID, err := uuid.Parse(query.KnowledgeBaseID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	profileRows, err := r.queries.ListProfileDistribution(ctx, sqlc.ListProfileDistributionParams{
+		KbID:      kbID,
+		StartTime: query.From,
+		EndTime:   query.To,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	weightRows, err := r.queries.ListSemanticWeightDistribution(ctx, sqlc.ListSemanticWeightDistributionParams{
+		KbID:      kbID,
+		StartTime: query.From,
+		EndTime:   query.To,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	profiles := make([]retrieval.ProfileUsage, 0, len(profileRows))
+	for _, row := range profileRows {
+		profiles = append(profiles, retrieval.ProfileUsage{
+			Profile:           row.RetrievalProfile,
+			RequestCount:      row.RequestCount,
+			AvgSemanticWeight: row.AvgSemanticWeight,
+		})
+	}
+	weights := make([]retrieval.WeightUsage, 0, len(weightRows))
+	for _, row := range weightRows {
+		weights = append(weights, retrieval.WeightUsage{
+			SemanticWeight: row.SemanticWeight,
+			RequestCount:   row.RequestCount,
+		})
+	}
+	return profiles, weights, nil
+}
+
+func (r *PostgresStore) ListMostRetrievedDocuments(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.DocumentUsage, error) {
+	kbID, err := uuid.Parse(query.KnowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.queries.ListMostRetrievedDocuments(ctx, sqlc.ListMostRetrievedDocumentsParams{
+		KbID:      kbID,
+		StartTime: query.From,
+		EndTime:   query.To,
+		Limit:     int32(query.Limit),
+		Offset:    int32(query.Offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	documents := make([]retrieval.DocumentUsage, 0, len(rows))
+	for _, row := range rows {
+		avgRank := row.AvgRank
+		lastRetrievedAt := row.LastRetrievedAt
+		documents = append(documents, retrieval.DocumentUsage{
+			DocumentID:      row.DocumentID.String(),
+			Path:            row.DocumentPath,
+			Title:           nullStringPtr(row.DocumentTitle),
+			RequestCount:    row.RequestCount,
+			ResultCount:     row.ResultCount,
+			AvgRank:         &avgRank,
+			LastRetrievedAt: &lastRetrievedAt,
+		})
+	}
+	return documents, nil
+}
+
+func (r *PostgresStore) ListNeverRetrievedDocuments(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.DocumentUsage, error) {
+	kbID, err := uuid.Parse(query.KnowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.queries.ListNeverRetrievedDocuments(ctx, sqlc.ListNeverRetrievedDocumentsParams{
+		KbID:      kbID,
+		StartTime: query.From,
+		EndTime:   query.To,
+		Limit:     int32(query.Limit),
+		Offset:    int32(query.Offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	documents := make([]retrieval.DocumentUsage, 0, len(rows))
+	for _, row := range rows {
+		createdAt := row.CreatedAt
+		documents = append(documents, retrieval.DocumentUsage{
+			DocumentID: row.DocumentID.String(),
+			Path:       row.DocumentPath,
+			Title:      nullStringPtr(row.DocumentTitle),
+			CreatedAt:  &createdAt,
+		})
+	}
+	return documents, nil
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ragtime-backend/internal/retrieval"
+	"ragtime-backend/internal/storage/sqlc"
+)
+
+// InsertFeedback stores the labels of chunks the query returned and reports
+// how many were stored; labels of other chunks are skipped. It returns
+// ErrInvalidQueryID for a malformed query ID and ErrUnknownQueryResult when no
+// label matched a result of a logged query in the knowledge base.
+func (r *PostgresStore) InsertFeedback(ctx context.Context, submission retrieval.FeedbackSubmission) (int, error) {
+	kbID, err := uuid.Parse(submission.KnowledgeBaseID)
+	if err != nil {
+		return 0, err
+	}
+	requestID, err := uuid.Parse(submission.QueryID)
+	if err != nil {
+		return 0, retrieval.ErrInvalidQueryID
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	queries := r.queries.WithTx(tx)
+	createdAt := time.Now().UTC()
+	stored := 0
+	for _, label := range submission.Labels {
+		chunkID, err := uuid.Parse(label.ChunkID)
+		if err != nil {
+			continue
+		}
+		var thumbs sql.NullInt16
+		switch label.Thumbs {
+		case retrieval.FeedbackThumbsUp:
+			thumbs = sql.NullInt16{Int16: 1, Valid: true}
+		case retrieval.FeedbackThumbsDown:
+			thumbs = sql.NullInt16{Int16: -1, Valid: true}
+		}
+		var grade sql.NullInt16
+		if label.Grade != nil {
+			grade = sql.NullInt16{Int16: int16(*label.Grade), Valid: true}
+		}
+
+		affected, err := queries.InsertRetrievalFeedback(ctx, sqlc.InsertRetrievalFeedbackParams{
+			ID:                 uuid.New(),
+			UserID:             sql.NullString{String: submission.UserID, Valid: submission.UserID != ""},
+			Thumbs:             thumbs,
+			Grade:              grade,
+			Clicked:            label.Clicked,
+			Cited:              label.Cited,
+			CreatedAt:          createdAt,
+			KbID:               kbID,
+			RetrievalRequestID: requestID,
+			ChunkID:            chunkID,
+		})
+		if err != nil {
+			return 0, err
+		}
+		stored += int(affected)
+	}
+	if stored == 0 {
+		return 0, retrieval.ErrUnknownQueryResult
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return stored, nil
+}
+
+func (r *PostgresStore) GetFeedbackSummary(ctx context.Context, query retrieval.AnalyticsQuery) (*retrieval.FeedbackSummary, error) {
+	kbID, err := uuid.Parse(query.KnowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := r.queries.GetFeedbackSummary(ctx, sqlc.GetFeedbackSummaryParams{
+		KbID:      kbID,
+		StartTime: query.From,
+		EndTime:   query.To,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &retrieval.FeedbackSummary{
+		KnowledgeBaseID: query.KnowledgeBaseID,
+		From:            query.From,
+		To:              query.To,
+		LabelCount:      row.LabelCount,
+		QueryCount:      row.QueryCount,
+		ThumbsUp:        row.ThumbsUp,
+		ThumbsDown:      row.ThumbsDown,
+		GradedCount:     row.GradedCount,
+		AvgGrade:        nullFloat64Ptr(row.AvgGrade),
+		ClickedCount:    row.ClickedCount,
+		CitedCount:      row.CitedCount,
+	}, nil
+}
+
+func (r *PostgresStore) ListChunkFeedback(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.ChunkFeedback, error) {
+	kbID, err := uuid.Parse(query.KnowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.queries.ListChunkFeedback(ctx, sqlc.ListChunkFeedbackParams{
+		KbID:      kbID,
+		StartTime: query.From,
+		EndTime:   query.To,
+		Limit:     int32(query.Limit),
+		Offset:    int32(query.Offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make([]retrieval.ChunkFeedback, 0, len(rows))
+	for _, row := range rows {
+		chunks = append(chunks, retrieval.ChunkFeedback{
+			ChunkID:      row.ChunkID.String(),
+			DocumentID:   row.DocumentID.String(),
+			DocumentPath: row.DocumentPath,
+			LabelCount:   row.LabelCount,
+			ThumbsUp:     row.ThumbsUp,
+			ThumbsDown:   row.ThumbsDown,
+			GradedCount:  row.GradedCount,
+			AvgGrade:     nullFloat64Ptr(row.AvgGrade),
+			ClickedCount: row.ClickedCount,
+			CitedCount:   row.CitedCount,
+			AvgRank:      row.AvgRank,
+		})
+	}
+	return chunks, nil
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+// sqlArgs collects positional query arguments and hands out their placeholders.
+type sqlArgs struct {
+	values []any
+}
+
+func (a *sqlArgs) add(value any) string {
+	a.values = append(a.values, value)
+	return fmt.Sprintf("$%d", len(a.values))
+}
+
+// searchPredicates builds the WHERE clause shared by semantic and lexical
+// search: active versions in the knowledge base plus the request filters.
+func searchPredicates(params retrieval.SearchParams, args *sqlArgs) (string, error) {
+	kbID, err := uuid.Parse(params.KnowledgeBaseID)
+	if err != nil {
+		return "", err
+	}
+
+	predicates := []string{
+		"dv.is_active = true",
+		"c.kb_id = " + args.add(kbID),
+	}
+	filters, err := filterPredicates(params, args)
+	if err != nil {
+		return "", err
+	}
+	predicates = append(predicates, filters...)
+
+	return strings.Join(predicates, "\n  AND "), nil
+}
+
+// filterPredicates builds one predicate per request filter, without the
+// knowledge base and active version conditions.
+func filterPredicates(params retrieval.SearchParams, args *sqlArgs) ([]string, error) {
+	var predicates []string
+	if params.DocumentType != nil && *params.DocumentType != "" {
+		predicates = append(predicates, "d.document_type = "+args.add(*params.DocumentType))
+	}
+	if params.PathPrefix != nil && *params.PathPrefix != "" {
+		predicates = append(predicates, "d.path LIKE "+args.add(*params.PathPrefix))
+	}
+	if params.Source != nil && *params.Source != "" {
+		predicates = append(predicates, "d.source_metadata ->> 'source' = "+args.add(*params.Source))
+	}
+	if len(params.TagsFilter) > 0 {
+		predicates = append(predicates, "d.source_metadata @> "+args.add(toJSON(params.TagsFilter))+"::jsonb")
+	}
+	if params.CreatedAfter != nil {
+		predicates = append(predicates, "dv.created_at >= "+args.add(*params.CreatedAfter))
+	}
+	if params.CreatedBefore != nil {
+		predicates = append(predicates, "dv.created_at <= "+args.add(*params.CreatedBefore))
+	}
+	if params.SectionPathPrefix != nil && *params.SectionPathPrefix != "" {
+		section := *params.SectionPathPrefix
+		predicates = append(predicates, fmt.Sprintf(
+			"(c.metadata ->> 'breadcrumb' = %s OR c.metadata ->> 'breadcrumb' LIKE %s)",
+			args.add(section),
+			args.add(escapeLike(section)+" > %"),
+		))
+	}
+	if len(params.Frontmatter) > 0 {
+		predicates = append(predicates, frontmatterPredicates(params.Frontmatter, args)...)
+	}
+	if params.BreadcrumbContains != nil && *params.BreadcrumbContains != "" {
+		predicates = append(predicates, "c.metadata ->> 'breadcrumb' ILIKE "+args.add("%"+escapeLike(*params.BreadcrumbContains)+"%"))
+	}
+	if params.Filter != nil {
+		clause, err := compileFilter(params.Filter, args)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, clause)
+	}
+	return predicates, nil
+}
+
+// frontmatterPredicates builds one predicate per frontmatter key, in key
+// order. The chunker stores frontmatter scalars as strings and lists as
+// arrays of strings, so each value is compared in its text form and matches
+// either the key's value or an element of its list. Both sides are
+// containment on metadata -> 'frontmatter' so chunks_frontmatter_gin_idx
+// serves them.
+func frontmatterPredicates(frontmatter map[string]any, args *sqlArgs) []string {
+	keys := make([]string, 0, len(frontmatter))
+	for key := range frontmatter {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	predicates := make([]string, 0, len(keys))
+	for _, key := range keys {
+		keyArg := args.add(key)
+		valueArg := args.add(frontmatterText(frontmatter[key]))
+		predicates = append(predicates, fmt.Sprintf(
+			"(c.metadata -> 'frontmatter' @> jsonb_build_object(%[1]s::text, %[2]s::text) OR c.metadata -> 'frontmatter' @> jsonb_build_object(%[1]s::text, jsonb_build_array(%[2]s::text)))",
+			keyArg,
+			valueArg,
+		))
+	}
+	return predicates
+}
+
+// frontmatterText renders a frontmatter filter value the way the chunker
+// stores it: booleans as true/false and numbers without trailing zeros.
+func frontmatterText(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// compileFilter turns a validated filter expression into a SQL predicate.
+// Every key path and value is bound as a parameter; only operator structure
+// is rendered into the SQL text.
+func compileFilter(expr *retrieval.FilterExpr, args *sqlArgs) (string, error) {
+	switch {
+	case expr.And != nil || expr.Or != nil:
+		children, joiner := expr.And, " AND "
+		if expr.Or != nil {
+			children, joiner = expr.Or, " OR "
+		}
+		parts := make([]string, 0, len(children))
+		for i := range children {
+			part, err := compileFilter(&children[i], args)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, part)
+		}
+		return "(" + strings.Join(parts, joiner) + ")", nil
+	case expr.Not != nil:
+		inner, err := compileFilter(expr.Not, args)
+		if err != nil {
+			return "", err
+		}
+		// A missing key yields NULL, so coalesce to make NOT match it.
+		return "(NOT COALESCE(" + inner + ", false))", nil
+	case expr.Eq != nil:
+		value, err := json.Marshal(expr.Eq.Value)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s = %s::jsonb)", filterJSONPath(expr.Eq.Field, args), args.add(string(value))), nil
+	case expr.In != nil:
+		values := make([]string, 0, len(expr.In.Values))
+		for _, item := range expr.In.Values {
+			encoded, err := json.Marshal(item)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, string(encoded))
+		}
+		return fmt.Sprintf("(%s = ANY(%s::jsonb[]))", filterJSONPath(expr.In.Field, args), args.add(pq.Array(values))), nil
+	case expr.Range != nil:
+		return compileFilterRange(expr.Range, args), nil
+	case expr.Exists != nil:
+		return fmt.Sprintf("(%s IS NOT NULL)", filterJSONPath(expr.Exists.Field, args)), nil
+	case expr.Prefix != nil:
+		prefix, _ := expr.Prefix.Value.(string)
+		return fmt.Sprintf("(%s LIKE %s)", filterTextPath(expr.Prefix.Field, args), args.add(escapeLike(prefix)+"%")), nil
+	default:
+		return "", retrieval.ErrInvalidFilter
+	}
+}
+
+func compileFilterRange(rng *retrieval.FilterRange, args *sqlArgs) string {
+	numeric := false
+	for _, bound := range []any{rng.Gt, rng.Gte, rng.Lt, rng.Lte} {
+		if bound == nil {
+			continue
+		}
+		_, isString := bound.(string)
+		numeric = !isString
+		break
+	}
+
+	// CASE guards the cast so non-matching JSON types compare as NULL instead of erroring.
+	jsonPath := filterJSONPath(rng.Field, args)
+	var target, cast string
+	if numeric {
+		target = fmt.Sprintf("(CASE WHEN jsonb_typeof(%s) = 'number' THEN (%s #>> '{}')::numeric END)", jsonPath, jsonPath)
+		cast = "::numeric"
+	} else {
+		target = fmt.Sprintf("(CASE WHEN jsonb_typeof(%s) = 'string' THEN %s #>> '{}' END)", jsonPath, jsonPath)
+		cast = "::text"
+	}
+
+	parts := make([]string, 0, 4)
+	for _, bound := range []struct {
+		op    string
+		value any
+	}{{">", rng.Gt}, {">=", rng.Gte}, {"<", rng.Lt}, {"<=", rng.Lte}} {
+		if bound.value == nil {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s%s", target, bound.op, args.add(fmt.Sprint(bound.value)), cast))
+	}
+	return "(" + strings.Join(parts, " AND ") + ")"
+}
+
+func filterJSONPath(field string, args *sqlArgs) string {
+	scope, path := retrieval.SplitFilterField(field)
+	return fmt.Sprintf("(%s #> %s::text[])", filterColumn(scope), args.add(pq.Array(path)))
+}
+
+func filterTextPath(field string, args *sqlArgs) string {
+	scope, path := retrieval.SplitFilterField(field)
+	return fmt.Sprintf("(%s #>> %s::text[])", filterColumn(scope), args.add(pq.Array(path)))
+}
+
+func filterColumn(scope string) string {
+	if scope == retrieval.FilterScopeMetadata {
+		return "c.metadata"
+	}
+	return "d.source_metadata"
+}
+
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(value)
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"ragtime-backend/internal/chunking/markdown"
+	"ragtime-backend/internal/retrieval"
+)
+
+func TestCompileFilter_BindsKeysAndValues(t *testing.T) {
+	expr, err := retrieval.ParseFilter([]byte(`{"and":[
+		{"eq":{"field":"source_metadata.team","value":"infra"}},
+		{"not":{"prefix":{"field":"metadata.path","value":"50%_off"}}}
+	]}`))
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+
+	args := &sqlArgs{}
+	clause, err := compileFilter(expr, args)
+	if err != nil {
+		t.Fatalf("compileFilter() error = %v", err)
+	}
+
+	want := `(((d.source_metadata #> $1::text[]) = $2::jsonb) AND (NOT COALESCE(((c.metadata #>> $3::text[]) LIKE $4), false)))`
+	if clause != want {
+		t.Fatalf("compileFilter() =\n%s\nwant\n%s", clause, want)
+	}
+	if strings.Contains(clause, "infra") || strings.Contains(clause, "team") {
+		t.Fatalf("compileFilter() inlined user input: %s", clause)
+	}
+	if len(args.values) != 4 || args.values[1] != `"infra"` || args.values[3] != `50\%\_off%` {
+		t.Fatalf("compileFilter() args = %#v", args.values)
+	}
+}
+
+func TestSearchPredicates_ChunkMarkdownFilters(t *testing.T) {
+	section := "Guide > 100%"
+	contains := "install"
+	args := &sqlArgs{}
+	clause, err := searchPredicates(retrieval.SearchParams{
+		KnowledgeBaseID:    "8c1f9a36-7f5d-4d8c-9d0b-2a7c3e0f6b11",
+		SectionPathPrefix:  &section,
+		Frontmatter:        map[string]any{"owner": "platform"},
+		BreadcrumbContains: &contains,
+	}, args)
+	if err != nil {
+		t.Fatalf("searchPredicates() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"(c.metadata ->> 'breadcrumb' = $2 OR c.metadata ->> 'breadcrumb' LIKE $3)",
+		"(c.metadata -> 'frontmatter' @> jsonb_build_object($4::text, $5::text) OR c.metadata -> 'frontmatter' @> jsonb_build_object($4::text, jsonb_build_array($5::text)))",
+		"c.metadata ->> 'breadcrumb' ILIKE $6",
+	} {
+		if !strings.Contains(clause, want) {
+			t.Fatalf("searchPredicates() = %s, missing %q", clause, want)
+		}
+	}
+	if args.values[2] != `Guide > 100\% > %` || args.values[3] != "owner" || args.values[4] != "platform" || args.values[5] != "%install%" {
+		t.Fatalf("searchPredicates() args = %#v", args.values)
+	}
+}
+
+func TestFrontmatterPredicates_UseIndexedContainment(t *testing.T) {
+	args := &sqlArgs{}
+	predicates := frontmatterPredicates(map[string]any{"owner": "platform", "draft": false}, args)
+
+	want := []string{
+		"(c.metadata -> 'frontmatter' @> jsonb_build_object($1::text, $2::text) OR c.metadata -> 'frontmatter' @> jsonb_build_object($1::text, jsonb_build_array($2::text)))",
+		"(c.metadata -> 'frontmatter' @> jsonb_build_object($3::text, $4::text) OR c.metadata -> 'frontmatter' @> jsonb_build_object($3::text, jsonb_build_array($4::text)))",
+	}
+	if len(predicates) != len(want) {
+		t.Fatalf("frontmatterPredicates() = %#v, want %d predicates", predicates, len(want))
+	}
+	for i := range want {
+		if predicates[i] != want[i] {
+			t.Fatalf("frontmatterPredicates()[%d] =\n%s\nwant\n%s", i, predicates[i], want[i])
+		}
+		// Only containment on the indexed expression can use the GIN index.
+		if strings.Contains(predicates[i], "->>") || strings.Contains(predicates[i], "-> $") {
+			t.Fatalf("frontmatterPredicates()[%d] = %s does not use containment on the indexed expression", i, predicates[i])
+		}
+	}
+	wantArgs := []any{"draft", "false", "owner", "platform"}
+	for i, value := range wantArgs {
+		if args.values[i] != value {
+			t.Fatalf("frontmatterPredicates() args = %#v, want %#v", args.values, wantArgs)
+		}
+	}
+}
+
+func TestFrontmatterPredicates_MatchChunkerMetadata(t *testing.T) {
+	chunker, err := markdown.NewMarkdownChunker(markdown.DefaultMarkdownOptions())
+	if err != nil {
+		t.Fatalf("NewMarkdownChunker() error = %v", err)
+	}
+	chunks, err := chunker.Chunk(`---
+owner: platform
+draft: false
+priority: 1
+tags:
+  - search
+  - ranking
+---
+
+# Guide
+
+Install the service.
+`)
+	if err != nil {
+		t.Fatalf("Chunk() error = %v", err)
+	}
+	if len(chunks) == 0 {
+		t.Fatal("Chunk() returned no chunks")
+	}
+	// Round-trip through JSON as the metadata column does.
+	var metadata map[string]any
+	if err := json.Unmarshal(toJSON(chunks[0].Metadata), &metadata); err != nil {
+		t.Fatalf("unmarshal metadata: %v", err)
+	}
+	frontmatter, ok := metadata["frontmatter"].(map[string]any)
+	if !ok {
+		t.Fatalf("chunk metadata = %#v, want frontmatter", metadata)
+	}
+
+	filter := map[string]any{"owner": "platform", "draft": false, "priority": float64(1), "tags": "ranking"}
+	args := &sqlArgs{}
+	predicates := frontmatterPredicates(filter, args)
+	if len(predicates) != len(filter) || len(args.values) != 2*len(filter) {
+		t.Fatalf("frontmatterPredicates() = %v, args %#v", predicates, args.values)
+	}
+	for i := 0; i < len(args.values); i += 2 {
+		key, value := args.values[i].(string), args.values[i+1].(string)
+		if !frontmatterMatches(frontmatter[key], value) {
+			t.Fatalf("filter %s = %q does not match chunk frontmatter %#v", key, value, frontmatter[key])
+		}
+	}
+}
+
+// frontmatterMatches mirrors the frontmatter predicate: containment of the
+// text scalar or of a one-element list of it.
+func frontmatterMatches(stored any, value string) bool {
+	switch stored := stored.(type) {
+	case string:
+		return stored == value
+	case []any:
+		for _, item := range stored {
+			if item == value {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -39,38 +42,52 @@ func (r *PostgresStore) InsertRetrievalRequest(ctx context.Context, req retrieva
 	}
 
 	filtersJSON := encodeJSON(req.Filters)
+	var federatedID uuid.NullUUID
+	if req.FederatedRequestID != nil {
+		parsed, err := uuid.Parse(*req.FederatedRequestID)
+		if err != nil {
+			return nil, err
+		}
+		federatedID = uuid.NullUUID{UUID: parsed, Valid: true}
+	}
 
 	row, err := r.queries.InsertRetrievalRequest(ctx, sqlc.InsertRetrievalRequestParams{
-		ID:           reqID,
-		KbID:         kbID,
-		Query:        req.Query,
-		Filters:      filtersJSON,
-		TopK:         int32(req.TopK),
-		HybridWeight: req.HybridWeight,
-		ResultCount:  int32(req.ResultCount),
-		LatencyMs:    req.LatencyMS,
-		EmptyResult:  req.EmptyResult,
-		CreatedAt:    req.CreatedAt,
+		ID:                 reqID,
+		KbID:               kbID,
+		Query:              req.Query,
+		Filters:            filtersJSON,
+		TopK:               int32(req.TopK),
+		HybridWeight:       req.HybridWeight,
+		ResultCount:        int32(req.ResultCount),
+		LatencyMs:          req.LatencyMS,
+		EmptyResult:        req.EmptyResult,
+		CreatedAt:          req.CreatedAt,
+		FederatedRequestID: federatedID,
+		FusionMethod:       sql.NullString{String: req.FusionMethod, Valid: req.FusionMethod != ""},
+		RetrievalProfile:   sql.NullString{String: req.RetrievalProfile, Valid: req.RetrievalProfile != ""},
 	})
 	if err != nil {
 		return nil, err
 	}
 
 	return &retrieval.RetrievalRequestRecord{
-		ID:            row.ID.String(),
-		KnowledgeBase: row.KbID.String(),
-		Query:         row.Query,
-		Filters:       decodeJSON(row.Filters),
-		TopK:          int(row.TopK),
-		HybridWeight:  row.HybridWeight,
-		ResultCount:   int(row.ResultCount),
-		LatencyMS:     row.LatencyMs,
-		EmptyResult:   row.EmptyResult,
-		CreatedAt:     row.CreatedAt,
+		ID:                 row.ID.String(),
+		KnowledgeBase:      row.KbID.String(),
+		Query:              row.Query,
+		Filters:            decodeJSON(row.Filters),
+		TopK:               int(row.TopK),
+		HybridWeight:       row.HybridWeight,
+		ResultCount:        int(row.ResultCount),
+		LatencyMS:          row.LatencyMs,
+		EmptyResult:        row.EmptyResult,
+		CreatedAt:          row.CreatedAt,
+		FederatedRequestID: req.FederatedRequestID,
+		FusionMethod:       row.FusionMethod.String,
+		RetrievalProfile:   row.RetrievalProfile.String,
 	}, nil
 }
 
-func (r *PostgresStore) UpdateRetrievalRequest(ctx context.Context, requestID string, resultCount int, latencyMS int64, emptyResult bool) error {
+func (r *PostgresStore) UpdateRetrievalRequest(ctx context.Context, requestID string, resultCount int, latencyMS int64, emptyResult bool, emptyReason string) error {
 	reqID, err := uuid.Parse(requestID)
 	if err != nil {
 		return err
@@ -81,6 +98,7 @@ func (r *PostgresStore) UpdateRetrievalRequest(ctx context.Context, requestID st
 		ResultCount: int32(resultCount),
 		LatencyMs:   latencyMS,
 		EmptyResult: emptyResult,
+		EmptyReason: sql.NullString{String: emptyReason, Valid: emptyReason != ""},
 	})
 }
 
@@ -123,6 +141,9 @@ func (r *PostgresStore) InsertRetrievalResults(ctx context.Context, results []re
 			SemanticScore:      result.SemanticScore,
 			LexicalScore:       result.LexicalScore,
 			FinalScore:         result.FinalScore,
+			RerankScore:        toNullFloat64(result.RerankScore),
+			SemanticRank:       toNullInt32(result.SemanticRank),
+			LexicalRank:        toNullInt32(result.LexicalRank),
 			CreatedAt:          result.CreatedAt,
 		}); err != nil {
 			rollback()
@@ -137,92 +158,450 @@ func (r *PostgresStore) InsertRetrievalResults(ctx context.Context, results []re
 	return nil
 }
 
-func (r *PostgresStore) SearchSemantic(ctx context.Context, params retrieval.SearchParams) ([]retrieval.ScoredChunk, error) {
-	kbID, err := uuid.Parse(params.KnowledgeBaseID)
+func (r *PostgresStore) SaveRetrievalRanking(ctx context.Context, requestID string, candidates []retrieval.RankedCandidate) error {
+	reqID, err := uuid.Parse(requestID)
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(candidates)
+	if err != nil {
+		return err
+	}
+	return r.queries.UpdateRetrievalRequestRanking(ctx, sqlc.UpdateRetrievalRequestRankingParams{
+		ID:               reqID,
+		RankedCandidates: encoded,
+	})
+}
+
+// GetRetrievalRanking returns nil when the request does not exist in the
+// knowledge base or was stored without a ranking.
+func (r *PostgresStore) GetRetrievalRanking(ctx context.Context, knowledgeBaseID string, requestID string) (*retrieval.RetrievalRanking, error) {
+	kbID, err := uuid.Parse(knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	reqID, err := uuid.Parse(requestID)
+	if err != nil {
+		return nil, nil
+	}
+
+	row, err := r.queries.GetRetrievalRequestRanking(ctx, sqlc.GetRetrievalRequestRankingParams{
+		ID:   reqID,
+		KbID: kbID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
+	if len(row.RankedCandidates) == 0 {
+		return nil, nil
+	}
 
+	var candidates []retrieval.RankedCandidate
+	if err := json.Unmarshal(row.RankedCandidates, &candidates); err != nil {
+		return nil, err
+	}
+
+	return &retrieval.RetrievalRanking{
+		RequestID:       row.ID.String(),
+		KnowledgeBaseID: row.KbID.String(),
+		Query:           row.Query,
+		TopK:            int(row.TopK),
+		HybridWeight:    row.HybridWeight,
+		CreatedAt:       row.CreatedAt,
+		Candidates:      candidates,
+	}, nil
+}
+
+// GetKnowledgeBaseMetadata returns nil when the knowledge base does not exist.
+func (r *PostgresStore) GetKnowledgeBaseMetadata(ctx context.Context, knowledgeBaseID string) (map[string]any, error) {
+	kbID, err := uuid.Parse(knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	kb, err := r.queries.GetKnowledgeBase(ctx, kbID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]any{}
+	if len(kb.Metadata) > 0 {
+		if err := json.Unmarshal(kb.Metadata, &metadata); err != nil {
+			return nil, err
+		}
+	}
+	return metadata, nil
+}
+
+func (r *PostgresStore) SearchSemantic(ctx context.Context, params retrieval.SearchParams) ([]retrieval.ScoredChunk, error) {
 	table := fmt.Sprintf("embeddings_%d", params.VectorDimension)
-	vector := pgvector.NewVector(params.QueryVector)
+	args := &sqlArgs{}
+	vector := args.add(pgvector.NewVector(params.QueryVector))
+	where, err := searchPredicates(params, args)
+	if err != nil {
+		return nil, err
+	}
+	limit := args.add(int32(params.Limit))
 
 	query := fmt.Sprintf(`
 SELECT
     c.id AS chunk_id,
-    CAST(1.0 - (e.embedding_vector <=> $1::vector) AS double precision) AS semantic_score
+    CAST(1.0 - (e.embedding_vector <=> %[2]s::vector) AS double precision) AS semantic_score
 FROM chunks c
-JOIN %s e ON c.embedding_id = e.id
+JOIN %[1]s e ON c.embedding_id = e.id
 JOIN document_versions dv ON c.document_version_id = dv.id
 JOIN documents d ON dv.document_id = d.id
-WHERE dv.is_active = true
-  AND c.kb_id = $2
-  AND ($3::text IS NULL OR d.document_type = $3)
-  AND ($4::text IS NULL OR d.path LIKE $4)
-  AND ($5::text IS NULL OR d.source_metadata ->> 'source' = $5)
-  AND ($6::jsonb = '{}'::jsonb OR d.source_metadata @> $6::jsonb)
-  AND ($7::timestamptz IS NULL OR dv.created_at >= $7)
-  AND ($8::timestamptz IS NULL OR dv.created_at <= $8)
-ORDER BY e.embedding_vector <=> $1::vector
-LIMIT $9`, table)
-
-	rows, err := r.db.QueryContext(ctx, query,
-		vector,
-		kbID,
-		toNullString(params.DocumentType),
-		toNullString(params.PathPrefix),
-		toNullString(params.Source),
-		toJSON(params.TagsFilter),
-		toNullTime(params.CreatedAfter),
-		toNullTime(params.CreatedBefore),
-		int32(params.Limit),
-	)
+WHERE %[3]s
+ORDER BY %[5]s
+LIMIT %[4]s`, table, vector, where, limit, semanticOrder(params.ANN, vector))
+
+	settings := annSettings(params.ANN)
+	if len(settings) == 0 {
+		return r.queryScoredChunks(ctx, r.db, query, args.values)
+	}
+
+	// SET LOCAL scopes the planner and index settings to this search.
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var results []retrieval.ScoredChunk
-	for rows.Next() {
-		var chunkID uuid.UUID
-		var score float64
-		if err := rows.Scan(&chunkID, &score); err != nil {
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	for _, statement := range settings {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
 			return nil, err
 		}
-		results = append(results, retrieval.ScoredChunk{ChunkID: chunkID.String(), Score: score})
 	}
-	return results, rows.Err()
+	results, err := r.queryScoredChunks(ctx, tx, query, args.values)
+	if err != nil {
+		return nil, err
+	}
+	return results, tx.Commit()
+}
+
+// semanticOrder returns the ORDER BY expression of the semantic search. Exact
+// search orders by an expression the vector indexes cannot serve, so the
+// distance is computed over every matching row while the joins keep their
+// btree indexes.
+func semanticOrder(opts retrieval.ANNOptions, vector string) string {
+	distance := "e.embedding_vector <=> " + vector + "::vector"
+	if opts.Exact {
+		return "(" + distance + ") + 0"
+	}
+	return distance
+}
+
+// annSettings returns the SET LOCAL statements applying opts' index tuning.
+func annSettings(opts retrieval.ANNOptions) []string {
+	var statements []string
+	if opts.HNSWEfSearch > 0 {
+		statements = append(statements, "SET LOCAL hnsw.ef_search = "+strconv.Itoa(opts.HNSWEfSearch))
+	}
+	if opts.IVFFlatProbes > 0 {
+		statements = append(statements, "SET LOCAL ivfflat.probes = "+strconv.Itoa(opts.IVFFlatProbes))
+	}
+	return statements
 }
 
+// bm25Query scores the chunks matching the tsquery with Okapi BM25 over the
+// query's positive lexemes; prefix lexemes match every lexeme they start.
+// Term frequencies and chunk length come from the chunk's content_tsv, and
+// document frequencies and the average chunk length from kb_lexical_stats and
+// kb_term_stats, which are built from the same vectors. Arguments: knowledge
+// base ID, exact term text, prefix term text, tsquery, search predicates, k1,
+// b, limit, the matched tsvector and the field boost added to the score.
+const bm25Query = `
+WITH corpus AS (
+    SELECT
+        GREATEST(COALESCE(MAX(s.chunk_count), 0), 1)::double precision AS chunk_count,
+        COALESCE(MAX(s.total_length::double precision / NULLIF(s.chunk_count, 0)), 1) AS avg_length
+    FROM kb_lexical_stats s
+    WHERE s.kb_id = %[1]s
+),
+query_lexemes AS (
+    SELECT t.term, false AS prefix
+    FROM unnest(tsvector_to_array(to_tsvector(kb_text_search_config(%[1]s), %[2]s))) AS t(term)
+    UNION
+    SELECT t.term, true AS prefix
+    FROM unnest(tsvector_to_array(to_tsvector(kb_text_search_config(%[1]s), %[3]s))) AS t(term)
+),
+query_terms AS (
+    SELECT
+        q.term,
+        q.prefix,
+        ln(1 + (corpus.chunk_count - COALESCE(ts.doc_freq, 0) + 0.5) / (COALESCE(ts.doc_freq, 0) + 0.5)) AS idf
+    FROM query_lexemes q
+    CROSS JOIN corpus
+    LEFT JOIN kb_term_stats ts ON ts.kb_id = %[1]s AND ts.term = q.term
+),
+matches AS (
+    SELECT c.id, c.content_tsv AS vector, %[9]s AS fields, tsvector_token_count(c.content_tsv) AS length
+    FROM chunks c
+    JOIN document_versions dv ON c.document_version_id = dv.id
+    JOIN documents d ON dv.document_id = d.id
+    WHERE %[5]s
+      AND %[9]s @@ %[4]s
+)
+SELECT
+    m.id AS chunk_id,
+    COALESCE((
+        SELECT SUM(
+            qt.idf * (tf.freq * (%[6]s::double precision + 1))
+            / (tf.freq + %[6]s::double precision * (1 - %[7]s::double precision + %[7]s::double precision * m.length / corpus.avg_length))
+        )
+        FROM (
+            SELECT l.lexeme, COALESCE(array_length(l.positions, 1), 1) AS freq
+            FROM unnest(m.vector) AS l
+        ) tf
+        JOIN query_terms qt
+          ON qt.term = tf.lexeme
+          OR (qt.prefix AND starts_with(tf.lexeme, qt.term))
+    ), 0)%[10]s AS lexical_score
+FROM matches m
+CROSS JOIN corpus
+ORDER BY lexical_score DESC
+LIMIT %[8]s`
+
 func (r *PostgresStore) SearchLexical(ctx context.Context, params retrieval.SearchParams) ([]retrieval.ScoredChunk, error) {
+	args := &sqlArgs{}
+	where, err := searchPredicates(params, args)
+	if err != nil {
+		return nil, err
+	}
+	lexical, err := compileLexicalQuery(params, args)
+	if err != nil {
+		return nil, err
+	}
+	tsQuery := lexical.tsQuery
+	vector := lexicalVector(params)
+
+	var query string
+	if params.LexicalScorer == retrieval.LexicalScorerBM25 {
+		exact := args.add(lexical.exactText)
+		prefix := args.add(lexical.prefixText)
+		k1 := args.add(params.BM25K1)
+		b := args.add(params.BM25B)
+		limit := args.add(int32(params.Limit))
+		boost := bm25FieldBoost(params, k1, args)
+		query = fmt.Sprintf(bm25Query, lexical.kb, exact, prefix, tsQuery, where, k1, b, limit, vector, boost)
+	} else {
+		rank := tsRank(params, vector, tsQuery, args)
+		limit := args.add(int32(params.Limit))
+		query = fmt.Sprintf(`
+SELECT
+    c.id AS chunk_id,
+    CAST(%[4]s AS double precision) AS lexical_score
+FROM chunks c
+JOIN document_versions dv ON c.document_version_id = dv.id
+JOIN documents d ON dv.document_id = d.id
+WHERE %[2]s
+  AND %[5]s @@ %[1]s
+ORDER BY lexical_score DESC
+LIMIT %[3]s`, tsQuery, where, limit, rank, vector)
+	}
+
+	return r.queryScoredChunks(ctx, r.db, query, args.values)
+}
+
+// lexicalVector is the chunk tsvector lexical search matches and ranks: the
+// field-weighted search_tsv when params weight a field besides content and
+// content_tsv otherwise.
+func lexicalVector(params retrieval.SearchParams) string {
+	if params.FieldWeights.Enabled() {
+		return "c.search_tsv"
+	}
+	return "c.content_tsv"
+}
+
+// tsRank renders ts_rank of vector against tsQuery, passing the field weights
+// as the {D, C, B, A} label weights when they are enabled.
+func tsRank(params retrieval.SearchParams, vector string, tsQuery string, args *sqlArgs) string {
+	if !params.FieldWeights.Enabled() {
+		return fmt.Sprintf("ts_rank(%s, %s)", vector, tsQuery)
+	}
+	w := params.FieldWeights
+	weights := args.add(pq.Array([]float64{w.Content, w.Path, w.Breadcrumb, w.Title}))
+	return fmt.Sprintf("ts_rank(%s::float4[], %s, %s)", weights, vector, tsQuery)
+}
+
+// bm25FieldBoost renders the term added to a BM25 score for query lexemes in
+// the chunk's title, breadcrumb and path, or nothing without field weights.
+// A lexeme's field frequency sums its field positions' weights relative to
+// content and saturates with k1 like a content term frequency, without length
+// normalisation; it shares the content idf.
+func bm25FieldBoost(params retrieval.SearchParams, k1 string, args *sqlArgs) string {
+	if !params.FieldWeights.Enabled() {
+		return ""
+	}
+	w := params.FieldWeights
+	title := args.add(w.Title / w.Content)
+	breadcrumb := args.add(w.Breadcrumb / w.Content)
+	path := args.add(w.Path / w.Content)
+	return fmt.Sprintf(` + COALESCE((
+        SELECT SUM(qt.idf * (ff.freq * (%[4]s::double precision + 1)) / (ff.freq + %[4]s::double precision))
+        FROM (
+            SELECT l.lexeme, (
+                SELECT SUM(CASE lw.label
+                    WHEN 'A' THEN %[1]s::double precision
+                    WHEN 'B' THEN %[2]s::double precision
+                    WHEN 'C' THEN %[3]s::double precision
+                    ELSE 0
+                END)
+                FROM unnest(l.weights) AS lw(label)
+            ) AS freq
+            FROM unnest(m.fields) AS l
+        ) ff
+        JOIN query_terms qt
+          ON qt.term = ff.lexeme
+          OR (qt.prefix AND starts_with(ff.lexeme, qt.term))
+        WHERE ff.freq > 0
+    ), 0)`, title, breadcrumb, path, k1)
+}
+
+// lexicalQuery is the lexical form of a search: the bound knowledge base ID,
+// the rendered tsquery and the text of its positive exact and prefix terms.
+type lexicalQuery struct {
+	kb         string
+	config     string
+	tsQuery    string
+	exactText  string
+	prefixText string
+}
+
+func compileLexicalQuery(params retrieval.SearchParams, args *sqlArgs) (lexicalQuery, error) {
+	// The query is parsed with the knowledge base's text search configuration,
+	// the same one its stored content_tsv vectors were built with.
 	kbID, err := uuid.Parse(params.KnowledgeBaseID)
+	if err != nil {
+		return lexicalQuery{}, err
+	}
+	kb := args.add(kbID)
+	lexical := lexicalQuery{
+		kb:        kb,
+		config:    fmt.Sprintf("kb_text_search_config(%s)", kb),
+		exactText: params.Query,
+	}
+	if params.ParsedQuery != nil && !params.ParsedQuery.IsEmpty() {
+		lexical.tsQuery = compileTSQuery(params.ParsedQuery, lexical.config, args)
+		lexical.exactText, lexical.prefixText = bm25TermText(params.ParsedQuery)
+	} else {
+		lexical.tsQuery = fmt.Sprintf("plainto_tsquery(%s, %s)", lexical.config, args.add(params.Query))
+	}
+	return lexical, nil
+}
+
+// ExplainChunk reports how one chunk relates to a search regardless of
+// whether it was a candidate. It returns nil when the chunk does not exist.
+func (r *PostgresStore) ExplainChunk(ctx context.Context, params retrieval.SearchParams, chunkID string) (*retrieval.ChunkExplanation, error) {
+	id, err := uuid.Parse(chunkID)
+	if err != nil {
+		return nil, nil
+	}
+
+	args := &sqlArgs{}
+	filters, err := filterPredicates(params, args)
+	if err != nil {
+		return nil, err
+	}
+	filterClause := "true"
+	if len(filters) > 0 {
+		filterClause = strings.Join(filters, "\n        AND ")
+	}
+	lexical, err := compileLexicalQuery(params, args)
 	if err != nil {
 		return nil, err
 	}
+	vector := args.add(pgvector.NewVector(params.QueryVector))
+	exact := args.add(lexical.exactText)
+	prefix := args.add(lexical.prefixText)
+	chunk := args.add(id)
+	searched := lexicalVector(params)
+	rank := tsRank(params, searched, lexical.tsQuery, args)
 
-	rows, err := r.queries.SearchLexical(ctx, sqlc.SearchLexicalParams{
-		Query:         params.Query,
-		KbID:          kbID,
-		DocumentType:  toNullString(params.DocumentType),
-		PathPrefix:    toNullString(params.PathPrefix),
-		Source:        toNullString(params.Source),
-		Tags:          toJSON(params.TagsFilter),
-		CreatedAfter:  toNullTime(params.CreatedAfter),
-		CreatedBefore: toNullTime(params.CreatedBefore),
-		Limit:         int32(params.Limit),
-	})
+	query := fmt.Sprintf(`
+SELECT
+    c.kb_id = %[2]s,
+    dv.is_active,
+    COALESCE((%[3]s), false),
+    CAST(e.embedding_vector <=> %[5]s::vector AS double precision),
+    %[10]s @@ %[4]s,
+    CAST(%[11]s AS double precision),
+    ARRAY(
+        SELECT l.lexeme
+        FROM unnest(%[10]s) AS l
+        WHERE l.lexeme = ANY(tsvector_to_array(to_tsvector(%[6]s, %[7]s)))
+           OR EXISTS (
+               SELECT 1
+               FROM unnest(tsvector_to_array(to_tsvector(%[6]s, %[8]s))) AS p(term)
+               WHERE starts_with(l.lexeme, p.term)
+           )
+        ORDER BY l.lexeme
+    )
+FROM chunks c
+JOIN document_versions dv ON c.document_version_id = dv.id
+JOIN documents d ON dv.document_id = d.id
+LEFT JOIN %[1]s e ON c.embedding_id = e.id
+WHERE c.id = %[9]s`,
+		fmt.Sprintf("embeddings_%d", params.VectorDimension),
+		lexical.kb,
+		filterClause,
+		lexical.tsQuery,
+		vector,
+		lexical.config,
+		exact,
+		prefix,
+		chunk,
+		searched,
+		rank,
+	)
+
+	explanation := retrieval.ChunkExplanation{ChunkID: chunkID}
+	var distance sql.NullFloat64
+	err = r.db.QueryRowContext(ctx, query, args.values...).Scan(
+		&explanation.InKnowledgeBase,
+		&explanation.ActiveVersion,
+		&explanation.PassesFilters,
+		&distance,
+		&explanation.LexicalMatch,
+		&explanation.TSRank,
+		pq.Array(&explanation.MatchedLexemes),
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
+	if distance.Valid {
+		explanation.CosineDistance = &distance.Float64
+	}
+	return &explanation, nil
+}
 
-	results := make([]retrieval.ScoredChunk, 0, len(rows))
-	for _, row := range rows {
-		results = append(results, retrieval.ScoredChunk{
-			ChunkID: row.ChunkID.String(),
-			Score:   float64(row.LexicalScore),
-		})
+func (r *PostgresStore) queryScoredChunks(ctx context.Context, db sqlc.DBTX, query string, args []any) ([]retrieval.ScoredChunk, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return results, nil
+	var results []retrieval.ScoredChunk
+	for rows.Next() {
+		var chunkID uuid.UUID
+		var score float64
+		if err := rows.Scan(&chunkID, &score); err != nil {
+			return nil, err
+		}
+		results = append(results, retrieval.ScoredChunk{ChunkID: chunkID.String(), Score: score})
+	}
+	return results, rows.Err()
 }
 
 func (r *PostgresStore) GetChunksWithDocuments(ctx context.Context, chunkIDs []string) ([]retrieval.ChunkRecord, error) {
@@ -268,6 +647,7 @@ func (r *PostgresStore) GetChunksWithDocumentsForKB(ctx context.Context, knowled
 	const query = `
 SELECT
     c.id AS chunk_id,
+    c.kb_id,
     c.document_version_id,
     c.sequence_number,
     c.content,
@@ -312,6 +692,7 @@ func (r *PostgresStore) GetChunksByDocumentVersionRange(ctx context.Context, doc
 	const query = `
 SELECT
     c.id AS chunk_id,
+    c.kb_id,
     c.document_version_id,
     c.sequence_number,
     c.content,
@@ -348,18 +729,276 @@ ORDER BY c.sequence_number ASC`
 	return results, rows.Err()
 }
 
-func toNullString(value *string) sql.NullString {
-	if value == nil || *value == "" {
-		return sql.NullString{}
+// GetChunkVectors loads the stored embedding for each chunk from the
+// dimension-specific embeddings table.
+func (r *PostgresStore) GetChunkVectors(ctx context.Context, vectorDimension int, chunkIDs []string) (map[string][]float32, error) {
+	if len(chunkIDs) == 0 {
+		return map[string][]float32{}, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(chunkIDs))
+	for _, id := range chunkIDs {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, parsed)
+	}
+
+	table := fmt.Sprintf("embeddings_%d", vectorDimension)
+	query := fmt.Sprintf(`
+SELECT
+    c.id AS chunk_id,
+    e.embedding_vector
+FROM chunks c
+JOIN %s e ON c.embedding_id = e.id
+WHERE c.id = ANY($1::uuid[])`, table)
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
 	}
-	return sql.NullString{String: *value, Valid: true}
+	defer rows.Close()
+
+	vectors := make(map[string][]float32, len(ids))
+	for rows.Next() {
+		var chunkID uuid.UUID
+		var vector pgvector.Vector
+		if err := rows.Scan(&chunkID, &vector); err != nil {
+			return nil, err
+		}
+		vectors[chunkID.String()] = vector.Slice()
+	}
+	return vectors, rows.Err()
 }
 
-func toNullTime(value *time.Time) sql.NullTime {
+// GetChunkDocumentIDs maps each chunk ID to the ID of its document.
+func (r *PostgresStore) GetChunkDocumentIDs(ctx context.Context, chunkIDs []string) (map[string]string, error) {
+	if len(chunkIDs) == 0 {
+		return map[string]string{}, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(chunkIDs))
+	for _, id := range chunkIDs {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, parsed)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT
+    c.id AS chunk_id,
+    dv.document_id
+FROM chunks c
+JOIN document_versions dv ON c.document_version_id = dv.id
+WHERE c.id = ANY($1::uuid[])`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	documents := make(map[string]string, len(ids))
+	for rows.Next() {
+		var chunkID, documentID uuid.UUID
+		if err := rows.Scan(&chunkID, &documentID); err != nil {
+			return nil, err
+		}
+		documents[chunkID.String()] = documentID.String()
+	}
+	return documents, rows.Err()
+}
+
+// GetLexemes normalizes each word with the knowledge base's text search
+// configuration. Stop words map to no lexemes.
+func (r *PostgresStore) GetLexemes(ctx context.Context, knowledgeBaseID string, words []string) (map[string][]string, error) {
+	if len(words) == 0 {
+		return map[string][]string{}, nil
+	}
+	kbID, err := uuid.Parse(knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT
+    w.word,
+    tsvector_to_array(to_tsvector(kb_text_search_config($1), w.word))
+FROM unnest($2::text[]) AS w(word)`, kbID, pq.Array(words))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lexemes := make(map[string][]string, len(words))
+	for rows.Next() {
+		var word string
+		var normalized []string
+		if err := rows.Scan(&word, pq.Array(&normalized)); err != nil {
+			return nil, err
+		}
+		lexemes[word] = normalized
+	}
+	return lexemes, rows.Err()
+}
+
+// InsertCalibrationJudgments stores judgments of results logged for the
+// knowledge base, replacing earlier labels of the same result. Judgments of
+// results that were never logged are skipped; the stored count is returned.
+func (r *PostgresStore) InsertCalibrationJudgments(ctx context.Context, knowledgeBaseID string, judgments []retrieval.RelevanceJudgment) (int, error) {
+	kbID, err := uuid.Parse(knowledgeBaseID)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stored := 0
+	for _, judgment := range judgments {
+		requestID, err := uuid.Parse(judgment.QueryID)
+		if err != nil {
+			continue
+		}
+		chunkID, err := uuid.Parse(judgment.ChunkID)
+		if err != nil {
+			continue
+		}
+		result, err := tx.ExecContext(ctx, `
+INSERT INTO calibration_judgments (id, kb_id, retrieval_request_id, chunk_id, relevant)
+SELECT $1, rq.kb_id, rr.retrieval_request_id, rr.chunk_id, $5
+FROM retrieval_results rr
+JOIN retrieval_requests rq ON rq.id = rr.retrieval_request_id
+WHERE rq.kb_id = $2
+  AND rr.retrieval_request_id = $3
+  AND rr.chunk_id = $4
+LIMIT 1
+ON CONFLICT (retrieval_request_id, chunk_id) DO UPDATE
+SET relevant = EXCLUDED.relevant,
+    created_at = now()`, uuid.New(), kbID, requestID, chunkID, judgment.Relevant)
+		if err != nil {
+			return 0, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		stored += int(affected)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return stored, nil
+}
+
+// GetCalibrationSamples pairs each judgment with the final score logged for
+// the result by requests that used the given fusion method.
+func (r *PostgresStore) GetCalibrationSamples(ctx context.Context, knowledgeBaseID string, fusionMethod string) ([]retrieval.CalibrationSample, error) {
+	kbID, err := uuid.Parse(knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT DISTINCT ON (j.id) rr.final_score, j.relevant
+FROM calibration_judgments j
+JOIN retrieval_requests rq ON rq.id = j.retrieval_request_id
+JOIN retrieval_results rr
+  ON rr.retrieval_request_id = j.retrieval_request_id
+ AND rr.chunk_id = j.chunk_id
+WHERE j.kb_id = $1
+  AND rq.fusion_method = $2
+ORDER BY j.id, rr.rank`, kbID, fusionMethod)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []retrieval.CalibrationSample
+	for rows.Next() {
+		var sample retrieval.CalibrationSample
+		if err := rows.Scan(&sample.Score, &sample.Relevant); err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	}
+	return samples, rows.Err()
+}
+
+func (r *PostgresStore) SaveScoreCalibration(ctx context.Context, calibration retrieval.ScoreCalibration) error {
+	kbID, err := uuid.Parse(calibration.KnowledgeBaseID)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+INSERT INTO kb_score_calibrations (kb_id, fusion_method, slope, intercept, sample_count, fitted_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (kb_id, fusion_method) DO UPDATE
+SET slope = EXCLUDED.slope,
+    intercept = EXCLUDED.intercept,
+    sample_count = EXCLUDED.sample_count,
+    fitted_at = EXCLUDED.fitted_at`,
+		kbID,
+		calibration.FusionMethod,
+		calibration.Slope,
+		calibration.Intercept,
+		calibration.SampleCount,
+		calibration.FittedAt,
+	)
+	return err
+}
+
+// GetScoreCalibration returns nil when the knowledge base has not been
+// calibrated for the fusion method.
+func (r *PostgresStore) GetScoreCalibration(ctx context.Context, knowledgeBaseID string, fusionMethod string) (*retrieval.ScoreCalibration, error) {
+	kbID, err := uuid.Parse(knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	calibration := retrieval.ScoreCalibration{
+		KnowledgeBaseID: knowledgeBaseID,
+		FusionMethod:    fusionMethod,
+	}
+	err = r.db.QueryRowContext(ctx, `
+SELECT slope, intercept, sample_count, fitted_at
+FROM kb_score_calibrations
+WHERE kb_id = $1
+  AND fusion_method = $2`, kbID, fusionMethod).Scan(
+		&calibration.Slope,
+		&calibration.Intercept,
+		&calibration.SampleCount,
+		&calibration.FittedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &calibration, nil
+}
+
+func toNullInt32(value *int) sql.NullInt32 {
 	if value == nil {
-		return sql.NullTime{}
+		return sql.NullInt32{}
 	}
-	return sql.NullTime{Time: *value, Valid: true}
+	return sql.NullInt32{Int32: int32(*value), Valid: true}
+}
+
+func toNullFloat64(value *float64) sql.NullFloat64 {
+	if value == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *value, Valid: true}
 }
 
 func toJSON(value map[string]any) json.RawMessage {
@@ -402,11 +1041,19 @@ func nullStringPtr(value sql.NullString) *string {
 	return &value.String
 }
 
+func nullFloat64Ptr(value sql.NullFloat64) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Float64
+}
+
 func chunkRecordsFromRows(rows []sqlc.GetChunksWithDocumentsRow) []retrieval.ChunkRecord {
 	results := make([]retrieval.ChunkRecord, 0, len(rows))
 	for _, row := range rows {
 		results = append(results, retrieval.ChunkRecord{
 			ChunkID:           row.ChunkID.String(),
+			KnowledgeBaseID:   row.KbID.String(),
 			DocumentID:        row.DocumentID.String(),
 			DocumentVersionID: row.DocumentVersionID.String(),
 			DocumentPath:      row.DocumentPath,
@@ -417,6 +1064,7 @@ func chunkRecordsFromRows(rows []sqlc.GetChunksWithDocumentsRow) []retrieval.Chu
 			VersionNumber:     row.VersionNumber,
 			SequenceNumber:    row.SequenceNumber,
 			SourceMetadata:    decodeJSON(row.SourceMetadata),
+			VersionCreatedAt:  row.VersionCreatedAt,
 		})
 	}
 	return results
@@ -427,6 +1075,7 @@ func scanChunkRecord(scanner interface {
 }) (retrieval.ChunkRecord, error) {
 	var (
 		chunkID           uuid.UUID
+		kbID              uuid.UUID
 		documentVersionID uuid.UUID
 		sequenceNumber    int32
 		content           string
@@ -442,6 +1091,7 @@ func scanChunkRecord(scanner interface {
 
 	if err := scanner.Scan(
 		&chunkID,
+		&kbID,
 		&documentVersionID,
 		&sequenceNumber,
 		&content,
@@ -457,9 +1107,9 @@ func scanChunkRecord(scanner interface {
 		return retrieval.ChunkRecord{}, err
 	}
 
-	_ = versionCreatedAt
 	return retrieval.ChunkRecord{
 		ChunkID:           chunkID.String(),
+		KnowledgeBaseID:   kbID.String(),
 		DocumentID:        documentID.String(),
 		DocumentVersionID: documentVersionID.String(),
 		DocumentPath:      documentPath,
@@ -470,6 +1120,7 @@ func scanChunkRecord(scanner interface {
 		VersionNumber:     versionNumber,
 		SequenceNumber:    sequenceNumber,
 		SourceMetadata:    decodeJSON(sourceMetadataRaw),
+		VersionCreatedAt:  versionCreatedAt,
 	}, nil
 }
 
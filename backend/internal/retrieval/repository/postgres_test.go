@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+func TestANNSettings(t *testing.T) {
+	if got := annSettings(retrieval.ANNOptions{}); len(got) != 0 {
+		t.Fatalf("annSettings(zero) = %#v, want none", got)
+	}
+
+	if got := annSettings(retrieval.ANNOptions{Exact: true}); len(got) != 0 {
+		t.Fatalf("annSettings(exact) = %#v, want none", got)
+	}
+
+	got := annSettings(retrieval.ANNOptions{HNSWEfSearch: 200, IVFFlatProbes: 10})
+	want := []string{"SET LOCAL hnsw.ef_search = 200", "SET LOCAL ivfflat.probes = 10"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("annSettings(tuned) = %#v, want %#v", got, want)
+	}
+}
+
+func TestSemanticOrder_ExactBypassesVectorIndex(t *testing.T) {
+	if got := semanticOrder(retrieval.ANNOptions{}, "$1"); got != "e.embedding_vector <=> $1::vector" {
+		t.Fatalf("semanticOrder(ann) = %q", got)
+	}
+	if got := semanticOrder(retrieval.ANNOptions{Exact: true}, "$1"); got != "(e.embedding_vector <=> $1::vector) + 0" {
+		t.Fatalf("semanticOrder(exact) = %q", got)
+	}
+}
+
+func TestTSRank_FieldWeights(t *testing.T) {
+	args := &sqlArgs{}
+	params := retrieval.SearchParams{}
+	if got := tsRank(params, lexicalVector(params), "q", args); got != "ts_rank(c.content_tsv, q)" || len(args.values) != 0 {
+		t.Fatalf("tsRank(content) = %q with %d args", got, len(args.values))
+	}
+
+	params.FieldWeights = retrieval.FieldWeights{Title: 1, Breadcrumb: 0.5, Path: 0.25, Content: 0.1}
+	if got := tsRank(params, lexicalVector(params), "q", args); got != "ts_rank($1::float4[], c.search_tsv, q)" {
+		t.Fatalf("tsRank(fields) = %q", got)
+	}
+	weights, ok := args.values[0].(interface{ Value() (driver.Value, error) })
+	if !ok {
+		t.Fatalf("tsRank(fields) weights arg = %#v, want an array", args.values[0])
+	}
+	if value, err := weights.Value(); err != nil || value != "{0.1,0.25,0.5,1}" {
+		t.Fatalf("tsRank(fields) weights = %v, %v, want D, C, B, A order", value, err)
+	}
+
+	boost := bm25FieldBoost(params, "$9", args)
+	if !strings.Contains(boost, "unnest(m.fields)") || !strings.Contains(boost, "ELSE 0") || len(args.values) != 4 {
+		t.Fatalf("bm25FieldBoost(fields) = %q with %d args", boost, len(args.values))
+	}
+	if args.values[1] != 10.0 || args.values[2] != 5.0 || args.values[3] != 2.5 {
+		t.Fatalf("bm25FieldBoost(fields) args = %#v, want weights relative to content", args.values[1:])
+	}
+	if got := bm25FieldBoost(retrieval.SearchParams{}, "$9", args); got != "" {
+		t.Fatalf("bm25FieldBoost(content) = %q, want none", got)
+	}
+}
+
+func TestBM25Query_ScoresContentAgainstContentStats(t *testing.T) {
+	// Term frequencies and lengths must come from content_tsv, the vector
+	// kb_term_stats and kb_lexical_stats are built from, whatever is matched.
+	for _, want := range []string{
+		"c.content_tsv AS vector",
+		"tsvector_token_count(c.content_tsv) AS length",
+		"FROM unnest(m.vector) AS l",
+		"JOIN kb_term_stats",
+	} {
+		if !strings.Contains(bm25Query, want) {
+			t.Fatalf("bm25Query missing %q", want)
+		}
+	}
+}
+
+func TestCompileLexicalQuery_UsesKnowledgeBaseTextSearchConfig(t *testing.T) {
+	kbID := "8c1f9a36-7f5d-4d8c-9d0b-2a7c3e0f6b11"
+	parsed := retrieval.ParseQuery("retry backoff")
+	for _, params := range []retrieval.SearchParams{
+		{KnowledgeBaseID: kbID, Query: "retry backoff"},
+		{KnowledgeBaseID: kbID, Query: "retry backoff", ParsedQuery: &parsed},
+	} {
+		args := &sqlArgs{}
+		lexical, err := compileLexicalQuery(params, args)
+		if err != nil {
+			t.Fatalf("compileLexicalQuery() error = %v", err)
+		}
+		if lexical.kb != "$1" || fmt.Sprint(args.values[0]) != kbID {
+			t.Fatalf("compileLexicalQuery() kb = %s bound to %v, want $1 bound to the knowledge base", lexical.kb, args.values[0])
+		}
+		if lexical.config != "kb_text_search_config($1)" || !strings.Contains(lexical.tsQuery, "(kb_text_search_config($1), $2)") {
+			t.Fatalf("compileLexicalQuery() config = %s, tsquery = %s, want the knowledge base's config", lexical.config, lexical.tsQuery)
+		}
+		if strings.Contains(lexical.tsQuery, "english") {
+			t.Fatalf("compileLexicalQuery() tsquery = %s hard-codes a config", lexical.tsQuery)
+		}
+	}
+
+	if strings.Count(bm25Query, "kb_text_search_config(%[1]s)") != 2 {
+		t.Fatal("bm25Query must parse its exact and prefix terms with the knowledge base's config")
+	}
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"ragtime-backend/internal/retrieval"
 )
@@ -9,11 +10,35 @@ import (
 // Store persists and searches retrieval-related records.
 type Store interface {
 	InsertRetrievalRequest(ctx context.Context, req retrieval.RetrievalRequestRecord) (*retrieval.RetrievalRequestRecord, error)
-	UpdateRetrievalRequest(ctx context.Context, requestID string, resultCount int, latencyMS int64, emptyResult bool) error
+	UpdateRetrievalRequest(ctx context.Context, requestID string, resultCount int, latencyMS int64, emptyResult bool, emptyReason string) error
 	InsertRetrievalResults(ctx context.Context, results []retrieval.RetrievalResultRecord) error
 	SearchSemantic(ctx context.Context, params retrieval.SearchParams) ([]retrieval.ScoredChunk, error)
 	SearchLexical(ctx context.Context, params retrieval.SearchParams) ([]retrieval.ScoredChunk, error)
+	ExplainChunk(ctx context.Context, params retrieval.SearchParams, chunkID string) (*retrieval.ChunkExplanation, error)
 	GetChunksWithDocuments(ctx context.Context, chunkIDs []string) ([]retrieval.ChunkRecord, error)
 	GetChunksWithDocumentsForKB(ctx context.Context, knowledgeBaseID string, chunkIDs []string) ([]retrieval.ChunkRecord, error)
 	GetChunksByDocumentVersionRange(ctx context.Context, documentVersionID string, startSeq int32, endSeq int32) ([]retrieval.ChunkRecord, error)
+	GetChunkVectors(ctx context.Context, vectorDimension int, chunkIDs []string) (map[string][]float32, error)
+	GetChunkDocumentIDs(ctx context.Context, chunkIDs []string) (map[string]string, error)
+	GetLexemes(ctx context.Context, knowledgeBaseID string, words []string) (map[string][]string, error)
+	SaveRetrievalRanking(ctx context.Context, requestID string, candidates []retrieval.RankedCandidate) error
+	GetRetrievalRanking(ctx context.Context, knowledgeBaseID string, requestID string) (*retrieval.RetrievalRanking, error)
+	GetKnowledgeBaseMetadata(ctx context.Context, knowledgeBaseID string) (map[string]any, error)
+	InsertCalibrationJudgments(ctx context.Context, knowledgeBaseID string, judgments []retrieval.RelevanceJudgment) (int, error)
+	GetCalibrationSamples(ctx context.Context, knowledgeBaseID string, fusionMethod string) ([]retrieval.CalibrationSample, error)
+	SaveScoreCalibration(ctx context.Context, calibration retrieval.ScoreCalibration) error
+	GetScoreCalibration(ctx context.Context, knowledgeBaseID string, fusionMethod string) (*retrieval.ScoreCalibration, error)
+	ListTopQueries(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.QueryStat, error)
+	ListZeroResultQueries(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.ZeroResultQuery, error)
+	ListLatencyPercentiles(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.LatencyBucket, error)
+	ListProfileDistribution(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.ProfileUsage, []retrieval.WeightUsage, error)
+	ListMostRetrievedDocuments(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.DocumentUsage, error)
+	ListNeverRetrievedDocuments(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.DocumentUsage, error)
+	InsertFeedback(ctx context.Context, submission retrieval.FeedbackSubmission) (int, error)
+	GetFeedbackSummary(ctx context.Context, query retrieval.AnalyticsQuery) (*retrieval.FeedbackSummary, error)
+	ListChunkFeedback(ctx context.Context, query retrieval.AnalyticsQuery) ([]retrieval.ChunkFeedback, error)
+	ListKnowledgeBaseIDs(ctx context.Context) ([]string, error)
+	ListTuningQueries(ctx context.Context, knowledgeBaseID string, since time.Time) ([]retrieval.TuningQuery, error)
+	SaveTunedWeight(ctx context.Context, knowledgeBaseID string, profile string, tuned retrieval.TunedWeight, skipPinned bool) (bool, error)
+	DeleteTunedWeights(ctx context.Context, knowledgeBaseID string, profile string) error
 }
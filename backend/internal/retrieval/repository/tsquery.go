@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+// compileTSQuery builds a tsquery expression for a parsed web-search query
+// using the text search configuration expression config. Each term's text is
+// bound as a parameter and normalized by Postgres; only the boolean structure
+// is rendered into the SQL text. Clauses are ANDed and the terms of a clause
+// ORed.
+func compileTSQuery(parsed *retrieval.ParsedQuery, config string, args *sqlArgs) string {
+	clauses := make([]string, 0, len(parsed.All))
+	for _, clause := range parsed.All {
+		terms := make([]string, 0, len(clause.Any))
+		for _, term := range clause.Any {
+			terms = append(terms, compileTSQueryTerm(term, config, args))
+		}
+		clauses = append(clauses, "("+strings.Join(terms, " || ")+")")
+	}
+	return "(" + strings.Join(clauses, " && ") + ")"
+}
+
+func compileTSQueryTerm(term retrieval.QueryTerm, config string, args *sqlArgs) string {
+	var expr string
+	switch {
+	case term.Phrase:
+		expr = fmt.Sprintf("phraseto_tsquery(%s, %s)", config, args.add(term.Text))
+	case term.Prefix:
+		// ParseQuery reduces prefix terms to letters and digits, so the
+		// bound value is always a valid to_tsquery operand.
+		expr = fmt.Sprintf("to_tsquery(%s, %s)", config, args.add(term.Text+":*"))
+	default:
+		expr = fmt.Sprintf("plainto_tsquery(%s, %s)", config, args.add(term.Text))
+	}
+	if term.Negated {
+		return "!!" + expr
+	}
+	return expr
+}
+
+// bm25TermText splits the positive terms of a parsed query into the text of
+// exact terms and of prefix terms, for BM25 to turn into lexemes.
+func bm25TermText(parsed *retrieval.ParsedQuery) (string, string) {
+	var exact, prefix []string
+	for _, clause := range parsed.All {
+		for _, term := range clause.Any {
+			switch {
+			case term.Negated:
+				// Excluded terms never match, so they carry no weight.
+			case term.Prefix:
+				prefix = append(prefix, term.Text)
+			default:
+				exact = append(exact, term.Text)
+			}
+		}
+	}
+	return strings.Join(exact, " "), strings.Join(prefix, " ")
+}
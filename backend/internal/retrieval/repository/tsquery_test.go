@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"testing"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+func TestCompileTSQuery(t *testing.T) {
+	parsed := retrieval.ParseQuery(`"retry budget" OR backoff -deprecated time*`)
+	args := &sqlArgs{}
+	got := compileTSQuery(&parsed, "cfg", args)
+
+	want := "((phraseto_tsquery(cfg, $1) || plainto_tsquery(cfg, $2)) && (!!plainto_tsquery(cfg, $3)) && (to_tsquery(cfg, $4)))"
+	if got != want {
+		t.Fatalf("compileTSQuery() =\n%s\nwant\n%s", got, want)
+	}
+	wantArgs := []any{"retry budget", "backoff", "deprecated", "time:*"}
+	for i, value := range wantArgs {
+		if args.values[i] != value {
+			t.Fatalf("compileTSQuery() args = %#v, want %#v", args.values, wantArgs)
+		}
+	}
+
+	exact, prefix := bm25TermText(&parsed)
+	if exact != "retry budget backoff" || prefix != "time" {
+		t.Fatalf("bm25TermText() = %q, %q", exact, prefix)
+	}
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ragtime-backend/internal/retrieval"
+	"ragtime-backend/internal/storage/sqlc"
+)
+
+func (r *PostgresStore) ListKnowledgeBaseIDs(ctx context.Context) ([]string, error) {
+	kbs, err := r.queries.ListKnowledgeBases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(kbs))
+	for _, kb := range kbs {
+		ids = append(ids, kb.ID.String())
+	}
+	return ids, nil
+}
+
+// ListTuningQueries groups the labelled linear-fusion results logged since
+// the given time by query.
+func (r *PostgresStore) ListTuningQueries(ctx context.Context, knowledgeBaseID string, since time.Time) ([]retrieval.TuningQuery, error) {
+	kbID, err := uuid.Parse(knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.queries.ListTuningSamples(ctx, sqlc.ListTuningSamplesParams{
+		KbID:      kbID,
+		StartTime: since,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var queries []retrieval.TuningQuery
+	for _, row := range rows {
+		requestID := row.RetrievalRequestID.String()
+		if len(queries) == 0 || queries[len(queries)-1].RequestID != requestID {
+			queries = append(queries, retrieval.TuningQuery{RequestID: requestID, Query: row.Query})
+		}
+		last := &queries[len(queries)-1]
+		last.Results = append(last.Results, retrieval.TuningResult{
+			SemanticScore: row.SemanticScore,
+			LexicalScore:  row.LexicalScore,
+			Relevance:     row.Relevance,
+		})
+	}
+	return queries, nil
+}
+
+// SaveTunedWeight stores the weight of one auto-profile class and reports
+// whether it was stored. With skipPinned set, a pinned weight is kept.
+func (r *PostgresStore) SaveTunedWeight(ctx context.Context, knowledgeBaseID string, profile string, tuned retrieval.TunedWeight, skipPinned bool) (bool, error) {
+	kbID, err := uuid.Parse(knowledgeBaseID)
+	if err != nil {
+		return false, err
+	}
+	payload, err := json.Marshal(tuned)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := r.queries.SetTunedSemanticWeight(ctx, sqlc.SetTunedSemanticWeightParams{
+		Profile:    profile,
+		Tuned:      payload,
+		UpdatedAt:  time.Now().UTC(),
+		KbID:       kbID,
+		SkipPinned: skipPinned,
+	})
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// DeleteTunedWeights removes the tuned weight of profile, or of every class
+// when profile is empty.
+func (r *PostgresStore) DeleteTunedWeights(ctx context.Context, knowledgeBaseID string, profile string) error {
+	kbID, err := uuid.Parse(knowledgeBaseID)
+	if err != nil {
+		return err
+	}
+
+	if profile == "" {
+		_, err = r.queries.DeleteTunedSemanticWeights(ctx, sqlc.DeleteTunedSemanticWeightsParams{
+			UpdatedAt: time.Now().UTC(),
+			KbID:      kbID,
+		})
+		return err
+	}
+	_, err = r.queries.DeleteTunedSemanticWeight(ctx, sqlc.DeleteTunedSemanticWeightParams{
+		Profile:   profile,
+		UpdatedAt: time.Now().UTC(),
+		KbID:      kbID,
+	})
+	return err
+}
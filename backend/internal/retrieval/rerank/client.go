@@ -0,0 +1,91 @@
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+const defaultBaseURL = "http://localhost:8000"
+
+// Client calls the /rerank endpoint on the FastAPI embedding sidecar.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewClient(baseURL string) *Client {
+	trimmed := strings.TrimRight(baseURL, "/")
+	if trimmed == "" {
+		trimmed = defaultBaseURL
+	}
+
+	return &Client{
+		baseURL: trimmed,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+type rerankRequest struct {
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+}
+
+type rerankResponse struct {
+	Model  string    `json:"model"`
+	Scores []float64 `json:"scores"`
+}
+
+// Rerank scores candidates against the query with the sidecar cross-encoder.
+func (c *Client) Rerank(ctx context.Context, query string, candidates []retrieval.RerankCandidate) ([]float64, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	documents := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		documents = append(documents, candidate.Content)
+	}
+
+	payload, err := json.Marshal(rerankRequest{Query: query, Documents: documents})
+	if err != nil {
+		return nil, fmt.Errorf("marshal rerank request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/rerank", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("create rerank request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("rerank request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("rerank request returned status %d", resp.StatusCode)
+	}
+
+	var decoded rerankResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("decode rerank response: %w", err)
+	}
+
+	if len(decoded.Scores) != len(candidates) {
+		return nil, fmt.Errorf("rerank response mismatch: expected %d scores, got %d", len(candidates), len(decoded.Scores))
+	}
+
+	return decoded.Scores, nil
+}
+
+var _ retrieval.Reranker = (*Client)(nil)
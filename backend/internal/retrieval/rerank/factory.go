@@ -0,0 +1,40 @@
+package rerank
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+const defaultRerankProvider = "fastapi"
+
+// NewRerankerFromEnv selects a reranker based on environment variables.
+// RERANK_PROVIDER: fastapi (default) | local | none
+// RERANK_BASE_URL: base URL for the FastAPI sidecar (falls back to EMBEDDING_BASE_URL, then FASTAPI_EMBEDDINGS_URL)
+// A nil reranker is returned for provider=none.
+func NewRerankerFromEnv() (retrieval.Reranker, error) {
+	provider := strings.ToLower(strings.TrimSpace(os.Getenv("RERANK_PROVIDER")))
+	if provider == "" {
+		provider = defaultRerankProvider
+	}
+
+	switch provider {
+	case "fastapi", "http":
+		baseURL := strings.TrimSpace(os.Getenv("RERANK_BASE_URL"))
+		if baseURL == "" {
+			baseURL = strings.TrimSpace(os.Getenv("EMBEDDING_BASE_URL"))
+		}
+		if baseURL == "" {
+			baseURL = strings.TrimSpace(os.Getenv("FASTAPI_EMBEDDINGS_URL"))
+		}
+		return NewClient(baseURL), nil
+	case "local":
+		return NewLocalReranker(), nil
+	case "none", "off", "disabled":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported RERANK_PROVIDER: %s", provider)
+	}
+}
@@ -0,0 +1,59 @@
+package rerank
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+// LocalReranker scores candidates by query term coverage without any model.
+// It is deterministic, which makes it suitable for tests and offline setups.
+type LocalReranker struct{}
+
+func NewLocalReranker() LocalReranker {
+	return LocalReranker{}
+}
+
+// Rerank returns the fraction of distinct query terms found in each candidate.
+func (LocalReranker) Rerank(_ context.Context, query string, candidates []retrieval.RerankCandidate) ([]float64, error) {
+	terms := tokenize(query)
+	scores := make([]float64, len(candidates))
+	if len(terms) == 0 {
+		return scores, nil
+	}
+
+	for i, candidate := range candidates {
+		present := make(map[string]struct{})
+		for _, token := range tokenize(candidate.Content) {
+			present[token] = struct{}{}
+		}
+		matched := 0
+		for _, term := range terms {
+			if _, ok := present[term]; ok {
+				matched++
+			}
+		}
+		scores[i] = float64(matched) / float64(len(terms))
+	}
+	return scores, nil
+}
+
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]struct{}, len(fields))
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if _, ok := seen[field]; ok {
+			continue
+		}
+		seen[field] = struct{}{}
+		tokens = append(tokens, field)
+	}
+	return tokens
+}
+
+var _ retrieval.Reranker = LocalReranker{}
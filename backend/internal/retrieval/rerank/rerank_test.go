@@ -0,0 +1,56 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+func TestLocalRerankerScoresTermCoverage(t *testing.T) {
+	scores, err := NewLocalReranker().Rerank(context.Background(), "activate document version", []retrieval.RerankCandidate{
+		{ChunkID: "a", Content: "Unrelated text about billing."},
+		{ChunkID: "b", Content: "ActivateDocumentVersion flips the document version flag."},
+		{ChunkID: "c", Content: "We activate each document version after embedding."},
+	})
+	if err != nil {
+		t.Fatalf("Rerank() error = %v", err)
+	}
+	want := []float64{0, 2.0 / 3.0, 1}
+	for i := range want {
+		if scores[i] != want[i] {
+			t.Fatalf("Rerank() scores = %v, want %v", scores, want)
+		}
+	}
+}
+
+func TestClientRerank(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rerank" {
+			t.Fatalf("unexpected path %q", r.URL.Path)
+		}
+		var payload rerankRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if payload.Query != "q" || len(payload.Documents) != 2 {
+			t.Fatalf("unexpected payload %+v", payload)
+		}
+		_ = json.NewEncoder(w).Encode(rerankResponse{Model: "m", Scores: []float64{0.2, 0.9}})
+	}))
+	defer server.Close()
+
+	scores, err := NewClient(server.URL).Rerank(context.Background(), "q", []retrieval.RerankCandidate{
+		{ChunkID: "a", Content: "first"},
+		{ChunkID: "b", Content: "second"},
+	})
+	if err != nil {
+		t.Fatalf("Rerank() error = %v", err)
+	}
+	if len(scores) != 2 || scores[1] != 0.9 {
+		t.Fatalf("Rerank() scores = %v", scores)
+	}
+}
@@ -0,0 +1,15 @@
+package retrieval
+
+import "context"
+
+// RerankCandidate is one merged candidate handed to a Reranker.
+type RerankCandidate struct {
+	ChunkID string
+	Content string
+}
+
+// Reranker rescores the top merged candidates for a query.
+// Implementations must return exactly one score per candidate, in candidate order.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, candidates []RerankCandidate) ([]float64, error)
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"ragtime-backend/internal/retrieval"
+	"ragtime-backend/internal/retrieval/cache"
+)
+
+func (s *Service) TopQueries(ctx context.Context, query retrieval.AnalyticsQuery) (*retrieval.AnalyticsPage[retrieval.QueryStat], error) {
+	return listAnalytics(ctx, s, query, cache.Layer.ListTopQueries)
+}
+
+func (s *Service) ZeroResultQueries(ctx context.Context, query retrieval.AnalyticsQuery) (*retrieval.AnalyticsPage[retrieval.ZeroResultQuery], error) {
+	return listAnalytics(ctx, s, query, cache.Layer.ListZeroResultQueries)
+}
+
+func (s *Service) LatencyPercentiles(ctx context.Context, query retrieval.AnalyticsQuery) (*retrieval.AnalyticsPage[retrieval.LatencyBucket], error) {
+	return listAnalytics(ctx, s, query, cache.Layer.ListLatencyPercentiles)
+}
+
+func (s *Service) MostRetrievedDocuments(ctx context.Context, query retrieval.AnalyticsQuery) (*retrieval.AnalyticsPage[retrieval.DocumentUsage], error) {
+	return listAnalytics(ctx, s, query, cache.Layer.ListMostRetrievedDocuments)
+}
+
+func (s *Service) NeverRetrievedDocuments(ctx context.Context, query retrieval.AnalyticsQuery) (*retrieval.AnalyticsPage[retrieval.DocumentUsage], error) {
+	return listAnalytics(ctx, s, query, cache.Layer.ListNeverRetrievedDocuments)
+}
+
+// ProfileDistribution counts a knowledge base's requests by effective
+// retrieval profile and semantic weight. Both lists are short, so they are
+// not paginated.
+func (s *Service) ProfileDistribution(ctx context.Context, query retrieval.AnalyticsQuery) (*retrieval.ProfileDistribution, error) {
+	if s.cache == nil {
+		return nil, retrieval.ErrNilRepository
+	}
+	query, err := s.analyticsQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, weights, err := s.cache.ListProfileDistribution(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if profiles == nil {
+		profiles = []retrieval.ProfileUsage{}
+	}
+	if weights == nil {
+		weights = []retrieval.WeightUsage{}
+	}
+	return &retrieval.ProfileDistribution{
+		KnowledgeBaseID: query.KnowledgeBaseID,
+		From:            query.From,
+		To:              query.To,
+		Profiles:        profiles,
+		SemanticWeights: weights,
+	}, nil
+}
+
+// listAnalytics reads one page of aggregated rows, asking for one row more
+// than the page holds to tell whether another page follows.
+func listAnalytics[T any](
+	ctx context.Context,
+	s *Service,
+	query retrieval.AnalyticsQuery,
+	list func(cache.Layer, context.Context, retrieval.AnalyticsQuery) ([]T, error),
+) (*retrieval.AnalyticsPage[T], error) {
+	if s.cache == nil {
+		return nil, retrieval.ErrNilRepository
+	}
+	query, err := s.analyticsQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	lookahead := query
+	lookahead.Limit++
+	items, err := list(s.cache, ctx, lookahead)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &retrieval.AnalyticsPage[T]{
+		KnowledgeBaseID: query.KnowledgeBaseID,
+		From:            query.From,
+		To:              query.To,
+		Limit:           query.Limit,
+		Offset:          query.Offset,
+		Items:           items,
+	}
+	if len(items) > query.Limit {
+		next := query.Offset + query.Limit
+		page.NextOffset = &next
+		page.Items = items[:query.Limit]
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	return page, nil
+}
+
+// analyticsQuery defaults the range to the DefaultAnalyticsWindow ending now,
+// the page to DefaultAnalyticsLimit rows and buckets to days, then validates.
+func (s *Service) analyticsQuery(query retrieval.AnalyticsQuery) (retrieval.AnalyticsQuery, error) {
+	if query.To.IsZero() {
+		query.To = s.now()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-retrieval.DefaultAnalyticsWindow)
+	}
+	query.From = query.From.UTC()
+	query.To = query.To.UTC()
+	if query.Limit == 0 {
+		query.Limit = retrieval.DefaultAnalyticsLimit
+	}
+	query.Bucket = strings.ToLower(strings.TrimSpace(query.Bucket))
+	if query.Bucket == "" {
+		query.Bucket = retrieval.DefaultAnalyticsBucket
+	}
+	return query, retrieval.ValidateAnalyticsQuery(query)
+}
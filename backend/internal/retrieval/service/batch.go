@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+// RetrieveBatch runs several queries against one knowledge base. Their query
+// texts are embedded in a single embedder call and the searches run on at
+// most the configured number of workers. Each query succeeds or fails on its
+// own; only an empty or oversized batch fails as a whole. Queries that fail
+// validation or continue a cursor are not embedded.
+func (s *Service) RetrieveBatch(ctx context.Context, knowledgeBaseID string, requests []retrieval.Request) ([]retrieval.BatchResult, error) {
+	if s.cache == nil {
+		return nil, retrieval.ErrNilRepository
+	}
+	if s.embedder == nil {
+		return nil, retrieval.ErrNilEmbedder
+	}
+	if knowledgeBaseID == "" {
+		return nil, retrieval.ErrMissingKnowledgeBase
+	}
+	if err := retrieval.ValidateBatch(len(requests)); err != nil {
+		return nil, err
+	}
+
+	results := make([]retrieval.BatchResult, len(requests))
+	batch := make([]retrieval.Request, len(requests))
+	// textIndexes maps each query to its text in texts, or -1 when it is not
+	// embedded; identical texts are embedded once.
+	textIndexes := make([]int, len(requests))
+	positions := map[string]int{}
+	var texts []string
+	for i, req := range requests {
+		req.KnowledgeBaseID = knowledgeBaseID
+		batch[i] = req
+		textIndexes[i] = -1
+
+		checked := req
+		applyDefaults(&checked, s.defaultTopK, s.defaultHybrid)
+		if err := retrieval.ValidateRequest(checked); err != nil {
+			results[i].Err = err
+			continue
+		}
+		if checked.Cursor != "" {
+			continue
+		}
+		text := embeddingText(checked.Query, retrieval.ParseQuery(checked.Query))
+		position, ok := positions[text]
+		if !ok {
+			position = len(texts)
+			positions[text] = position
+			texts = append(texts, text)
+		}
+		textIndexes[i] = position
+	}
+
+	embedded := make([]*queryEmbedding, len(requests))
+	if len(texts) > 0 {
+		vectors, dim, hits, err := s.embedQueries(ctx, texts)
+		if err == nil && len(vectors) != len(texts) {
+			err = fmt.Errorf("embedding service returned %d vectors for %d texts", len(vectors), len(texts))
+		}
+		for i, position := range textIndexes {
+			if position < 0 {
+				continue
+			}
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			embedded[i] = &queryEmbedding{vector: vectors[position], dim: dim, cached: hits[position]}
+		}
+	}
+
+	workers := make(chan struct{}, s.batchWorkers)
+	var wg sync.WaitGroup
+	for i := range batch {
+		if results[i].Err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			results[i].Response, results[i].Err = s.retrieve(ctx, batch[i], embedded[i])
+		}(i)
+	}
+	wg.Wait()
+	return results, nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+// SubmitJudgments stores relevance judgments of results logged for the
+// knowledge base and returns how many matched a logged result.
+func (s *Service) SubmitJudgments(ctx context.Context, knowledgeBaseID string, judgments []retrieval.RelevanceJudgment) (int, error) {
+	if s.cache == nil {
+		return 0, retrieval.ErrNilRepository
+	}
+	if knowledgeBaseID == "" {
+		return 0, retrieval.ErrMissingKnowledgeBase
+	}
+	if len(judgments) == 0 {
+		return 0, retrieval.ErrMissingJudgments
+	}
+	if len(judgments) > retrieval.MaxJudgmentsPerUpload {
+		return 0, retrieval.ErrTooManyJudgments
+	}
+	for _, judgment := range judgments {
+		if strings.TrimSpace(judgment.QueryID) == "" || strings.TrimSpace(judgment.ChunkID) == "" {
+			return 0, retrieval.ErrInvalidJudgment
+		}
+	}
+	return s.cache.InsertCalibrationJudgments(ctx, knowledgeBaseID, judgments)
+}
+
+// Calibrate fits the knowledge base's confidence calibration for a fusion
+// method from its judged results and stores it for later queries.
+func (s *Service) Calibrate(ctx context.Context, knowledgeBaseID string, fusionMethod string) (*retrieval.ScoreCalibration, error) {
+	if s.cache == nil {
+		return nil, retrieval.ErrNilRepository
+	}
+	if knowledgeBaseID == "" {
+		return nil, retrieval.ErrMissingKnowledgeBase
+	}
+	fusionMethod = strings.ToLower(strings.TrimSpace(fusionMethod))
+	if fusionMethod == "" {
+		fusionMethod = retrieval.DefaultFusion
+	}
+	if !retrieval.IsValidFusion(fusionMethod) {
+		return nil, retrieval.ErrInvalidFusion
+	}
+
+	samples, err := s.cache.GetCalibrationSamples(ctx, knowledgeBaseID, fusionMethod)
+	if err != nil {
+		return nil, err
+	}
+	slope, intercept, err := retrieval.FitCalibration(samples)
+	if err != nil {
+		return nil, err
+	}
+
+	calibration := retrieval.ScoreCalibration{
+		KnowledgeBaseID: knowledgeBaseID,
+		FusionMethod:    fusionMethod,
+		Slope:           slope,
+		Intercept:       intercept,
+		SampleCount:     len(samples),
+		FittedAt:        s.now(),
+	}
+	if err := s.cache.SaveScoreCalibration(ctx, calibration); err != nil {
+		return nil, err
+	}
+	return &calibration, nil
+}
+
+// applyCalibration sets each candidate's confidence from the knowledge base's
+// calibration for the request's fusion method, reporting whether one exists.
+func (s *Service) applyCalibration(ctx context.Context, req retrieval.Request, merged []mergedScore) (bool, error) {
+	calibration, err := s.cache.GetScoreCalibration(ctx, req.KnowledgeBaseID, req.Fusion)
+	if err != nil {
+		return false, err
+	}
+	if calibration == nil {
+		return false, nil
+	}
+	for i := range merged {
+		confidence := calibration.Confidence(merged[i].Score.Final)
+		merged[i].Confidence = &confidence
+	}
+	return true, nil
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+// collapseByDocument keeps at most perDocument chunks of each document,
+// walking merged in rank order. With fold, a document's surplus chunks are
+// recorded as siblings of its best chunk; otherwise they are dropped. Chunks
+// whose document cannot be resolved are kept. It returns the new ranking and
+// how many chunks were removed from it.
+func (s *Service) collapseByDocument(
+	ctx context.Context,
+	merged []mergedScore,
+	perDocument int,
+	fold bool,
+) ([]mergedScore, int, error) {
+	chunkIDs := make([]string, 0, len(merged))
+	for _, item := range merged {
+		chunkIDs = append(chunkIDs, item.ChunkID)
+	}
+	documents, err := s.cache.GetChunkDocumentIDs(ctx, chunkIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	kept := make([]mergedScore, 0, len(merged))
+	best := make(map[string]int, len(merged))
+	counts := make(map[string]int, len(merged))
+	for _, item := range merged {
+		documentID, ok := documents[item.ChunkID]
+		if !ok {
+			kept = append(kept, item)
+			continue
+		}
+
+		counts[documentID]++
+		if counts[documentID] <= perDocument {
+			if _, seen := best[documentID]; !seen {
+				best[documentID] = len(kept)
+			}
+			kept = append(kept, item)
+			continue
+		}
+		if fold {
+			head := &kept[best[documentID]]
+			head.Siblings = append(head.Siblings, retrieval.Sibling{ChunkID: item.ChunkID, Score: item.Score.Final})
+		}
+	}
+	return kept, len(merged) - len(kept), nil
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"unicode/utf8"
+
+	md "ragtime-backend/internal/markdown"
+	"ragtime-backend/internal/retrieval"
+)
+
+// contextChunk is a chunk chosen for a packed context; retrieved marks the
+// query's results as opposed to neighbours added around them.
+type contextChunk struct {
+	record    retrieval.ChunkRecord
+	retrieved bool
+}
+
+// BuildContext retrieves results for the query and packs as many as fit in the
+// token budget into one context string, best results first. Each result is
+// packed with its neighbours when they fit and alone otherwise; results that
+// do not fit even alone are skipped.
+func (s *Service) BuildContext(ctx context.Context, req retrieval.ContextRequest) (*retrieval.ContextResponse, error) {
+	if s.cache == nil {
+		return nil, retrieval.ErrNilRepository
+	}
+	if req.TokenBudget == 0 {
+		req.TokenBudget = retrieval.DefaultContextTokenBudget
+	}
+	if err := retrieval.ValidateContextRequest(req); err != nil {
+		return nil, err
+	}
+	// A context is always packed from the first page of results.
+	req.Cursor = ""
+	req.PageSize = 0
+
+	res, err := s.Retrieve(ctx, req.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	chunkIDs := make([]string, 0, len(res.Results))
+	for _, result := range res.Results {
+		chunkIDs = append(chunkIDs, result.ChunkID)
+	}
+	records := map[string]retrieval.ChunkRecord{}
+	if len(chunkIDs) > 0 {
+		found, err := s.cache.GetChunksWithDocumentsForKB(ctx, req.KnowledgeBaseID, chunkIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range found {
+			records[record.ChunkID] = record
+		}
+	}
+
+	selected := map[string]contextChunk{}
+	packed := 0
+	truncated := false
+	for _, result := range res.Results {
+		record, ok := records[result.ChunkID]
+		if !ok {
+			continue
+		}
+		hit := map[string]contextChunk{record.ChunkID: {record: record, retrieved: true}}
+
+		attempts := []map[string]contextChunk{hit}
+		if req.AdjacentBefore > 0 || req.AdjacentAfter > 0 {
+			neighbours, err := s.cache.GetChunksByDocumentVersionRange(
+				ctx,
+				record.DocumentVersionID,
+				max(record.SequenceNumber-int32(req.AdjacentBefore), 0),
+				record.SequenceNumber+int32(req.AdjacentAfter),
+			)
+			if err != nil {
+				return nil, err
+			}
+			expanded := maps.Clone(hit)
+			for _, neighbour := range neighbours {
+				if _, ok := expanded[neighbour.ChunkID]; !ok {
+					expanded[neighbour.ChunkID] = contextChunk{record: neighbour}
+				}
+			}
+			attempts = []map[string]contextChunk{expanded, hit}
+		}
+
+		fitted := false
+		for i, additions := range attempts {
+			candidate := maps.Clone(selected)
+			for chunkID, chunk := range additions {
+				if existing, ok := candidate[chunkID]; !ok || chunk.retrieved && !existing.retrieved {
+					candidate[chunkID] = chunk
+				}
+			}
+			text, _, _ := packContext(candidate)
+			if estimateContextTokens(text) <= req.TokenBudget {
+				selected = candidate
+				fitted = true
+				truncated = truncated || i > 0
+				break
+			}
+		}
+		if !fitted {
+			truncated = true
+			continue
+		}
+		packed++
+	}
+
+	text, sources, citations := packContext(selected)
+	return &retrieval.ContextResponse{
+		RequestID:       res.RequestID,
+		QueryID:         res.QueryID,
+		KnowledgeBaseID: req.KnowledgeBaseID,
+		Query:           req.Query,
+		TokenBudget:     req.TokenBudget,
+		TokenCount:      estimateContextTokens(text),
+		ResultCount:     len(res.Results),
+		PackedResults:   packed,
+		Truncated:       truncated,
+		Context:         text,
+		Sources:         sources,
+		Citations:       citations,
+		EmptyReason:     res.EmptyReason,
+	}, nil
+}
+
+// packContext writes the chunks grouped by document, ordered by path and
+// sequence, each document under a "[n] path" header. Contiguous chunks are
+// stitched into one passage without repeating their overlap; passages are
+// separated by a blank line.
+func packContext(chunks map[string]contextChunk) (string, []retrieval.ContextSource, []retrieval.ContextCitation) {
+	ordered := make([]retrieval.ChunkRecord, 0, len(chunks))
+	for _, chunk := range chunks {
+		ordered = append(ordered, chunk.record)
+	}
+	sortChunkRecords(ordered)
+
+	var out strings.Builder
+	pos := 0
+	write := func(text string) {
+		out.WriteString(text)
+		pos += utf8.RuneCountInString(text)
+	}
+
+	sources := []retrieval.ContextSource{}
+	citations := []retrieval.ContextCitation{}
+	for i, span := range stitchChunks(ordered) {
+		first := span.chunks[0]
+		if len(sources) == 0 || sources[len(sources)-1].DocumentVersionID != first.DocumentVersionID {
+			if i > 0 {
+				write("\n\n")
+			}
+			sources = append(sources, retrieval.ContextSource{
+				Index:             len(sources) + 1,
+				DocumentID:        first.DocumentID,
+				DocumentVersionID: first.DocumentVersionID,
+				DocumentPath:      first.DocumentPath,
+				DocumentTitle:     first.DocumentTitle,
+			})
+			write(fmt.Sprintf("[%d] %s\n", len(sources), first.DocumentPath))
+		} else {
+			write("\n\n")
+		}
+
+		passageStart := pos
+		write(span.content)
+		for j, chunk := range span.chunks {
+			citations = append(citations, retrieval.ContextCitation{
+				StartRune:     passageStart + span.offsets[j].start,
+				EndRune:       passageStart + span.offsets[j].end,
+				ChunkID:       chunk.ChunkID,
+				ChunkSequence: chunk.SequenceNumber,
+				Source:        len(sources),
+				Retrieved:     chunks[chunk.ChunkID].retrieved,
+			})
+		}
+	}
+	return out.String(), sources, citations
+}
+
+// estimateContextTokens uses the chunkers' token estimate so budgets match
+// chunk sizes.
+func estimateContextTokens(text string) int {
+	return md.EstimateTokens(md.Block{Type: md.BlockParagraph, Content: text}, md.BiasBalanced)
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+const (
+	diversityPoolFloor      = 20
+	diversityPoolMultiplier = 4
+)
+
+// diversityPoolSize bounds how many ranked candidates MMR may pull from.
+func diversityPoolSize(topK int) int {
+	size := topK * diversityPoolMultiplier
+	if size < diversityPoolFloor {
+		size = diversityPoolFloor
+	}
+	return size
+}
+
+// diversify reorders the head of merged with Maximal Marginal Relevance so the
+// first topK entries trade relevance against similarity to already selected
+// chunks. It returns how many chunks were pushed out of the original top K.
+func (s *Service) diversify(
+	ctx context.Context,
+	merged []mergedScore,
+	poolSize int,
+	topK int,
+	lambda float64,
+	vectorDimension int,
+) (int, error) {
+	if poolSize > len(merged) {
+		poolSize = len(merged)
+	}
+	if poolSize <= 1 || topK <= 0 {
+		return 0, nil
+	}
+
+	pool := merged[:poolSize]
+	chunkIDs := make([]string, 0, len(pool))
+	for _, item := range pool {
+		chunkIDs = append(chunkIDs, item.ChunkID)
+	}
+	vectors, err := s.cache.GetChunkVectors(ctx, vectorDimension, chunkIDs)
+	if err != nil {
+		return 0, fmt.Errorf("load chunk vectors: %w", err)
+	}
+
+	order := selectMMR(pool, vectors, lambda, topK)
+
+	originalTop := make(map[string]struct{}, topK)
+	for i := 0; i < len(pool) && i < topK; i++ {
+		originalTop[pool[i].ChunkID] = struct{}{}
+	}
+
+	reordered := make([]mergedScore, 0, len(pool))
+	for _, idx := range order {
+		reordered = append(reordered, pool[idx])
+	}
+	copy(pool, reordered)
+
+	displaced := 0
+	for i := 0; i < len(pool) && i < topK; i++ {
+		if _, ok := originalTop[pool[i].ChunkID]; !ok {
+			displaced++
+		}
+	}
+	return displaced, nil
+}
+
+// selectMMR returns pool indexes in MMR order for the first limit picks,
+// followed by the unselected remainder in their original order.
+func selectMMR(pool []mergedScore, vectors map[string][]float32, lambda float64, limit int) []int {
+	relevance := poolRelevance(pool)
+	selected := make([]int, 0, len(pool))
+	picked := make([]bool, len(pool))
+	maxSimilarity := make([]float64, len(pool))
+
+	for len(selected) < limit && len(selected) < len(pool) {
+		best := -1
+		bestScore := math.Inf(-1)
+		for i := range pool {
+			if picked[i] {
+				continue
+			}
+			score := lambda*relevance[i] - (1-lambda)*maxSimilarity[i]
+			if score > bestScore {
+				best = i
+				bestScore = score
+			}
+		}
+
+		picked[best] = true
+		selected = append(selected, best)
+
+		chosen := vectors[pool[best].ChunkID]
+		for i := range pool {
+			if picked[i] {
+				continue
+			}
+			if similarity := cosineSimilarity(chosen, vectors[pool[i].ChunkID]); similarity > maxSimilarity[i] {
+				maxSimilarity[i] = similarity
+			}
+		}
+	}
+
+	for i := range pool {
+		if !picked[i] {
+			selected = append(selected, i)
+		}
+	}
+	return selected
+}
+
+// poolRelevance rescales the ranking signal of each candidate to [0, 1] so it
+// is comparable with cosine similarity regardless of fusion method.
+func poolRelevance(pool []mergedScore) []float64 {
+	relevance := make([]float64, len(pool))
+	minValue := math.Inf(1)
+	maxValue := math.Inf(-1)
+	for i, item := range pool {
+		value := item.Score.Final
+		if item.Score.Rerank != nil {
+			value = *item.Score.Rerank
+		}
+		relevance[i] = value
+		minValue = math.Min(minValue, value)
+		maxValue = math.Max(maxValue, value)
+	}
+
+	spread := maxValue - minValue
+	for i := range relevance {
+		if spread <= 0 {
+			relevance[i] = 1
+			continue
+		}
+		relevance[i] = (relevance[i] - minValue) / spread
+	}
+	return relevance
+}
+
+// cosineSimilarity returns 0 when either vector is missing so chunks without a
+// stored embedding are never penalised as redundant.
+func cosineSimilarity(a []float32, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+// Explain runs req's query the way Retrieve does and reports how the chunk
+// scored in each candidate list, or why it was not a candidate. Nothing is
+// logged as a retrieval request.
+func (s *Service) Explain(ctx context.Context, req retrieval.ExplainRequest) (*retrieval.Explanation, error) {
+	if s.cache == nil {
+		return nil, retrieval.ErrNilRepository
+	}
+	if s.embedder == nil {
+		return nil, retrieval.ErrNilEmbedder
+	}
+
+	// Explanations always search afresh rather than reading a stored page.
+	req.Cursor = ""
+	req.ChunkID = strings.TrimSpace(req.ChunkID)
+	applyDefaults(&req.Request, s.defaultTopK, s.defaultHybrid)
+	if err := retrieval.ValidateRequest(req.Request); err != nil {
+		return nil, err
+	}
+	if req.ChunkID == "" {
+		return nil, retrieval.ErrMissingChunkID
+	}
+	if err := s.applyKnowledgeBaseSettings(ctx, &req.Request); err != nil {
+		return nil, err
+	}
+
+	profileEffective, semanticWeight, autoSignals := resolveProfileAndWeight(req.Request)
+	req.HybridWeight = semanticWeight
+
+	parsedQuery := retrieval.ParseQuery(req.Query)
+	embeddings, dim, _, err := s.embedQuery(ctx, embeddingText(req.Query, parsedQuery))
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) == 0 {
+		return nil, fmt.Errorf("embedding service returned no vectors")
+	}
+
+	candidates, err := s.searchCandidates(ctx, req.Request, parsedQuery, embeddings[0], dim, semanticWeight)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.applyFreshness(ctx, req.Request, candidates.merged, map[string]retrieval.ChunkRecord{}); err != nil {
+		return nil, err
+	}
+	chunk, err := s.cache.ExplainChunk(ctx, candidates.params, req.ChunkID)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &retrieval.Explanation{
+		KnowledgeBaseID:           req.KnowledgeBaseID,
+		ChunkID:                   req.ChunkID,
+		Query:                     req.Query,
+		ParsedQuery:               parsedQuery,
+		RetrievalProfileEffective: profileEffective,
+		AutoSignalsDetected:       autoSignals,
+		SemanticWeight:            semanticWeight,
+		LexicalWeight:             1 - semanticWeight,
+		FusionMethod:              req.Fusion,
+		FiltersApplied:            buildFilterPayload(req.Filters),
+		Semantic: retrieval.SemanticExplanation{
+			ListExplanation: explainList(candidates.semantic, candidates.params.Limit, req.ChunkID),
+		},
+		Lexical: retrieval.LexicalExplanation{
+			ListExplanation: explainList(candidates.lexical, candidates.params.Limit, req.ChunkID),
+			Scorer:          req.LexicalScorer,
+			MatchedLexemes:  []string{},
+		},
+	}
+	if req.Fusion == retrieval.FusionRRF {
+		explanation.RRFK = req.RRFK
+	}
+	if req.LexicalScorer == retrieval.LexicalScorerBM25 {
+		k1, b := req.BM25K1, req.BM25B
+		explanation.Lexical.BM25K1 = &k1
+		explanation.Lexical.BM25B = &b
+	}
+	if req.FieldWeights.Enabled() {
+		weights := req.FieldWeights
+		explanation.Lexical.FieldWeights = &weights
+	}
+
+	if chunk != nil {
+		explanation.Semantic.CosineDistance = chunk.CosineDistance
+		tsRank := chunk.TSRank
+		explanation.Lexical.TSRank = &tsRank
+		if chunk.MatchedLexemes != nil {
+			explanation.Lexical.MatchedLexemes = chunk.MatchedLexemes
+		}
+	}
+	if explanation.Semantic.Rank == nil {
+		explanation.Semantic.NotCandidateReason = notCandidateReason(chunk, chunk != nil && chunk.CosineDistance != nil, retrieval.NotCandidateNoEmbedding)
+	}
+	if explanation.Lexical.Rank == nil {
+		explanation.Lexical.NotCandidateReason = notCandidateReason(chunk, chunk != nil && chunk.LexicalMatch, retrieval.NotCandidateNoLexicalMatch)
+	}
+
+	for i, item := range candidates.merged {
+		if item.ChunkID != req.ChunkID {
+			continue
+		}
+		rank := i + 1
+		score := item.Score
+		explanation.Candidate = true
+		explanation.FusedRank = &rank
+		explanation.Score = &score
+		break
+	}
+	return explanation, nil
+}
+
+// explainList locates chunkID in a candidate list ordered by descending score.
+func explainList(items []retrieval.ScoredChunk, limit int, chunkID string) retrieval.ListExplanation {
+	list := retrieval.ListExplanation{
+		Candidates:               len(items),
+		Limit:                    limit,
+		NormalizationDenominator: normalizationDenominator(items),
+	}
+	rank, ok := rankPositions(items)[chunkID]
+	if !ok {
+		return list
+	}
+	raw := items[rank-1].Score
+	normalized := normalizeScores(items)[chunkID]
+	list.Rank = &rank
+	list.Score = &raw
+	list.NormalizedScore = &normalized
+	return list
+}
+
+// notCandidateReason explains a chunk's absence from a list: the first search
+// predicate it fails, unmatched when it cannot appear in the list at all, or
+// beyond the candidate limit.
+func notCandidateReason(chunk *retrieval.ChunkExplanation, listed bool, unmatched string) string {
+	switch {
+	case chunk == nil:
+		return retrieval.NotCandidateNotFound
+	case !chunk.InKnowledgeBase:
+		return retrieval.NotCandidateOtherKB
+	case !chunk.ActiveVersion:
+		return retrieval.NotCandidateInactiveVersion
+	case !chunk.PassesFilters:
+		return retrieval.NotCandidateFilteredOut
+	case !listed:
+		return unmatched
+	default:
+		return retrieval.NotCandidateBeyondLimit
+	}
+}
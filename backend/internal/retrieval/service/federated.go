@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+// RetrieveFederated searches several knowledge bases with one query vector and
+// fuses their rankings. Each knowledge base's fused scores are scaled by its
+// weight before the lists are merged, and each knowledge base gets its own
+// retrieval_requests row linked by the federated request ID. The merged list
+// is cut to TopK by weighted fused score alone: rerank, diversity, score
+// thresholds, collapse and freshness boosts are not applied, including those
+// enabled by knowledge base settings.
+func (s *Service) RetrieveFederated(ctx context.Context, req retrieval.FederatedRequest) (*retrieval.FederatedResponse, error) {
+	if s.cache == nil {
+		return nil, retrieval.ErrNilRepository
+	}
+	if s.embedder == nil {
+		return nil, retrieval.ErrNilEmbedder
+	}
+
+	applyDefaults(&req.Request, s.defaultTopK, s.defaultHybrid)
+	if err := retrieval.ValidateFederatedRequest(req); err != nil {
+		return nil, err
+	}
+
+	profileEffective, semanticWeight, autoSignals := resolveProfileAndWeight(req.Request)
+	req.HybridWeight = semanticWeight
+
+	start := s.now()
+	federatedID := uuid.NewString()
+	filterPayload := buildFilterPayload(req.Filters)
+
+	requestIDs := make(map[string]string, len(req.Targets))
+	for _, target := range req.Targets {
+		requestID := uuid.NewString()
+		_, err := s.cache.InsertRetrievalRequest(ctx, retrieval.RetrievalRequestRecord{
+			ID:                 requestID,
+			KnowledgeBase:      target.KnowledgeBaseID,
+			Query:              req.Query,
+			Filters:            filterPayload,
+			TopK:               req.TopK,
+			HybridWeight:       req.HybridWeight,
+			CreatedAt:          start,
+			FederatedRequestID: &federatedID,
+			RetrievalProfile:   profileEffective,
+			// FusionMethod stays unset: the logged final scores include the
+			// knowledge base weight, so they must not feed score calibration.
+		})
+		if err != nil {
+			return nil, err
+		}
+		requestIDs[target.KnowledgeBaseID] = requestID
+	}
+
+	// All knowledge bases share the service embedder, so its single output
+	// dimension covers every target and the query is embedded once.
+	parsedQuery := retrieval.ParseQuery(req.Query)
+	embeddings, dim, embeddingCached, err := s.embedQuery(ctx, embeddingText(req.Query, parsedQuery))
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) == 0 {
+		return nil, fmt.Errorf("embedding service returned no vectors")
+	}
+
+	sets := make([]candidateSet, len(req.Targets))
+	kbRequests := make([]retrieval.Request, len(req.Targets))
+	errs := make([]error, len(req.Targets))
+	var wg sync.WaitGroup
+	for i, target := range req.Targets {
+		wg.Add(1)
+		go func(i int, target retrieval.FederatedTarget) {
+			defer wg.Done()
+			kbReq := req.Request
+			kbReq.KnowledgeBaseID = target.KnowledgeBaseID
+			if errs[i] = s.applyKnowledgeBaseSettings(ctx, &kbReq); errs[i] != nil {
+				return
+			}
+			kbRequests[i] = kbReq
+			sets[i], errs[i] = s.searchCandidates(ctx, kbReq, parsedQuery, embeddings[0], dim, semanticWeight)
+		}(i, target)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("search kb %s: %w", req.Targets[i].KnowledgeBaseID, err)
+		}
+	}
+
+	kbByChunk := map[string]string{}
+	merged := make([]mergedScore, 0)
+	for i, target := range req.Targets {
+		for _, item := range sets[i].merged {
+			if _, ok := kbByChunk[item.ChunkID]; ok {
+				continue
+			}
+			kbByChunk[item.ChunkID] = target.KnowledgeBaseID
+			item.Score.Final *= target.Weight
+			merged = append(merged, item)
+		}
+	}
+	sortResults(merged)
+	if len(merged) > req.TopK {
+		merged = merged[:req.TopK]
+	}
+
+	results, resultRecords, err := s.buildPage(ctx, "", merged, 0, map[string]retrieval.ChunkRecord{})
+	if err != nil {
+		return nil, err
+	}
+	resultCounts := make(map[string]int, len(req.Targets))
+	for i := range resultRecords {
+		kbID := kbByChunk[resultRecords[i].ChunkID]
+		resultRecords[i].RetrievalRequestID = requestIDs[kbID]
+		results[i].KnowledgeBaseID = kbID
+		resultCounts[kbID]++
+	}
+
+	latency := s.now().Sub(start).Milliseconds()
+	if err := s.cache.InsertRetrievalResults(ctx, resultRecords); err != nil {
+		return nil, err
+	}
+
+	summaries := make([]retrieval.FederatedKnowledgeBase, 0, len(req.Targets))
+	for i, target := range req.Targets {
+		requestID := requestIDs[target.KnowledgeBaseID]
+		count := resultCounts[target.KnowledgeBaseID]
+		emptyReason := ""
+		if count == 0 {
+			emptyReason = retrieval.EmptyReasonOutranked
+			if len(sets[i].merged) == 0 {
+				emptyReason = retrieval.EmptyReasonNoCandidates
+			}
+		}
+		if err := s.cache.UpdateRetrievalRequest(ctx, requestID, count, latency, count == 0, emptyReason); err != nil {
+			return nil, err
+		}
+
+		summary := retrieval.FederatedKnowledgeBase{
+			KnowledgeBaseID: target.KnowledgeBaseID,
+			RequestID:       requestID,
+			Weight:          target.Weight,
+			ResultCount:     count,
+		}
+		if req.Debug {
+			summary.Debug = &retrieval.DebugMetadata{
+				RetrievalProfileEffective: profileEffective,
+				SemanticWeightEffective:   semanticWeight,
+				FusionMethod:              req.Fusion,
+				AutoSignalsDetected:       autoSignals,
+				LexicalCandidates:         sets[i].lexicalCandidates,
+				SemanticCandidates:        sets[i].semanticCandidates,
+				QueryEmbeddingCached:      embeddingCached,
+				FiltersApplied:            filterPayload,
+				ParsedQuery:               &parsedQuery,
+			}
+			if req.Fusion == retrieval.FusionRRF {
+				summary.Debug.RRFK = req.RRFK
+			}
+			setLexicalScorerDebug(summary.Debug, kbRequests[i])
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return &retrieval.FederatedResponse{
+		FederatedRequestID: federatedID,
+		Query:              req.Query,
+		TopK:               req.TopK,
+		HybridWeight:       semanticWeight,
+		ResultCount:        len(results),
+		LatencyMS:          latency,
+		KnowledgeBases:     summaries,
+		Results:            results,
+		Passages:           results,
+	}, nil
+}
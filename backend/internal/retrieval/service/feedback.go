@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"ragtime-backend/internal/retrieval"
+	"ragtime-backend/internal/retrieval/cache"
+)
+
+// SubmitFeedback stores relevance labels for results of a logged query and
+// returns how many were stored. Labels of chunks the query did not return are
+// skipped; a submission none of whose labels match a result of a logged query
+// in the knowledge base fails with ErrUnknownQueryResult.
+func (s *Service) SubmitFeedback(ctx context.Context, submission retrieval.FeedbackSubmission) (int, error) {
+	if s.cache == nil {
+		return 0, retrieval.ErrNilRepository
+	}
+
+	submission.QueryID = strings.TrimSpace(submission.QueryID)
+	submission.UserID = strings.TrimSpace(submission.UserID)
+	labels := make([]retrieval.FeedbackLabel, len(submission.Labels))
+	for i, label := range submission.Labels {
+		label.ChunkID = strings.TrimSpace(label.ChunkID)
+		label.Thumbs = strings.ToLower(strings.TrimSpace(label.Thumbs))
+		labels[i] = label
+	}
+	submission.Labels = labels
+	if err := retrieval.ValidateFeedback(submission); err != nil {
+		return 0, err
+	}
+	return s.cache.InsertFeedback(ctx, submission)
+}
+
+// FeedbackSummary totals the knowledge base's feedback labels in a time range.
+func (s *Service) FeedbackSummary(ctx context.Context, query retrieval.AnalyticsQuery) (*retrieval.FeedbackSummary, error) {
+	if s.cache == nil {
+		return nil, retrieval.ErrNilRepository
+	}
+	query, err := s.analyticsQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return s.cache.GetFeedbackSummary(ctx, query)
+}
+
+// ChunkFeedback pages through per-chunk feedback totals, most labelled first.
+func (s *Service) ChunkFeedback(ctx context.Context, query retrieval.AnalyticsQuery) (*retrieval.AnalyticsPage[retrieval.ChunkFeedback], error) {
+	return listAnalytics(ctx, s, query, cache.Layer.ListChunkFeedback)
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+// applyFreshness adds req's freshness boost to every candidate's final score
+// and re-sorts them. Candidates are dated by the request's date field in their
+// document's source metadata, falling back to the version's created_at.
+// Chunks loaded for their dates are left in chunkMap for reuse. It returns
+// nil when the request has no boost.
+func (s *Service) applyFreshness(
+	ctx context.Context,
+	req retrieval.Request,
+	merged []mergedScore,
+	chunkMap map[string]retrieval.ChunkRecord,
+) (*retrieval.FreshnessDebug, error) {
+	if !req.Freshness.Enabled() {
+		return nil, nil
+	}
+	debug := &retrieval.FreshnessDebug{FreshnessOptions: req.Freshness}
+	if len(merged) == 0 {
+		return debug, nil
+	}
+
+	chunkIDs := make([]string, 0, len(merged))
+	for _, item := range merged {
+		chunkIDs = append(chunkIDs, item.ChunkID)
+	}
+	if err := s.loadChunks(ctx, chunkIDs, chunkMap); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	for i := range merged {
+		chunk, ok := chunkMap[merged[i].ChunkID]
+		if !ok {
+			continue
+		}
+		date, byField := freshnessDate(chunk, req.Freshness.DateField)
+		if date.IsZero() {
+			continue
+		}
+		boost := retrieval.FreshnessBoost(req.Freshness, now.Sub(date))
+		merged[i].Score.Freshness = &boost
+		merged[i].Score.Final += boost
+		debug.Boosted++
+		if byField {
+			debug.DatedByField++
+		}
+	}
+	sortResults(merged)
+	return debug, nil
+}
+
+// freshnessDate returns the chunk's date field when set and parseable and its
+// version's created_at otherwise, reporting whether the field was used.
+func freshnessDate(chunk retrieval.ChunkRecord, field string) (time.Time, bool) {
+	if field != "" {
+		if date, ok := retrieval.ParseFreshnessDate(chunk.SourceMetadata[field]); ok {
+			return date, true
+		}
+	}
+	return chunk.VersionCreatedAt, false
+}
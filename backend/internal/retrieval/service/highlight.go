@@ -0,0 +1,369 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+// maxSemanticWindows bounds how many sentence windows of one chunk are
+// embedded when looking for its semantic highlight.
+const maxSemanticWindows = 8
+
+// contentSpan is a run of runes in a chunk's content, by rune offset.
+type contentSpan struct {
+	start int
+	end   int
+}
+
+// highlight attaches snippets to results. Words whose lexemes match the
+// positive query terms are marked in lexical snippets; results without a
+// lexical match get the sentence window closest to queryVector instead.
+func (s *Service) highlight(
+	ctx context.Context,
+	knowledgeBaseID string,
+	parsed retrieval.ParsedQuery,
+	queryVector []float32,
+	results []retrieval.Result,
+) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	contents := make([][]rune, len(results))
+	for i := range results {
+		contents[i] = []rune(results[i].Content)
+	}
+	matcher, err := s.newLexicalMatcher(ctx, knowledgeBaseID, parsed, contents)
+	if err != nil {
+		return err
+	}
+
+	var semantic []int
+	for i := range results {
+		matches, _ := matcher.match(contents[i])
+		if len(matches) == 0 {
+			semantic = append(semantic, i)
+			continue
+		}
+		results[i].Highlights = lexicalSnippets(contents[i], matches)
+	}
+	if len(semantic) == 0 || len(queryVector) == 0 {
+		return nil
+	}
+	return s.semanticHighlights(ctx, queryVector, results, contents, semantic)
+}
+
+// lexicalMatcher finds the words of chunk contents whose lexemes match the
+// positive terms of a parsed query, using the knowledge base's text search
+// configuration.
+type lexicalMatcher struct {
+	lexemes  map[string][]string
+	exact    map[string]struct{}
+	prefixes []string
+}
+
+// newLexicalMatcher normalizes the query terms and every word of contents in
+// one GetLexemes call.
+func (s *Service) newLexicalMatcher(
+	ctx context.Context,
+	knowledgeBaseID string,
+	parsed retrieval.ParsedQuery,
+	contents [][]rune,
+) (*lexicalMatcher, error) {
+	var exactWords, prefixWords []string
+	for _, clause := range parsed.All {
+		for _, term := range clause.Any {
+			switch {
+			case term.Negated:
+				// Excluded terms never appear in a match, so there is nothing to mark.
+			case term.Prefix:
+				prefixWords = append(prefixWords, term.Text)
+			default:
+				text := []rune(term.Text)
+				exactWords = append(exactWords, spanTexts(text, wordSpans(text))...)
+			}
+		}
+	}
+	matcher := &lexicalMatcher{exact: make(map[string]struct{})}
+	if len(exactWords)+len(prefixWords) == 0 {
+		return matcher, nil
+	}
+
+	distinct := make(map[string]struct{})
+	for _, word := range append(append([]string{}, exactWords...), prefixWords...) {
+		distinct[word] = struct{}{}
+	}
+	for _, content := range contents {
+		for _, word := range spanTexts(content, wordSpans(content)) {
+			distinct[word] = struct{}{}
+		}
+	}
+	words := make([]string, 0, len(distinct))
+	for word := range distinct {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	lexemes, err := s.cache.GetLexemes(ctx, knowledgeBaseID, words)
+	if err != nil {
+		return nil, err
+	}
+	matcher.lexemes = lexemes
+	for _, word := range exactWords {
+		for _, lexeme := range lexemes[word] {
+			matcher.exact[lexeme] = struct{}{}
+		}
+	}
+	for _, word := range prefixWords {
+		matcher.prefixes = append(matcher.prefixes, lexemes[word]...)
+	}
+	return matcher, nil
+}
+
+// match returns the words of content with a lexeme that equals an exact query
+// lexeme or starts with a prefix lexeme, and how many distinct query lexemes
+// and prefixes those words cover.
+func (m *lexicalMatcher) match(content []rune) ([]retrieval.TermMatch, int) {
+	var matches []retrieval.TermMatch
+	covered := make(map[string]struct{})
+	for _, word := range wordSpans(content) {
+		lexeme, key, ok := m.matchLexeme(m.lexemes[string(content[word.start:word.end])])
+		if !ok {
+			continue
+		}
+		covered[key] = struct{}{}
+		matches = append(matches, retrieval.TermMatch{
+			Lexeme:    lexeme,
+			StartRune: word.start,
+			EndRune:   word.end,
+		})
+	}
+	return matches, len(covered)
+}
+
+// matchLexeme returns the matching candidate lexeme and the query lexeme or
+// prefix it matched.
+func (m *lexicalMatcher) matchLexeme(candidates []string) (string, string, bool) {
+	for _, lexeme := range candidates {
+		if _, ok := m.exact[lexeme]; ok {
+			return lexeme, lexeme, true
+		}
+		for _, prefix := range m.prefixes {
+			if strings.HasPrefix(lexeme, prefix) {
+				return lexeme, prefix + "*", true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// lexicalSnippets builds up to MaxHighlightSnippets windows, densest first,
+// and returns them in content order. Every match inside a window is reported
+// with it so no match is marked twice.
+func lexicalSnippets(content []rune, matches []retrieval.TermMatch) []retrieval.Highlight {
+	var snippets []retrieval.Highlight
+	remaining := matches
+	for len(remaining) > 0 && len(snippets) < retrieval.MaxHighlightSnippets {
+		first, last := 0, 0
+		for i := range remaining {
+			j := i
+			for j+1 < len(remaining) && remaining[j+1].EndRune-remaining[i].StartRune <= retrieval.HighlightSnippetRunes {
+				j++
+			}
+			if j-i > last-first {
+				first, last = i, j
+			}
+		}
+
+		window := snippetWindow(content, remaining[first].StartRune, remaining[last].EndRune)
+		var inside, outside []retrieval.TermMatch
+		for _, match := range remaining {
+			if match.StartRune >= window.start && match.EndRune <= window.end {
+				inside = append(inside, match)
+			} else {
+				outside = append(outside, match)
+			}
+		}
+		snippets = append(snippets, retrieval.Highlight{
+			Text:      string(content[window.start:window.end]),
+			StartRune: window.start,
+			EndRune:   window.end,
+			Source:    retrieval.HighlightLexical,
+			Matches:   inside,
+		})
+		remaining = outside
+	}
+
+	sort.Slice(snippets, func(i, j int) bool {
+		return snippets[i].StartRune < snippets[j].StartRune
+	})
+	return snippets
+}
+
+// snippetWindow pads the span [from, to) with context up to
+// HighlightSnippetRunes and trims the padding back to whole words.
+func snippetWindow(content []rune, from int, to int) contentSpan {
+	pad := (retrieval.HighlightSnippetRunes - (to - from)) / 2
+	if pad < 0 {
+		pad = 0
+	}
+	start := max(from-pad, 0)
+	end := min(to+pad, len(content))
+
+	for start > 0 && start < from && !unicode.IsSpace(content[start-1]) {
+		start++
+	}
+	for start < from && unicode.IsSpace(content[start]) {
+		start++
+	}
+	for end < len(content) && end > to && !unicode.IsSpace(content[end]) {
+		end--
+	}
+	for end > to && unicode.IsSpace(content[end-1]) {
+		end--
+	}
+	return contentSpan{start: start, end: end}
+}
+
+// semanticHighlights embeds the sentence windows of each listed result in one
+// call and keeps the window most similar to the query.
+func (s *Service) semanticHighlights(
+	ctx context.Context,
+	queryVector []float32,
+	results []retrieval.Result,
+	contents [][]rune,
+	indexes []int,
+) error {
+	var owners []int
+	var windows []contentSpan
+	var texts []string
+	for _, i := range indexes {
+		for _, window := range sentenceWindows(contents[i]) {
+			owners = append(owners, i)
+			windows = append(windows, window)
+			texts = append(texts, string(contents[i][window.start:window.end]))
+		}
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+
+	vectors, _, err := s.highlighter.EmbedTexts(ctx, texts)
+	if err != nil {
+		return err
+	}
+	if len(vectors) != len(texts) {
+		return fmt.Errorf("embedding service returned %d vectors for %d highlight windows", len(vectors), len(texts))
+	}
+
+	best := make(map[int]int, len(indexes))
+	scores := make([]float64, len(windows))
+	for k := range windows {
+		scores[k] = cosineSimilarity(queryVector, vectors[k])
+		if current, ok := best[owners[k]]; !ok || scores[k] > scores[current] {
+			best[owners[k]] = k
+		}
+	}
+	for i, k := range best {
+		score := scores[k]
+		results[i].Highlights = []retrieval.Highlight{{
+			Text:      texts[k],
+			StartRune: windows[k].start,
+			EndRune:   windows[k].end,
+			Source:    retrieval.HighlightSemantic,
+			Score:     &score,
+		}}
+	}
+	return nil
+}
+
+// sentenceWindows groups consecutive sentences into windows of at most
+// HighlightSnippetRunes; a longer sentence is its own window. Only the first
+// maxSemanticWindows windows are returned to bound embedding cost.
+func sentenceWindows(content []rune) []contentSpan {
+	var windows []contentSpan
+	current := contentSpan{start: -1}
+	for _, sentence := range sentenceSpans(content) {
+		if current.start >= 0 && sentence.end-current.start <= retrieval.HighlightSnippetRunes {
+			current.end = sentence.end
+			continue
+		}
+		if current.start >= 0 {
+			windows = append(windows, current)
+			if len(windows) == maxSemanticWindows {
+				return windows
+			}
+		}
+		current = sentence
+	}
+	if current.start >= 0 {
+		windows = append(windows, current)
+	}
+	return windows
+}
+
+// sentenceSpans splits content after sentence punctuation followed by
+// whitespace and at line breaks, trimming surrounding whitespace.
+func sentenceSpans(content []rune) []contentSpan {
+	var spans []contentSpan
+	emit := func(start int, end int) {
+		for start < end && unicode.IsSpace(content[start]) {
+			start++
+		}
+		for end > start && unicode.IsSpace(content[end-1]) {
+			end--
+		}
+		if start < end {
+			spans = append(spans, contentSpan{start: start, end: end})
+		}
+	}
+
+	start := 0
+	for i, r := range content {
+		switch {
+		case r == '\n':
+			emit(start, i)
+			start = i + 1
+		case (r == '.' || r == '!' || r == '?') && i+1 < len(content) && unicode.IsSpace(content[i+1]):
+			emit(start, i+1)
+			start = i + 1
+		}
+	}
+	emit(start, len(content))
+	return spans
+}
+
+// wordSpans returns the runs of letters and digits in content.
+func wordSpans(content []rune) []contentSpan {
+	var spans []contentSpan
+	start := -1
+	for i, r := range content {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			spans = append(spans, contentSpan{start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, contentSpan{start: start, end: len(content)})
+	}
+	return spans
+}
+
+func spanTexts(content []rune, spans []contentSpan) []string {
+	texts := make([]string, 0, len(spans))
+	for _, span := range spans {
+		texts = append(texts, string(content[span.start:span.end]))
+	}
+	return texts
+}
@@ -22,32 +22,92 @@ const (
 )
 
 var (
-	quotedPhrasePattern = regexp.MustCompile(`"[^"]+"|'[^']+'`)
-	symbolPattern       = regexp.MustCompile(`[/._]|::|->`)
-	camelSnakePattern   = regexp.MustCompile(`[a-z]+[A-Z][a-zA-Z0-9]*|[a-zA-Z]+_[a-zA-Z0-9_]+`)
-	errorCodePattern    = regexp.MustCompile(`\b(?:[A-Z]{2,}[_-]?\d+|\d+\.\d+\.\d+|v\d+(?:\.\d+)*)\b`)
+	symbolPattern     = regexp.MustCompile(`[/._]|::|->`)
+	camelSnakePattern = regexp.MustCompile(`[a-z]+[A-Z][a-zA-Z0-9]*|[a-zA-Z]+_[a-zA-Z0-9_]+`)
+	errorCodePattern  = regexp.MustCompile(`\b(?:[A-Z]{2,}[_-]?\d+|\d+\.\d+\.\d+|v\d+(?:\.\d+)*)\b`)
 )
 
 // Service orchestrates query embedding, hybrid search, and retrieval observability records.
 type Service struct {
 	cache         cache.Layer
 	embedder      embedding.TextEmbedder
+	highlighter   embedding.TextEmbedder
+	reranker      retrieval.Reranker
+	cursorTTL     time.Duration
+	batchWorkers  int
 	now           func() time.Time
 	defaultTopK   int
 	defaultHybrid float64
 }
 
-func New(cacheLayer cache.Layer, embedder embedding.TextEmbedder) *Service {
-	return &Service{
+// Option configures optional Service collaborators.
+type Option func(*Service)
+
+// WithReranker enables the rerank stage for requests that ask for it.
+func WithReranker(reranker retrieval.Reranker) Option {
+	return func(s *Service) {
+		s.reranker = reranker
+	}
+}
+
+// WithCursorTTL sets how long pagination cursors stay valid after the first page.
+func WithCursorTTL(ttl time.Duration) Option {
+	return func(s *Service) {
+		if ttl > 0 {
+			s.cursorTTL = ttl
+		}
+	}
+}
+
+// WithBatchConcurrency sets how many queries of a batch run at once.
+func WithBatchConcurrency(workers int) Option {
+	return func(s *Service) {
+		if workers > 0 {
+			s.batchWorkers = workers
+		}
+	}
+}
+
+// WithHighlightEmbedder embeds semantic highlight windows with embedder in
+// place of the query embedder, so they bypass a query vector cache.
+func WithHighlightEmbedder(embedder embedding.TextEmbedder) Option {
+	return func(s *Service) {
+		if embedder != nil {
+			s.highlighter = embedder
+		}
+	}
+}
+
+func New(cacheLayer cache.Layer, embedder embedding.TextEmbedder, opts ...Option) *Service {
+	s := &Service{
 		cache:         cacheLayer,
 		embedder:      embedder,
+		highlighter:   embedder,
+		cursorTTL:     retrieval.DefaultCursorTTL,
+		batchWorkers:  retrieval.DefaultBatchConcurrency,
 		now:           func() time.Time { return time.Now().UTC() },
 		defaultTopK:   retrieval.DefaultTopK,
 		defaultHybrid: retrieval.DefaultHybridWeight,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *Service) Retrieve(ctx context.Context, req retrieval.Request) (*retrieval.Response, error) {
+	return s.retrieve(ctx, req, nil)
+}
+
+// queryEmbedding is a query vector embedded ahead of its retrieval.
+type queryEmbedding struct {
+	vector []float32
+	dim    int
+	cached bool
+}
+
+// retrieve runs req, embedding its query unless embedded is given.
+func (s *Service) retrieve(ctx context.Context, req retrieval.Request, embedded *queryEmbedding) (*retrieval.Response, error) {
 	if s.cache == nil {
 		return nil, retrieval.ErrNilRepository
 	}
@@ -59,6 +119,15 @@ func (s *Service) Retrieve(ctx context.Context, req retrieval.Request) (*retriev
 	if err := retrieval.ValidateRequest(req); err != nil {
 		return nil, err
 	}
+	if req.Cursor != "" {
+		return s.retrievePage(ctx, req)
+	}
+	if req.Rerank && s.reranker == nil {
+		return nil, retrieval.ErrRerankerUnavailable
+	}
+	if err := s.applyKnowledgeBaseSettings(ctx, &req); err != nil {
+		return nil, err
+	}
 
 	profileEffective, semanticWeight, autoSignals := resolveProfileAndWeight(req)
 	// Maintain legacy field semantics while adding explicit semantic weight controls.
@@ -69,137 +138,654 @@ func (s *Service) Retrieve(ctx context.Context, req retrieval.Request) (*retriev
 
 	filterPayload := buildFilterPayload(req.Filters)
 	_, err := s.cache.InsertRetrievalRequest(ctx, retrieval.RetrievalRequestRecord{
-		ID:            requestID,
-		KnowledgeBase: req.KnowledgeBaseID,
-		Query:         req.Query,
-		Filters:       filterPayload,
-		TopK:          req.TopK,
-		HybridWeight:  req.HybridWeight,
-		ResultCount:   0,
-		LatencyMS:     0,
-		EmptyResult:   false,
-		CreatedAt:     start,
+		ID:               requestID,
+		KnowledgeBase:    req.KnowledgeBaseID,
+		Query:            req.Query,
+		Filters:          filterPayload,
+		TopK:             req.TopK,
+		HybridWeight:     req.HybridWeight,
+		ResultCount:      0,
+		LatencyMS:        0,
+		EmptyResult:      false,
+		CreatedAt:        start,
+		FusionMethod:     req.Fusion,
+		RetrievalProfile: profileEffective,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	embeddings, dim, err := s.embedder.EmbedTexts(ctx, []string{req.Query})
+	parsedQuery := retrieval.ParseQuery(req.Query)
+	if embedded == nil {
+		embeddings, dim, cached, err := s.embedQuery(ctx, embeddingText(req.Query, parsedQuery))
+		if err != nil {
+			return nil, err
+		}
+		if len(embeddings) == 0 {
+			return nil, fmt.Errorf("embedding service returned no vectors")
+		}
+		embedded = &queryEmbedding{vector: embeddings[0], dim: dim, cached: cached}
+	}
+
+	candidates, err := s.searchCandidates(ctx, req, parsedQuery, embedded.vector, embedded.dim, semanticWeight)
 	if err != nil {
 		return nil, err
 	}
-	if len(embeddings) == 0 {
-		return nil, fmt.Errorf("embedding service returned no vectors")
+	chunkMap := make(map[string]retrieval.ChunkRecord, req.TopK)
+	freshness, err := s.applyFreshness(ctx, req, candidates.merged, chunkMap)
+	if err != nil {
+		return nil, err
+	}
+	merged, thresholdDropped, emptyReason, err := s.applyThresholds(ctx, req, parsedQuery, candidates, chunkMap)
+	if err != nil {
+		return nil, err
+	}
+
+	rerankCandidates := 0
+	if req.Rerank {
+		rerankCandidates, err = s.rerank(ctx, req.Query, merged, req.RerankTopN, chunkMap)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	searchParams := retrieval.SearchParams{
+	var displaced *int
+	if req.DiversitySet {
+		poolSize := diversityPoolSize(req.TopK)
+		if rerankCandidates > 0 {
+			// Rerank scores only exist for the reranked head, so keep MMR on one scale.
+			poolSize = rerankCandidates
+		}
+		count, err := s.diversify(ctx, merged, poolSize, req.TopK, req.Diversity, embedded.dim)
+		if err != nil {
+			return nil, err
+		}
+		displaced = &count
+	}
+
+	// Collapse before truncation so TopK counts distinct documents.
+	var collapsed *int
+	if req.Collapse != "" || req.MaxPerDocument > 0 {
+		perDocument := req.MaxPerDocument
+		if perDocument == 0 {
+			perDocument = 1
+		}
+		var count int
+		merged, count, err = s.collapseByDocument(ctx, merged, perDocument, req.Collapse == retrieval.CollapseDocument)
+		if err != nil {
+			return nil, err
+		}
+		collapsed = &count
+	}
+
+	calibrated, err := s.applyCalibration(ctx, req, merged)
+	if err != nil {
+		return nil, err
+	}
+
+	var nextCursor string
+	if req.PageSize > 0 {
+		if err := s.cache.SaveRetrievalRanking(ctx, requestID, rankedCandidates(merged)); err != nil {
+			return nil, err
+		}
+		if len(merged) > req.PageSize {
+			nextCursor = retrieval.EncodeCursor(requestID, req.PageSize)
+		}
+	}
+
+	if len(merged) > req.TopK {
+		merged = merged[:req.TopK]
+	}
+
+	results, resultRecords, err := s.buildPage(ctx, requestID, merged, 0, chunkMap)
+	if err != nil {
+		return nil, err
+	}
+	if req.Highlight {
+		if err := s.highlight(ctx, req.KnowledgeBaseID, parsedQuery, embedded.vector, results); err != nil {
+			return nil, err
+		}
+	}
+
+	latency := s.now().Sub(start).Milliseconds()
+	emptyResult := len(results) == 0
+	if !emptyResult {
+		emptyReason = ""
+	} else if emptyReason == "" {
+		emptyReason = retrieval.EmptyReasonNoCandidates
+	}
+
+	if err := s.cache.InsertRetrievalResults(ctx, resultRecords); err != nil {
+		return nil, err
+	}
+	if err := s.cache.UpdateRetrievalRequest(ctx, requestID, len(results), latency, emptyResult, emptyReason); err != nil {
+		return nil, err
+	}
+
+	response := &retrieval.Response{
+		RequestID:       requestID,
+		QueryID:         requestID,
+		IndexVersion:    "active-document-versions",
 		KnowledgeBaseID: req.KnowledgeBaseID,
 		Query:           req.Query,
-		QueryVector:     embeddings[0],
-		VectorDimension: dim,
-		DocumentType:    req.Filters.DocumentType,
-		PathPrefix:      normalizePathPrefix(req.Filters.PathPrefix),
-		Source:          req.Filters.Source,
-		TagsFilter:      buildTagsFilter(req.Filters.Tags),
-		CreatedAfter:    req.Filters.CreatedAfter,
-		CreatedBefore:   req.Filters.CreatedBefore,
-		Limit:           candidateLimit(req.TopK),
+		TopK:            req.TopK,
+		HybridWeight:    semanticWeight,
+		ResultCount:     len(results),
+		LatencyMS:       latency,
+		Results:         results,
+		Passages:        results,
+		NextCursor:      nextCursor,
+		EmptyReason:     emptyReason,
 	}
+	if req.Debug {
+		response.Debug = &retrieval.DebugMetadata{
+			RetrievalProfileEffective: profileEffective,
+			SemanticWeightEffective:   semanticWeight,
+			FusionMethod:              req.Fusion,
+			AutoSignalsDetected:       autoSignals,
+			LexicalCandidates:         candidates.lexicalCandidates,
+			SemanticCandidates:        candidates.semanticCandidates,
+			QueryEmbeddingCached:      embedded.cached,
+			RerankerApplied:           rerankCandidates > 0,
+			RerankCandidates:          rerankCandidates,
+			FiltersApplied:            filterPayload,
+			ParsedQuery:               &parsedQuery,
+		}
+		if req.Fusion == retrieval.FusionRRF {
+			response.Debug.RRFK = req.RRFK
+		}
+		if req.DiversitySet {
+			lambda := req.Diversity
+			response.Debug.DiversityLambda = &lambda
+			response.Debug.DiversityDisplaced = displaced
+		}
+		response.Debug.CollapsedChunks = collapsed
+		response.Debug.ThresholdDropped = thresholdDropped
+		response.Debug.CalibrationApplied = calibrated
+		setLexicalScorerDebug(response.Debug, req)
+		if !req.ANN.IsZero() {
+			ann := req.ANN
+			response.Debug.ANN = &ann
+		}
+		response.Debug.Freshness = freshness
+	}
+
+	return response, nil
+}
+
+// applyKnowledgeBaseSettings fills lexical scoring, field weight and freshness
+// options the request left unset from the knowledge base's stored settings, then from the
+// defaults, and attaches the knowledge base's tuned weights to auto-profile
+// requests that do not set a weight. Tuned weights are fitted on linear fusion
+// only, so requests fusing with RRF never get them.
+func (s *Service) applyKnowledgeBaseSettings(ctx context.Context, req *retrieval.Request) error {
+	req.LexicalScorer = strings.ToLower(strings.TrimSpace(req.LexicalScorer))
+	profile := strings.ToLower(strings.TrimSpace(req.RetrievalProfile))
+	fusion := strings.ToLower(strings.TrimSpace(req.Fusion))
+	if fusion == "" {
+		fusion = retrieval.DefaultFusion
+	}
+	usesTunedWeights := (profile == "" || profile == retrieval.RetrievalProfileAuto) &&
+		!req.SemanticWeightSet && !req.HybridWeightSet && fusion == retrieval.FusionLinear
+	if req.LexicalScorer == "" || !req.BM25K1Set || !req.BM25BSet || req.FieldWeights.IsZero() ||
+		req.Freshness.IsZero() || usesTunedWeights {
+		metadata, err := s.cache.GetKnowledgeBaseMetadata(ctx, req.KnowledgeBaseID)
+		if err != nil {
+			return err
+		}
+		settings := retrieval.ParseKnowledgeBaseSettings(metadata)
+		if req.LexicalScorer == "" {
+			req.LexicalScorer = settings.LexicalScorer
+		}
+		if !req.BM25K1Set && settings.BM25K1 != nil {
+			req.BM25K1, req.BM25K1Set = *settings.BM25K1, true
+		}
+		if !req.BM25BSet && settings.BM25B != nil {
+			req.BM25B, req.BM25BSet = *settings.BM25B, true
+		}
+		if req.FieldWeights.IsZero() && settings.FieldWeights != nil {
+			req.FieldWeights = *settings.FieldWeights
+		}
+		if req.Freshness.IsZero() && settings.Freshness != nil {
+			req.Freshness = *settings.Freshness
+		}
+		if usesTunedWeights && len(settings.TunedWeights) > 0 {
+			req.TunedWeights = make(map[string]float64, len(settings.TunedWeights))
+			for class, tuned := range settings.TunedWeights {
+				req.TunedWeights[class] = tuned.Weight
+			}
+		}
+	}
+
+	if req.LexicalScorer == "" {
+		req.LexicalScorer = retrieval.DefaultLexicalScorer
+	}
+	if !req.BM25K1Set {
+		req.BM25K1 = retrieval.DefaultBM25K1
+	}
+	if !req.BM25BSet {
+		req.BM25B = retrieval.DefaultBM25B
+	}
+	if !req.Freshness.IsZero() {
+		req.Freshness = retrieval.NormalizeFreshness(req.Freshness)
+	}
+	return nil
+}
+
+func setLexicalScorerDebug(debug *retrieval.DebugMetadata, req retrieval.Request) {
+	debug.LexicalScorer = req.LexicalScorer
+	if req.LexicalScorer == retrieval.LexicalScorerBM25 {
+		k1, b := req.BM25K1, req.BM25B
+		debug.BM25K1 = &k1
+		debug.BM25B = &b
+	}
+	if req.FieldWeights.Enabled() {
+		weights := req.FieldWeights
+		debug.FieldWeights = &weights
+	}
+}
+
+// candidateSet is the fused, sorted ranking for one knowledge base before truncation.
+type candidateSet struct {
+	merged []mergedScore
+	// similarity is the raw cosine similarity of each semantic candidate.
+	similarity         map[string]float64
+	semanticCandidates int
+	lexicalCandidates  int
+	// params and the raw candidate lists are kept for explaining a ranking.
+	params   retrieval.SearchParams
+	semantic []retrieval.ScoredChunk
+	lexical  []retrieval.ScoredChunk
+}
+
+// buildSearchParams builds the repository search for req. Retrieval and
+// explanations share it so both see the same candidates.
+func buildSearchParams(
+	req retrieval.Request,
+	parsedQuery retrieval.ParsedQuery,
+	queryVector []float32,
+	dim int,
+) retrieval.SearchParams {
+	params := retrieval.SearchParams{
+		KnowledgeBaseID:    req.KnowledgeBaseID,
+		Query:              req.Query,
+		QueryVector:        queryVector,
+		VectorDimension:    dim,
+		DocumentType:       req.Filters.DocumentType,
+		PathPrefix:         normalizePathPrefix(req.Filters.PathPrefix),
+		Source:             req.Filters.Source,
+		TagsFilter:         buildTagsFilter(req.Filters.Tags),
+		CreatedAfter:       req.Filters.CreatedAfter,
+		CreatedBefore:      req.Filters.CreatedBefore,
+		SectionPathPrefix:  normalizeSectionPath(req.Filters.SectionPathPrefix),
+		Frontmatter:        req.Filters.Frontmatter,
+		BreadcrumbContains: req.Filters.BreadcrumbContains,
+		Filter:             req.Filters.Expr,
+		ParsedQuery:        &parsedQuery,
+		LexicalScorer:      req.LexicalScorer,
+		BM25K1:             req.BM25K1,
+		BM25B:              req.BM25B,
+		FieldWeights:       req.FieldWeights,
+		Limit:              candidateLimit(req.TopK),
+		ANN:                req.ANN,
+	}
+	if req.PageSize > 0 {
+		params.Limit = retrieval.MaxPaginatedCandidates
+	}
+	return params
+}
+
+// searchCandidates runs the semantic and lexical searches for req's knowledge
+// base and fuses them into a single ranking.
+func (s *Service) searchCandidates(
+	ctx context.Context,
+	req retrieval.Request,
+	parsedQuery retrieval.ParsedQuery,
+	queryVector []float32,
+	dim int,
+	semanticWeight float64,
+) (candidateSet, error) {
+	searchParams := buildSearchParams(req, parsedQuery, queryVector, dim)
 
 	semantic, err := s.cache.SearchSemantic(ctx, searchParams)
 	if err != nil {
-		return nil, err
+		return candidateSet{}, err
 	}
 	lexical, err := s.cache.SearchLexical(ctx, searchParams)
 	if err != nil {
-		return nil, err
+		return candidateSet{}, err
 	}
 
 	semanticScores := normalizeScores(semantic)
 	lexicalScores := normalizeScores(lexical)
+	semanticRanks := rankPositions(semantic)
+	lexicalRanks := rankPositions(lexical)
 
-	merged := mergeScores(semanticScores, lexicalScores, semanticWeight)
+	var merged []mergedScore
+	switch req.Fusion {
+	case retrieval.FusionRRF:
+		merged = fuseReciprocalRank(semanticRanks, lexicalRanks, semanticScores, lexicalScores, semanticWeight, req.RRFK)
+	default:
+		merged = mergeScores(semanticScores, lexicalScores, semanticWeight)
+	}
+	attachRanks(merged, semanticRanks, lexicalRanks)
 	sortResults(merged)
 
-	if len(merged) > req.TopK {
-		merged = merged[:req.TopK]
+	similarity := make(map[string]float64, len(semantic))
+	for _, item := range semantic {
+		similarity[item.ChunkID] = item.Score
 	}
 
-	chunkIDs := make([]string, 0, len(merged))
-	for _, item := range merged {
-		chunkIDs = append(chunkIDs, item.ChunkID)
+	return candidateSet{
+		merged:             merged,
+		similarity:         similarity,
+		semanticCandidates: len(semantic),
+		lexicalCandidates:  len(lexical),
+		params:             searchParams,
+		semantic:           semantic,
+		lexical:            lexical,
+	}, nil
+}
+
+// retrievePage serves a later page from the ranking stored with the first
+// page's retrieval request instead of searching again.
+func (s *Service) retrievePage(ctx context.Context, req retrieval.Request) (*retrieval.Response, error) {
+	start := s.now()
+	requestID, offset, err := retrieval.DecodeCursor(req.Cursor)
+	if err != nil {
+		return nil, err
 	}
 
-	chunks, err := s.cache.GetChunksWithDocuments(ctx, chunkIDs)
+	ranking, err := s.cache.GetRetrievalRanking(ctx, req.KnowledgeBaseID, requestID)
 	if err != nil {
 		return nil, err
 	}
+	if ranking == nil {
+		return nil, retrieval.ErrInvalidCursor
+	}
+	if start.Sub(ranking.CreatedAt) > s.cursorTTL {
+		return nil, retrieval.ErrCursorExpired
+	}
 
-	chunkMap := make(map[string]retrieval.ChunkRecord, len(chunks))
-	for _, chunk := range chunks {
-		chunkMap[chunk.ChunkID] = chunk
+	pageSize := ranking.TopK
+	if req.PageSize > 0 {
+		pageSize = req.PageSize
+	}
+	if offset > len(ranking.Candidates) {
+		offset = len(ranking.Candidates)
+	}
+	end := offset + pageSize
+	if end > len(ranking.Candidates) {
+		end = len(ranking.Candidates)
+	}
+
+	page := make([]mergedScore, 0, end-offset)
+	for _, candidate := range ranking.Candidates[offset:end] {
+		page = append(page, mergedScore{
+			ChunkID: candidate.ChunkID,
+			Score: retrieval.Score{
+				Semantic:  candidate.Semantic,
+				Lexical:   candidate.Lexical,
+				Rerank:    candidate.Rerank,
+				Freshness: candidate.Freshness,
+				Final:     candidate.Final,
+			},
+			SemanticRank: candidate.SemanticRank,
+			LexicalRank:  candidate.LexicalRank,
+			Siblings:     candidate.Siblings,
+			Confidence:   candidate.Confidence,
+		})
 	}
 
-	results := make([]retrieval.Result, 0, len(merged))
-	resultRecords := make([]retrieval.RetrievalResultRecord, 0, len(merged))
+	results, resultRecords, err := s.buildPage(ctx, requestID, page, offset, map[string]retrieval.ChunkRecord{})
+	if err != nil {
+		return nil, err
+	}
+	if req.Highlight {
+		// Later pages do not search again, so embed the stored query (usually
+		// from cache) for semantic highlights.
+		parsedQuery := retrieval.ParseQuery(ranking.Query)
+		embeddings, _, _, err := s.embedQuery(ctx, embeddingText(ranking.Query, parsedQuery))
+		if err != nil {
+			return nil, err
+		}
+		var queryVector []float32
+		if len(embeddings) > 0 {
+			queryVector = embeddings[0]
+		}
+		if err := s.highlight(ctx, req.KnowledgeBaseID, parsedQuery, queryVector, results); err != nil {
+			return nil, err
+		}
+	}
+	// Page rows keep absolute ranks so they line up with the first page's records.
+	if err := s.cache.InsertRetrievalResults(ctx, resultRecords); err != nil {
+		return nil, err
+	}
 
-	for i, item := range merged {
+	var nextCursor string
+	if end < len(ranking.Candidates) {
+		nextCursor = retrieval.EncodeCursor(requestID, end)
+	}
+
+	return &retrieval.Response{
+		RequestID:       requestID,
+		QueryID:         requestID,
+		IndexVersion:    "active-document-versions",
+		KnowledgeBaseID: req.KnowledgeBaseID,
+		Query:           ranking.Query,
+		TopK:            pageSize,
+		HybridWeight:    ranking.HybridWeight,
+		ResultCount:     len(results),
+		LatencyMS:       s.now().Sub(start).Milliseconds(),
+		Results:         results,
+		Passages:        results,
+		NextCursor:      nextCursor,
+	}, nil
+}
+
+// buildPage hydrates ranked items into results and observability records.
+// rankOffset is added to each position so later pages keep absolute ranks.
+func (s *Service) buildPage(
+	ctx context.Context,
+	requestID string,
+	items []mergedScore,
+	rankOffset int,
+	chunkMap map[string]retrieval.ChunkRecord,
+) ([]retrieval.Result, []retrieval.RetrievalResultRecord, error) {
+	chunkIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		chunkIDs = append(chunkIDs, item.ChunkID)
+	}
+	if err := s.loadChunks(ctx, chunkIDs, chunkMap); err != nil {
+		return nil, nil, err
+	}
+
+	results := make([]retrieval.Result, 0, len(items))
+	resultRecords := make([]retrieval.RetrievalResultRecord, 0, len(items))
+
+	for i, item := range items {
 		chunk, ok := chunkMap[item.ChunkID]
 		if !ok {
 			continue
 		}
 
 		result := buildResult(chunk, item.Score)
+		result.Siblings = item.Siblings
+		result.Confidence = item.Confidence
 		results = append(results, result)
 
 		resultRecords = append(resultRecords, retrieval.RetrievalResultRecord{
 			ID:                 uuid.NewString(),
 			RetrievalRequestID: requestID,
 			ChunkID:            chunk.ChunkID,
-			Rank:               i + 1,
+			Rank:               rankOffset + i + 1,
 			SemanticScore:      item.Score.Semantic,
 			LexicalScore:       item.Score.Lexical,
 			FinalScore:         item.Score.Final,
+			RerankScore:        item.Score.Rerank,
+			SemanticRank:       item.SemanticRank,
+			LexicalRank:        item.LexicalRank,
 			CreatedAt:          s.now(),
 		})
 	}
+	return results, resultRecords, nil
+}
 
-	latency := s.now().Sub(start).Milliseconds()
-	emptyResult := len(results) == 0
+func rankedCandidates(merged []mergedScore) []retrieval.RankedCandidate {
+	candidates := make([]retrieval.RankedCandidate, 0, len(merged))
+	for _, item := range merged {
+		candidates = append(candidates, retrieval.RankedCandidate{
+			ChunkID:      item.ChunkID,
+			Semantic:     item.Score.Semantic,
+			Lexical:      item.Score.Lexical,
+			Rerank:       item.Score.Rerank,
+			Freshness:    item.Score.Freshness,
+			Final:        item.Score.Final,
+			SemanticRank: item.SemanticRank,
+			LexicalRank:  item.LexicalRank,
+			Siblings:     item.Siblings,
+			Confidence:   item.Confidence,
+		})
+	}
+	return candidates
+}
 
-	if err := s.cache.InsertRetrievalResults(ctx, resultRecords); err != nil {
-		return nil, err
+// cachedEmbedder is implemented by embedders that can report cache hits.
+type cachedEmbedder interface {
+	EmbedTextsCached(ctx context.Context, texts []string) ([][]float32, int, []bool, error)
+}
+
+// embeddingText drops search operators and excluded terms from the text sent
+// to the embedder so "-term" does not pull the query vector toward term.
+func embeddingText(query string, parsed retrieval.ParsedQuery) string {
+	if !parsed.HasOperators() {
+		return query
 	}
-	if err := s.cache.UpdateRetrievalRequest(ctx, requestID, len(results), latency, emptyResult); err != nil {
-		return nil, err
+	if text := parsed.PositiveText(); text != "" {
+		return text
 	}
+	return query
+}
 
-	response := &retrieval.Response{
-		RequestID:       requestID,
-		QueryID:         requestID,
-		IndexVersion:    "active-document-versions",
-		KnowledgeBaseID: req.KnowledgeBaseID,
-		Query:           req.Query,
-		TopK:            req.TopK,
-		HybridWeight:    semanticWeight,
-		ResultCount:     len(results),
-		LatencyMS:       latency,
-		Results:         results,
-		Passages:        results,
+// embedQuery embeds the query text and reports whether the vector came from cache.
+func (s *Service) embedQuery(ctx context.Context, query string) ([][]float32, int, bool, error) {
+	vectors, dim, hits, err := s.embedQueries(ctx, []string{query})
+	if err != nil {
+		return nil, 0, false, err
 	}
-	if req.Debug {
-		response.Debug = &retrieval.DebugMetadata{
-			RetrievalProfileEffective: profileEffective,
-			SemanticWeightEffective:   semanticWeight,
-			AutoSignalsDetected:       autoSignals,
-			LexicalCandidates:         len(lexical),
-			SemanticCandidates:        len(semantic),
-			RerankerApplied:           false,
-			FiltersApplied:            filterPayload,
+	return vectors, dim, len(hits) > 0 && hits[0], nil
+}
+
+// embedQueries embeds texts in one embedder call, reporting which vectors
+// came from the query embedding cache.
+func (s *Service) embedQueries(ctx context.Context, texts []string) ([][]float32, int, []bool, error) {
+	if embedder, ok := s.embedder.(cachedEmbedder); ok {
+		return embedder.EmbedTextsCached(ctx, texts)
+	}
+	vectors, dim, err := s.embedder.EmbedTexts(ctx, texts)
+	return vectors, dim, make([]bool, len(texts)), err
+}
+
+// rerank rescores the head of the merged list with the configured reranker and
+// moves the reranked candidates to the front. Their Final becomes the min-max
+// normalised rerank score mapped onto the head's range of fused scores before
+// freshness, plus the candidate's freshness boost, and the head is ordered by
+// it, so Final stays on the fused scale the tail and calibration use and newer
+// versions still win rerank ties. Rerank keeps the raw reranker score.
+func (s *Service) rerank(
+	ctx context.Context,
+	query string,
+	merged []mergedScore,
+	topN int,
+	chunkMap map[string]retrieval.ChunkRecord,
+) (int, error) {
+	if topN > len(merged) {
+		topN = len(merged)
+	}
+	if topN == 0 {
+		return 0, nil
+	}
+
+	head := merged[:topN]
+	chunkIDs := make([]string, 0, len(head))
+	for _, item := range head {
+		chunkIDs = append(chunkIDs, item.ChunkID)
+	}
+	if err := s.loadChunks(ctx, chunkIDs, chunkMap); err != nil {
+		return 0, err
+	}
+
+	candidates := make([]retrieval.RerankCandidate, 0, len(head))
+	for _, item := range head {
+		candidates = append(candidates, retrieval.RerankCandidate{
+			ChunkID: item.ChunkID,
+			Content: chunkMap[item.ChunkID].Content,
+		})
+	}
+
+	scores, err := s.reranker.Rerank(ctx, query, candidates)
+	if err != nil {
+		return 0, fmt.Errorf("rerank: %w", err)
+	}
+	if len(scores) != len(head) {
+		return 0, fmt.Errorf("rerank: expected %d scores, got %d", len(head), len(scores))
+	}
+
+	fused := func(item mergedScore) float64 {
+		if item.Score.Freshness != nil {
+			return item.Score.Final - *item.Score.Freshness
+		}
+		return item.Score.Final
+	}
+	low, high := fused(head[0]), fused(head[0])
+	minRerank, maxRerank := scores[0], scores[0]
+	for i := range head {
+		low = min(low, fused(head[i]))
+		high = max(high, fused(head[i]))
+		minRerank = min(minRerank, scores[i])
+		maxRerank = max(maxRerank, scores[i])
+	}
+	for i := range head {
+		score := scores[i]
+		head[i].Score.Rerank = &score
+		normalized := 1.0
+		if maxRerank > minRerank {
+			normalized = (score - minRerank) / (maxRerank - minRerank)
+		}
+		head[i].Score.Final = low + normalized*(high-low)
+		if head[i].Score.Freshness != nil {
+			head[i].Score.Final += *head[i].Score.Freshness
 		}
 	}
+	sort.SliceStable(head, func(i, j int) bool {
+		if head[i].Score.Final != head[j].Score.Final {
+			return head[i].Score.Final > head[j].Score.Final
+		}
+		return *head[i].Score.Rerank > *head[j].Score.Rerank
+	})
+	return len(head), nil
+}
 
-	return response, nil
+// loadChunks fetches chunk records that are not already present in chunkMap.
+func (s *Service) loadChunks(ctx context.Context, chunkIDs []string, chunkMap map[string]retrieval.ChunkRecord) error {
+	missing := make([]string, 0, len(chunkIDs))
+	for _, id := range chunkIDs {
+		if _, ok := chunkMap[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	chunks, err := s.cache.GetChunksWithDocuments(ctx, missing)
+	if err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		chunkMap[chunk.ChunkID] = chunk
+	}
+	return nil
 }
 
 func (s *Service) Hydrate(ctx context.Context, req retrieval.HydrateRequest) (*retrieval.HydrateResponse, error) {
@@ -243,26 +829,47 @@ func (s *Service) Hydrate(ctx context.Context, req retrieval.HydrateRequest) (*r
 	for _, chunk := range chunkMap {
 		chunks = append(chunks, chunk)
 	}
-	sort.Slice(chunks, func(i, j int) bool {
-		if chunks[i].DocumentPath != chunks[j].DocumentPath {
-			return chunks[i].DocumentPath < chunks[j].DocumentPath
-		}
-		return chunks[i].SequenceNumber < chunks[j].SequenceNumber
-	})
+	sortChunkRecords(chunks)
 
 	results := make([]retrieval.Result, 0, len(chunks))
 	for _, chunk := range chunks {
 		results = append(results, buildResult(chunk, retrieval.Score{}))
 	}
 
-	return &retrieval.HydrateResponse{
+	res := &retrieval.HydrateResponse{
 		KnowledgeBaseID: req.KnowledgeBaseID,
 		ChunkCount:      len(results),
 		Chunks:          results,
-	}, nil
+	}
+	if req.Merge {
+		stitched := stitchChunks(chunks)
+		res.Spans = make([]retrieval.MergedSpan, 0, len(stitched))
+		for _, span := range stitched {
+			res.Spans = append(res.Spans, mergedSpan(span))
+		}
+		spanCount := len(res.Spans)
+		res.SpanCount = &spanCount
+	}
+	return res, nil
+}
+
+// sortChunkRecords orders chunks by document path, version and sequence.
+func sortChunkRecords(chunks []retrieval.ChunkRecord) {
+	sort.Slice(chunks, func(i, j int) bool {
+		if chunks[i].DocumentPath != chunks[j].DocumentPath {
+			return chunks[i].DocumentPath < chunks[j].DocumentPath
+		}
+		if chunks[i].DocumentVersionID != chunks[j].DocumentVersionID {
+			return chunks[i].DocumentVersionID < chunks[j].DocumentVersionID
+		}
+		return chunks[i].SequenceNumber < chunks[j].SequenceNumber
+	})
 }
 
 func applyDefaults(req *retrieval.Request, topK int, weight float64) {
+	if req.PageSize > 0 {
+		req.TopK = req.PageSize
+	}
 	if req.TopK == 0 {
 		req.TopK = topK
 	}
@@ -276,6 +883,19 @@ func applyDefaults(req *retrieval.Request, topK int, weight float64) {
 		req.HybridWeight = req.SemanticWeight
 		req.HybridWeightSet = true
 	}
+	req.Fusion = strings.ToLower(strings.TrimSpace(req.Fusion))
+	if req.Fusion == "" {
+		req.Fusion = retrieval.DefaultFusion
+	}
+	if req.RRFK == 0 && !req.RRFKSet {
+		req.RRFK = retrieval.DefaultRRFK
+	}
+	if req.RerankTopN == 0 {
+		req.RerankTopN = retrieval.DefaultRerankTopN
+		if req.TopK > req.RerankTopN {
+			req.RerankTopN = req.TopK
+		}
+	}
 }
 
 func resolveProfileAndWeight(req retrieval.Request) (string, float64, []string) {
@@ -291,24 +911,15 @@ func resolveProfileAndWeight(req retrieval.Request) (string, float64, []string)
 		return profile, req.HybridWeight, []string{"hybrid_weight_override"}
 	}
 
-	switch profile {
-	case retrieval.RetrievalProfileExact:
-		return profile, 0.2, nil
-	case retrieval.RetrievalProfileBalanced:
-		return profile, 0.5, nil
-	case retrieval.RetrievalProfileSemantic:
-		return profile, 0.8, nil
-	default:
-		autoProfile, signals := classifyAutoProfile(req.Query)
-		switch autoProfile {
-		case retrieval.RetrievalProfileExact:
-			return autoProfile, 0.2, signals
-		case retrieval.RetrievalProfileSemantic:
-			return autoProfile, 0.8, signals
-		default:
-			return retrieval.RetrievalProfileBalanced, 0.5, signals
-		}
+	if weight, ok := retrieval.DefaultProfileWeights[profile]; ok {
+		return profile, weight, nil
+	}
+
+	autoProfile, signals := classifyAutoProfile(req.Query)
+	if weight, ok := req.TunedWeights[autoProfile]; ok {
+		return autoProfile, weight, append(signals, "tuned_semantic_weight")
 	}
+	return autoProfile, retrieval.DefaultProfileWeights[autoProfile], signals
 }
 
 func classifyAutoProfile(query string) (string, []string) {
@@ -319,12 +930,16 @@ func classifyAutoProfile(query string) (string, []string) {
 
 	tokens := strings.Fields(trimmed)
 	lower := strings.ToLower(trimmed)
+	parsed := retrieval.ParseQuery(trimmed)
 	lexicalSignals := make([]string, 0)
 	semanticSignals := make([]string, 0)
 
-	if quotedPhrasePattern.MatchString(trimmed) {
+	if parsed.HasPhrase() {
 		lexicalSignals = append(lexicalSignals, "quoted_phrase")
 	}
+	if parsed.HasOperators() {
+		lexicalSignals = append(lexicalSignals, "search_operators")
+	}
 	if symbolPattern.MatchString(trimmed) {
 		lexicalSignals = append(lexicalSignals, "symbols")
 	}
@@ -385,6 +1000,26 @@ func normalizePathPrefix(prefix *string) *string {
 	return &value
 }
 
+// normalizeSectionPath rewrites a heading path into the " > " separated form
+// the markdown chunker stores as the chunk breadcrumb.
+func normalizeSectionPath(path *string) *string {
+	if path == nil {
+		return nil
+	}
+	parts := strings.Split(*path, ">")
+	titles := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if title := strings.TrimSpace(part); title != "" {
+			titles = append(titles, title)
+		}
+	}
+	if len(titles) == 0 {
+		return nil
+	}
+	value := strings.Join(titles, " > ")
+	return &value
+}
+
 func buildTagsFilter(tags []string) map[string]any {
 	if len(tags) == 0 {
 		return nil
@@ -412,36 +1047,55 @@ func buildFilterPayload(filters retrieval.Filters) map[string]any {
 	if filters.CreatedBefore != nil {
 		payload["created_before"] = filters.CreatedBefore.Format(time.RFC3339)
 	}
+	if filters.SectionPathPrefix != nil {
+		payload["section_path_prefix"] = *filters.SectionPathPrefix
+	}
+	if len(filters.Frontmatter) > 0 {
+		payload["frontmatter"] = filters.Frontmatter
+	}
+	if filters.BreadcrumbContains != nil {
+		payload["breadcrumb_contains"] = *filters.BreadcrumbContains
+	}
+	if filters.Expr != nil {
+		payload["filter"] = filters.Expr
+	}
 	return payload
 }
 
 type mergedScore struct {
-	ChunkID string
-	Score   retrieval.Score
+	ChunkID      string
+	Score        retrieval.Score
+	SemanticRank *int
+	LexicalRank  *int
+	Siblings     []retrieval.Sibling
+	Confidence   *float64
 }
 
 func normalizeScores(items []retrieval.ScoredChunk) map[string]float64 {
 	scores := make(map[string]float64, len(items))
-	var max float64
 	for _, item := range items {
-		value := item.Score
-		if value < 0 {
-			value = 0
-		}
-		scores[item.ChunkID] = value
-		if value > max {
-			max = value
-		}
+		scores[item.ChunkID] = max(item.Score, 0)
 	}
-	if max <= 0 {
+	denominator := normalizationDenominator(items)
+	if denominator <= 0 {
 		return scores
 	}
 	for id, value := range scores {
-		scores[id] = value / max
+		scores[id] = value / denominator
 	}
 	return scores
 }
 
+// normalizationDenominator is the largest non-negative score of a candidate
+// list; normalizeScores divides by it unless it is zero.
+func normalizationDenominator(items []retrieval.ScoredChunk) float64 {
+	var denominator float64
+	for _, item := range items {
+		denominator = max(denominator, item.Score)
+	}
+	return denominator
+}
+
 func mergeScores(semantic map[string]float64, lexical map[string]float64, weight float64) []mergedScore {
 	merged := make([]mergedScore, 0, len(semantic)+len(lexical))
 	seen := map[string]struct{}{}
@@ -474,6 +1128,81 @@ func mergeScores(semantic map[string]float64, lexical map[string]float64, weight
 	return merged
 }
 
+// rankPositions maps each chunk to its 1-based position in a candidate list.
+// Repository searches already return candidates in descending score order.
+func rankPositions(items []retrieval.ScoredChunk) map[string]int {
+	ranks := make(map[string]int, len(items))
+	for i, item := range items {
+		if _, ok := ranks[item.ChunkID]; ok {
+			continue
+		}
+		ranks[item.ChunkID] = i + 1
+	}
+	return ranks
+}
+
+// fuseReciprocalRank merges candidates by rank rather than by score, so badly
+// calibrated lexical scores cannot squash the semantic list (or vice versa).
+// The semantic weight still steers how much each list contributes.
+func fuseReciprocalRank(
+	semanticRanks map[string]int,
+	lexicalRanks map[string]int,
+	semantic map[string]float64,
+	lexical map[string]float64,
+	weight float64,
+	k int,
+) []mergedScore {
+	if k <= 0 {
+		k = retrieval.DefaultRRFK
+	}
+	merged := make([]mergedScore, 0, len(semanticRanks)+len(lexicalRanks))
+	seen := map[string]struct{}{}
+
+	add := func(id string) {
+		if _, ok := seen[id]; ok {
+			return
+		}
+		seen[id] = struct{}{}
+		var final float64
+		if rank, ok := semanticRanks[id]; ok {
+			final += weight / float64(k+rank)
+		}
+		if rank, ok := lexicalRanks[id]; ok {
+			final += (1 - weight) / float64(k+rank)
+		}
+		merged = append(merged, mergedScore{
+			ChunkID: id,
+			Score: retrieval.Score{
+				Semantic: semantic[id],
+				Lexical:  lexical[id],
+				Final:    final,
+			},
+		})
+	}
+
+	for id := range semanticRanks {
+		add(id)
+	}
+	for id := range lexicalRanks {
+		add(id)
+	}
+
+	return merged
+}
+
+func attachRanks(merged []mergedScore, semanticRanks map[string]int, lexicalRanks map[string]int) {
+	for i := range merged {
+		if rank, ok := semanticRanks[merged[i].ChunkID]; ok {
+			value := rank
+			merged[i].SemanticRank = &value
+		}
+		if rank, ok := lexicalRanks[merged[i].ChunkID]; ok {
+			value := rank
+			merged[i].LexicalRank = &value
+		}
+	}
+}
+
 func sortResults(results []mergedScore) {
 	sort.Slice(results, func(i, j int) bool {
 		if results[i].Score.Final != results[j].Score.Final {
@@ -503,6 +1232,7 @@ func buildResult(chunk retrieval.ChunkRecord, score retrieval.Score) retrieval.R
 
 	return retrieval.Result{
 		ChunkID:           chunk.ChunkID,
+		KnowledgeBaseID:   chunk.KnowledgeBaseID,
 		DocumentID:        chunk.DocumentID,
 		DocumentVersionID: chunk.DocumentVersionID,
 		DocumentPath:      chunk.DocumentPath,
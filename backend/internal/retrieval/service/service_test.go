@@ -1,11 +1,169 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"ragtime-backend/internal/retrieval"
+	"ragtime-backend/internal/retrieval/cache"
 )
 
+// fakeLayer serves chunk records from memory; unused Layer methods panic via the nil embed.
+type fakeLayer struct {
+	cache.Layer
+	chunks   map[string]retrieval.ChunkRecord
+	ranking  *retrieval.RetrievalRanking
+	recorded []retrieval.RetrievalResultRecord
+	requests []retrieval.RetrievalRequestRecord
+	semantic map[string][]retrieval.ScoredChunk
+	metadata map[string]map[string]any
+	docs     map[string]string
+
+	calibration  *retrieval.ScoreCalibration
+	emptyReasons []string
+	explained    map[string]*retrieval.ChunkExplanation
+	analytics    []retrieval.AnalyticsQuery
+	feedback     []retrieval.FeedbackSubmission
+	tuning       []retrieval.TuningQuery
+	tuned        map[string]retrieval.TunedWeight
+
+	mu      sync.Mutex
+	lexical []retrieval.SearchParams
+}
+
+func (f *fakeLayer) InsertRetrievalRequest(_ context.Context, req retrieval.RetrievalRequestRecord) (*retrieval.RetrievalRequestRecord, error) {
+	f.requests = append(f.requests, req)
+	return &req, nil
+}
+
+func (f *fakeLayer) UpdateRetrievalRequest(_ context.Context, _ string, _ int, _ int64, _ bool, emptyReason string) error {
+	f.emptyReasons = append(f.emptyReasons, emptyReason)
+	return nil
+}
+
+func (f *fakeLayer) GetScoreCalibration(context.Context, string, string) (*retrieval.ScoreCalibration, error) {
+	return f.calibration, nil
+}
+
+func (f *fakeLayer) SearchSemantic(_ context.Context, params retrieval.SearchParams) ([]retrieval.ScoredChunk, error) {
+	return f.semantic[params.KnowledgeBaseID], nil
+}
+
+func (f *fakeLayer) SearchLexical(_ context.Context, params retrieval.SearchParams) ([]retrieval.ScoredChunk, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lexical = append(f.lexical, params)
+	return nil, nil
+}
+
+func (f *fakeLayer) ExplainChunk(_ context.Context, _ retrieval.SearchParams, chunkID string) (*retrieval.ChunkExplanation, error) {
+	return f.explained[chunkID], nil
+}
+
+// ListTopQueries returns one stat per requested row, recording the query it was given.
+func (f *fakeLayer) ListTopQueries(_ context.Context, query retrieval.AnalyticsQuery) ([]retrieval.QueryStat, error) {
+	f.analytics = append(f.analytics, query)
+	stats := make([]retrieval.QueryStat, query.Limit)
+	for i := range stats {
+		stats[i] = retrieval.QueryStat{Query: fmt.Sprintf("query %d", query.Offset+i), RequestCount: 1}
+	}
+	return stats, nil
+}
+
+func (f *fakeLayer) InsertFeedback(_ context.Context, submission retrieval.FeedbackSubmission) (int, error) {
+	f.feedback = append(f.feedback, submission)
+	return len(submission.Labels), nil
+}
+
+func (f *fakeLayer) ListTuningQueries(context.Context, string, time.Time) ([]retrieval.TuningQuery, error) {
+	return f.tuning, nil
+}
+
+func (f *fakeLayer) SaveTunedWeight(_ context.Context, _ string, profile string, tuned retrieval.TunedWeight, _ bool) (bool, error) {
+	if f.tuned == nil {
+		f.tuned = map[string]retrieval.TunedWeight{}
+	}
+	f.tuned[profile] = tuned
+	return true, nil
+}
+
+func (f *fakeLayer) GetChunkDocumentIDs(context.Context, []string) (map[string]string, error) {
+	return f.docs, nil
+}
+
+func (f *fakeLayer) GetKnowledgeBaseMetadata(_ context.Context, knowledgeBaseID string) (map[string]any, error) {
+	return f.metadata[knowledgeBaseID], nil
+}
+
+// GetLexemes lowercases words and drops a plural "s", standing in for Postgres stemming.
+func (f *fakeLayer) GetLexemes(_ context.Context, _ string, words []string) (map[string][]string, error) {
+	out := make(map[string][]string, len(words))
+	for _, word := range words {
+		out[word] = []string{strings.TrimSuffix(strings.ToLower(word), "s")}
+	}
+	return out, nil
+}
+
+type fixedEmbedder struct{}
+
+func (fixedEmbedder) EmbedTexts(_ context.Context, texts []string) ([][]float32, int, error) {
+	out := make([][]float32, len(texts))
+	for i := range texts {
+		out[i] = []float32{1, 0}
+	}
+	return out, 2, nil
+}
+
+func (f *fakeLayer) GetRetrievalRanking(context.Context, string, string) (*retrieval.RetrievalRanking, error) {
+	return f.ranking, nil
+}
+
+func (f *fakeLayer) InsertRetrievalResults(_ context.Context, results []retrieval.RetrievalResultRecord) error {
+	f.recorded = append(f.recorded, results...)
+	return nil
+}
+
+func (f *fakeLayer) GetChunksWithDocuments(_ context.Context, chunkIDs []string) ([]retrieval.ChunkRecord, error) {
+	out := make([]retrieval.ChunkRecord, 0, len(chunkIDs))
+	for _, id := range chunkIDs {
+		if chunk, ok := f.chunks[id]; ok {
+			out = append(out, chunk)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeLayer) GetChunksWithDocumentsForKB(ctx context.Context, _ string, chunkIDs []string) ([]retrieval.ChunkRecord, error) {
+	return f.GetChunksWithDocuments(ctx, chunkIDs)
+}
+
+func (f *fakeLayer) GetChunksByDocumentVersionRange(_ context.Context, versionID string, start int32, end int32) ([]retrieval.ChunkRecord, error) {
+	var out []retrieval.ChunkRecord
+	for _, chunk := range f.chunks {
+		if chunk.DocumentVersionID == versionID && chunk.SequenceNumber >= start && chunk.SequenceNumber <= end {
+			out = append(out, chunk)
+		}
+	}
+	return out, nil
+}
+
+type stubReranker struct {
+	scores map[string]float64
+}
+
+func (r stubReranker) Rerank(_ context.Context, _ string, candidates []retrieval.RerankCandidate) ([]float64, error) {
+	out := make([]float64, len(candidates))
+	for i, candidate := range candidates {
+		out[i] = r.scores[candidate.ChunkID]
+	}
+	return out, nil
+}
+
 func TestResolveProfileAndWeight_ExplicitProfiles(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -49,8 +207,959 @@ func TestClassifyAutoProfile(t *testing.T) {
 		t.Fatalf("classifyAutoProfile() = %q, want %q", profile, retrieval.RetrievalProfileExact)
 	}
 
+	profile, signals := classifyAutoProfile(`"retry budget" -deprecated`)
+	if profile != retrieval.RetrievalProfileExact || !containsSignal(signals, "search_operators") {
+		t.Fatalf("classifyAutoProfile() = %q %v, want exact with search_operators", profile, signals)
+	}
+
 	profile, _ = classifyAutoProfile("how does chunk activation preserve old active versions during failure")
 	if profile != retrieval.RetrievalProfileSemantic {
 		t.Fatalf("classifyAutoProfile() = %q, want %q", profile, retrieval.RetrievalProfileSemantic)
 	}
 }
+
+func TestFuseReciprocalRank_IgnoresScoreOutliers(t *testing.T) {
+	semantic := []retrieval.ScoredChunk{
+		{ChunkID: "a", Score: 0.91},
+		{ChunkID: "b", Score: 0.90},
+	}
+	lexical := []retrieval.ScoredChunk{
+		{ChunkID: "c", Score: 45.0},
+		{ChunkID: "b", Score: 0.01},
+	}
+
+	semanticRanks := rankPositions(semantic)
+	lexicalRanks := rankPositions(lexical)
+	merged := fuseReciprocalRank(semanticRanks, lexicalRanks, normalizeScores(semantic), normalizeScores(lexical), 0.5, 60)
+	attachRanks(merged, semanticRanks, lexicalRanks)
+	sortResults(merged)
+
+	if len(merged) != 3 {
+		t.Fatalf("fuseReciprocalRank() returned %d results, want 3", len(merged))
+	}
+	if merged[0].ChunkID != "b" {
+		t.Fatalf("fuseReciprocalRank() top = %q, want %q (present in both lists)", merged[0].ChunkID, "b")
+	}
+	if merged[0].SemanticRank == nil || *merged[0].SemanticRank != 2 {
+		t.Fatalf("semantic rank = %v, want 2", merged[0].SemanticRank)
+	}
+	if merged[0].LexicalRank == nil || *merged[0].LexicalRank != 2 {
+		t.Fatalf("lexical rank = %v, want 2", merged[0].LexicalRank)
+	}
+	want := 0.5/62.0 + 0.5/62.0
+	if merged[0].Score.Final != want {
+		t.Fatalf("final score = %v, want %v", merged[0].Score.Final, want)
+	}
+}
+
+func TestRerank_ReordersHeadOnly(t *testing.T) {
+	layer := &fakeLayer{chunks: map[string]retrieval.ChunkRecord{
+		"a": {ChunkID: "a", Content: "alpha"},
+		"b": {ChunkID: "b", Content: "beta"},
+		"c": {ChunkID: "c", Content: "gamma"},
+	}}
+	svc := New(layer, nil, WithReranker(stubReranker{scores: map[string]float64{"a": 0.1, "b": 0.9}}))
+
+	merged := []mergedScore{
+		{ChunkID: "a", Score: retrieval.Score{Final: 0.9}},
+		{ChunkID: "b", Score: retrieval.Score{Final: 0.8}},
+		{ChunkID: "c", Score: retrieval.Score{Final: 0.7}},
+	}
+	chunkMap := map[string]retrieval.ChunkRecord{}
+
+	n, err := svc.rerank(context.Background(), "query", merged, 2, chunkMap)
+	if err != nil {
+		t.Fatalf("rerank() error = %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("rerank() candidates = %d, want 2", n)
+	}
+	got := []string{merged[0].ChunkID, merged[1].ChunkID, merged[2].ChunkID}
+	if got[0] != "b" || got[1] != "a" || got[2] != "c" {
+		t.Fatalf("rerank() order = %v, want [b a c]", got)
+	}
+	if merged[0].Score.Rerank == nil || *merged[0].Score.Rerank != 0.9 {
+		t.Fatalf("rerank() top rerank score = %v, want 0.9", merged[0].Score.Rerank)
+	}
+	if merged[0].Score.Final != 0.9 || merged[1].Score.Final != 0.8 {
+		t.Fatalf("rerank() final scores = %v, %v, want rerank order mapped onto the head's fused range [0.8, 0.9]",
+			merged[0].Score.Final, merged[1].Score.Final)
+	}
+	if merged[1].Score.Final < merged[2].Score.Final {
+		t.Fatalf("rerank() head final %v dropped below tail %v", merged[1].Score.Final, merged[2].Score.Final)
+	}
+	if merged[2].Score.Rerank != nil {
+		t.Fatalf("rerank() scored tail candidate")
+	}
+	if len(chunkMap) != 2 {
+		t.Fatalf("rerank() loaded %d chunks, want 2", len(chunkMap))
+	}
+}
+
+func TestRetrieve_FreshnessBreaksRerankTies(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	layer := &fakeLayer{
+		chunks: map[string]retrieval.ChunkRecord{
+			"old": {ChunkID: "old", Content: "Outage notes.", VersionCreatedAt: now.AddDate(0, 0, -300)},
+			"new": {ChunkID: "new", Content: "Outage notes.", VersionCreatedAt: now.AddDate(0, 0, -1)},
+		},
+		semantic: map[string][]retrieval.ScoredChunk{
+			"kb-1": {
+				{ChunkID: "old", Score: 0.9},
+				{ChunkID: "new", Score: 0.85},
+			},
+		},
+	}
+	svc := New(layer, fixedEmbedder{}, WithReranker(stubReranker{scores: map[string]float64{"old": 0.7, "new": 0.7}}))
+	svc.now = func() time.Time { return now }
+
+	resp, err := svc.Retrieve(context.Background(), retrieval.Request{
+		KnowledgeBaseID:  "kb-1",
+		Query:            "outage",
+		RetrievalProfile: retrieval.RetrievalProfileSemantic,
+		Rerank:           true,
+		Freshness:        retrieval.FreshnessOptions{Decay: retrieval.FreshnessDecayExponential, HalfLifeDays: 10, Weight: 0.2},
+	})
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if len(resp.Results) != 2 || resp.Results[0].ChunkID != "new" {
+		t.Fatalf("Retrieve() results = %+v, want the newer version to win the rerank tie", resp.Results)
+	}
+	top, other := resp.Results[0].Scores, resp.Results[1].Scores
+	if top.Rerank == nil || top.Freshness == nil || other.Freshness == nil {
+		t.Fatalf("scores = %+v, %+v, want rerank and freshness reported", top, other)
+	}
+	if got := top.Final - other.Final; got < *top.Freshness-*other.Freshness-1e-9 || got > *top.Freshness-*other.Freshness+1e-9 {
+		t.Fatalf("final gap = %v, want the freshness gap %v", got, *top.Freshness-*other.Freshness)
+	}
+}
+
+func TestSelectMMR_PenalisesNearDuplicates(t *testing.T) {
+	pool := []mergedScore{
+		{ChunkID: "a", Score: retrieval.Score{Final: 1.0}},
+		{ChunkID: "a-dup", Score: retrieval.Score{Final: 0.95}},
+		{ChunkID: "b", Score: retrieval.Score{Final: 0.8}},
+	}
+	vectors := map[string][]float32{
+		"a":     {1, 0},
+		"a-dup": {0.99, 0.01},
+		"b":     {0, 1},
+	}
+
+	order := selectMMR(pool, vectors, 0.5, 2)
+	got := []string{pool[order[0]].ChunkID, pool[order[1]].ChunkID, pool[order[2]].ChunkID}
+	if got[0] != "a" || got[1] != "b" || got[2] != "a-dup" {
+		t.Fatalf("selectMMR() order = %v, want [a b a-dup]", got)
+	}
+
+	order = selectMMR(pool, vectors, 1, 2)
+	if pool[order[1]].ChunkID != "a-dup" {
+		t.Fatalf("selectMMR() with lambda 1 = %q at rank 2, want a-dup", pool[order[1]].ChunkID)
+	}
+}
+
+func TestRetrievePage_ServesStoredRanking(t *testing.T) {
+	createdAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	layer := &fakeLayer{
+		chunks: map[string]retrieval.ChunkRecord{
+			"a": {ChunkID: "a"}, "b": {ChunkID: "b"}, "c": {ChunkID: "c"},
+		},
+		ranking: &retrieval.RetrievalRanking{
+			RequestID: "req-1",
+			Query:     "activation",
+			TopK:      2,
+			CreatedAt: createdAt,
+			Candidates: []retrieval.RankedCandidate{
+				{ChunkID: "a", Final: 0.9}, {ChunkID: "b", Final: 0.8}, {ChunkID: "c", Final: 0.7},
+			},
+		},
+	}
+	svc := New(layer, nil)
+	svc.now = func() time.Time { return createdAt.Add(time.Minute) }
+
+	resp, err := svc.retrievePage(context.Background(), retrieval.Request{
+		KnowledgeBaseID: "kb-1",
+		Cursor:          retrieval.EncodeCursor("req-1", 2),
+	})
+	if err != nil {
+		t.Fatalf("retrievePage() error = %v", err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].ChunkID != "c" {
+		t.Fatalf("retrievePage() results = %+v, want only c", resp.Results)
+	}
+	if resp.NextCursor != "" {
+		t.Fatalf("retrievePage() next cursor = %q, want none on last page", resp.NextCursor)
+	}
+	if len(layer.recorded) != 1 || layer.recorded[0].Rank != 3 {
+		t.Fatalf("retrievePage() recorded = %+v, want absolute rank 3", layer.recorded)
+	}
+
+	svc.now = func() time.Time { return createdAt.Add(retrieval.DefaultCursorTTL + time.Second) }
+	_, err = svc.retrievePage(context.Background(), retrieval.Request{
+		KnowledgeBaseID: "kb-1",
+		Cursor:          retrieval.EncodeCursor("req-1", 2),
+	})
+	if !errors.Is(err, retrieval.ErrCursorExpired) {
+		t.Fatalf("retrievePage() error = %v, want ErrCursorExpired", err)
+	}
+}
+
+func TestRetrieveFederated_WeightsAndTagsKnowledgeBases(t *testing.T) {
+	layer := &fakeLayer{
+		chunks: map[string]retrieval.ChunkRecord{
+			"docs-1":  {ChunkID: "docs-1", KnowledgeBaseID: "kb-docs"},
+			"runbk-1": {ChunkID: "runbk-1", KnowledgeBaseID: "kb-runbooks"},
+		},
+		semantic: map[string][]retrieval.ScoredChunk{
+			"kb-docs":     {{ChunkID: "docs-1", Score: 0.9}},
+			"kb-runbooks": {{ChunkID: "runbk-1", Score: 0.5}},
+		},
+	}
+	svc := New(layer, fixedEmbedder{})
+
+	resp, err := svc.RetrieveFederated(context.Background(), retrieval.FederatedRequest{
+		Request: retrieval.Request{Query: "restart the worker", RetrievalProfile: retrieval.RetrievalProfileSemantic},
+		Targets: []retrieval.FederatedTarget{
+			{KnowledgeBaseID: "kb-docs", Weight: 0.5},
+			{KnowledgeBaseID: "kb-runbooks", Weight: 2},
+		},
+	})
+	if err != nil {
+		t.Fatalf("RetrieveFederated() error = %v", err)
+	}
+	if len(resp.Results) != 2 || resp.Results[0].KnowledgeBaseID != "kb-runbooks" {
+		t.Fatalf("RetrieveFederated() results = %+v, want runbook result weighted first", resp.Results)
+	}
+	if len(layer.requests) != 2 {
+		t.Fatalf("RetrieveFederated() logged %d requests, want one per KB", len(layer.requests))
+	}
+	for _, logged := range layer.requests {
+		if logged.FederatedRequestID == nil || *logged.FederatedRequestID != resp.FederatedRequestID {
+			t.Fatalf("logged request %+v not linked to federated id %q", logged, resp.FederatedRequestID)
+		}
+	}
+	for _, record := range layer.recorded {
+		if record.RetrievalRequestID == "" {
+			t.Fatalf("result record %+v missing per-KB request id", record)
+		}
+	}
+}
+
+func TestRetrieve_RejectsExplicitZeroRRFK(t *testing.T) {
+	svc := New(&fakeLayer{}, fixedEmbedder{})
+
+	_, err := svc.Retrieve(context.Background(), retrieval.Request{
+		KnowledgeBaseID: "kb-1",
+		Query:           "restart the worker",
+		Fusion:          retrieval.FusionRRF,
+		RRFKSet:         true,
+	})
+	if !errors.Is(err, retrieval.ErrInvalidRRFK) {
+		t.Fatalf("Retrieve() error = %v, want ErrInvalidRRFK", err)
+	}
+
+	if _, err := svc.Retrieve(context.Background(), retrieval.Request{
+		KnowledgeBaseID: "kb-1",
+		Query:           "restart the worker",
+		Fusion:          retrieval.FusionRRF,
+	}); err != nil {
+		t.Fatalf("Retrieve() with default rrf_k error = %v", err)
+	}
+}
+
+func TestRetrieveFederated_RejectsZeroWeight(t *testing.T) {
+	svc := New(&fakeLayer{}, fixedEmbedder{})
+
+	_, err := svc.RetrieveFederated(context.Background(), retrieval.FederatedRequest{
+		Request: retrieval.Request{Query: "restart the worker"},
+		Targets: []retrieval.FederatedTarget{
+			{KnowledgeBaseID: "kb-docs", Weight: 1},
+			{KnowledgeBaseID: "kb-runbooks", Weight: 0},
+		},
+	})
+	if !errors.Is(err, retrieval.ErrInvalidKBWeight) {
+		t.Fatalf("RetrieveFederated() error = %v, want ErrInvalidKBWeight", err)
+	}
+}
+
+func TestRetrieve_LexicalScorerFromKnowledgeBaseSettings(t *testing.T) {
+	layer := &fakeLayer{
+		metadata: map[string]map[string]any{
+			"kb-1": {retrieval.SettingLexicalScorer: "bm25", retrieval.SettingBM25B: 0.3},
+		},
+	}
+	svc := New(layer, fixedEmbedder{})
+
+	resp, err := svc.Retrieve(context.Background(), retrieval.Request{
+		KnowledgeBaseID: "kb-1",
+		Query:           "connection pool",
+		BM25K1:          2,
+		BM25K1Set:       true,
+		Debug:           true,
+	})
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if len(layer.lexical) != 1 {
+		t.Fatalf("SearchLexical called %d times, want 1", len(layer.lexical))
+	}
+	params := layer.lexical[0]
+	if params.LexicalScorer != retrieval.LexicalScorerBM25 || params.BM25K1 != 2 || params.BM25B != 0.3 {
+		t.Fatalf("SearchLexical params = %+v, want bm25 with request k1 and KB b", params)
+	}
+	if resp.Debug.LexicalScorer != retrieval.LexicalScorerBM25 || resp.Debug.BM25B == nil || *resp.Debug.BM25B != 0.3 {
+		t.Fatalf("debug = %+v, want bm25 settings", resp.Debug)
+	}
+
+	layer.lexical = nil
+	if _, err := svc.Retrieve(context.Background(), retrieval.Request{
+		KnowledgeBaseID: "kb-2",
+		Query:           "connection pool",
+	}); err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if got := layer.lexical[0].LexicalScorer; got != retrieval.DefaultLexicalScorer {
+		t.Fatalf("LexicalScorer = %q, want default %q", got, retrieval.DefaultLexicalScorer)
+	}
+}
+
+func containsSignal(signals []string, want string) bool {
+	for _, signal := range signals {
+		if signal == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRetrieve_CollapseByDocumentBeforeTruncation(t *testing.T) {
+	layer := &fakeLayer{
+		chunks: map[string]retrieval.ChunkRecord{
+			"a1": {ChunkID: "a1", DocumentID: "doc-a"},
+			"a2": {ChunkID: "a2", DocumentID: "doc-a"},
+			"b1": {ChunkID: "b1", DocumentID: "doc-b"},
+			"c1": {ChunkID: "c1", DocumentID: "doc-c"},
+		},
+		semantic: map[string][]retrieval.ScoredChunk{
+			"kb-1": {
+				{ChunkID: "a1", Score: 0.9},
+				{ChunkID: "a2", Score: 0.8},
+				{ChunkID: "b1", Score: 0.7},
+				{ChunkID: "c1", Score: 0.6},
+			},
+		},
+		docs: map[string]string{"a1": "doc-a", "a2": "doc-a", "b1": "doc-b", "c1": "doc-c"},
+	}
+	svc := New(layer, fixedEmbedder{})
+
+	resp, err := svc.Retrieve(context.Background(), retrieval.Request{
+		KnowledgeBaseID:  "kb-1",
+		Query:            "rotate credentials",
+		TopK:             2,
+		RetrievalProfile: retrieval.RetrievalProfileSemantic,
+		Collapse:         retrieval.CollapseDocument,
+	})
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if len(resp.Results) != 2 || resp.Results[0].ChunkID != "a1" || resp.Results[1].ChunkID != "b1" {
+		t.Fatalf("Retrieve() results = %+v, want a1 then b1", resp.Results)
+	}
+	siblings := resp.Results[0].Siblings
+	if len(siblings) != 1 || siblings[0].ChunkID != "a2" {
+		t.Fatalf("a1 siblings = %+v, want a2 folded in", siblings)
+	}
+	if resp.Results[1].Siblings != nil {
+		t.Fatalf("b1 siblings = %+v, want none", resp.Results[1].Siblings)
+	}
+}
+
+// keywordEmbedder points texts containing keyword along the first axis and
+// everything else along the second.
+type keywordEmbedder struct {
+	keyword string
+}
+
+func (e keywordEmbedder) EmbedTexts(_ context.Context, texts []string) ([][]float32, int, error) {
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = []float32{0, 1}
+		if strings.Contains(text, e.keyword) {
+			out[i] = []float32{1, 0}
+		}
+	}
+	return out, 2, nil
+}
+
+func TestHighlight_LexicalMatchesUseRuneOffsets(t *testing.T) {
+	svc := New(&fakeLayer{}, fixedEmbedder{})
+	results := []retrieval.Result{{Content: "Über Konfiguration: Caches expire. Nothing else here."}}
+
+	err := svc.highlight(context.Background(), "kb-1", retrieval.ParseQuery("cache -nothing"), []float32{1, 0}, results)
+	if err != nil {
+		t.Fatalf("highlight() error = %v", err)
+	}
+
+	if len(results[0].Highlights) != 1 {
+		t.Fatalf("highlights = %+v, want one snippet", results[0].Highlights)
+	}
+	snippet := results[0].Highlights[0]
+	if snippet.Source != retrieval.HighlightLexical || len(snippet.Matches) != 1 {
+		t.Fatalf("snippet = %+v, want one lexical match", snippet)
+	}
+	match := snippet.Matches[0]
+	content := []rune(results[0].Content)
+	if match.Lexeme != "cache" || string(content[match.StartRune:match.EndRune]) != "Caches" {
+		t.Fatalf("match = %+v, want lexeme cache over Caches", match)
+	}
+	if string(content[snippet.StartRune:snippet.EndRune]) != snippet.Text {
+		t.Fatalf("snippet offsets [%d,%d) do not cover %q", snippet.StartRune, snippet.EndRune, snippet.Text)
+	}
+}
+
+func TestHighlight_SemanticOnlyHitGetsBestSentenceWindow(t *testing.T) {
+	svc := New(&fakeLayer{}, keywordEmbedder{keyword: "refund"})
+	content := strings.Repeat("Shipping takes five days. ", 8) + "A refund is issued within a week."
+	results := []retrieval.Result{{Content: content}}
+
+	err := svc.highlight(context.Background(), "kb-1", retrieval.ParseQuery("money back"), []float32{1, 0}, results)
+	if err != nil {
+		t.Fatalf("highlight() error = %v", err)
+	}
+
+	if len(results[0].Highlights) != 1 {
+		t.Fatalf("highlights = %+v, want one snippet", results[0].Highlights)
+	}
+	snippet := results[0].Highlights[0]
+	if snippet.Source != retrieval.HighlightSemantic || snippet.Score == nil || *snippet.Score != 1 {
+		t.Fatalf("snippet = %+v, want semantic window scored 1", snippet)
+	}
+	if !strings.Contains(snippet.Text, "refund") || string([]rune(content)[snippet.StartRune:snippet.EndRune]) != snippet.Text {
+		t.Fatalf("snippet = %+v, want the refund window at its content offsets", snippet)
+	}
+}
+
+func TestHighlight_SemanticWindowsUseHighlightEmbedder(t *testing.T) {
+	queries := &recordingEmbedder{}
+	svc := New(&fakeLayer{}, queries, WithHighlightEmbedder(keywordEmbedder{keyword: "refund"}))
+	content := strings.Repeat("Shipping takes five days. ", 8) + "A refund is issued within a week."
+	results := []retrieval.Result{{Content: content}}
+
+	err := svc.highlight(context.Background(), "kb-1", retrieval.ParseQuery("money back"), []float32{1, 0}, results)
+	if err != nil {
+		t.Fatalf("highlight() error = %v", err)
+	}
+
+	if len(queries.calls) != 0 {
+		t.Fatalf("query embedder calls = %v, want none", queries.calls)
+	}
+	if len(results[0].Highlights) != 1 || !strings.Contains(results[0].Highlights[0].Text, "refund") {
+		t.Fatalf("highlights = %+v, want the refund window", results[0].Highlights)
+	}
+}
+
+func TestRetrieve_ThresholdsDropWeakCandidates(t *testing.T) {
+	layer := &fakeLayer{
+		chunks: map[string]retrieval.ChunkRecord{
+			"strong": {ChunkID: "strong", Content: "Rotate credentials every week."},
+			"weak":   {ChunkID: "weak", Content: "Rotate the log files."},
+			"far":    {ChunkID: "far", Content: "Credentials live in the vault."},
+		},
+		semantic: map[string][]retrieval.ScoredChunk{
+			"kb-1": {
+				{ChunkID: "strong", Score: 0.82},
+				{ChunkID: "weak", Score: 0.7},
+				{ChunkID: "far", Score: 0.3},
+			},
+		},
+		calibration: &retrieval.ScoreCalibration{Slope: 4, Intercept: -2},
+	}
+	svc := New(layer, fixedEmbedder{})
+
+	resp, err := svc.Retrieve(context.Background(), retrieval.Request{
+		KnowledgeBaseID:     "kb-1",
+		Query:               "rotate credentials",
+		RetrievalProfile:    retrieval.RetrievalProfileSemantic,
+		MinSemanticScore:    0.5,
+		MinSemanticScoreSet: true,
+		MinLexicalHits:      2,
+		Debug:               true,
+	})
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].ChunkID != "strong" {
+		t.Fatalf("Retrieve() results = %+v, want only strong", resp.Results)
+	}
+	dropped := resp.Debug.ThresholdDropped
+	if dropped[retrieval.EmptyReasonMinSemanticScore] != 1 || dropped[retrieval.EmptyReasonMinLexicalHits] != 1 {
+		t.Fatalf("ThresholdDropped = %v, want one semantic and one lexical drop", dropped)
+	}
+	confidence := resp.Results[0].Confidence
+	want := layer.calibration.Confidence(resp.Results[0].Score)
+	if confidence == nil || *confidence != want || !resp.Debug.CalibrationApplied {
+		t.Fatalf("Confidence = %v, want calibrated %v", confidence, want)
+	}
+
+	resp, err = svc.Retrieve(context.Background(), retrieval.Request{
+		KnowledgeBaseID:  "kb-1",
+		Query:            "rotate credentials",
+		RetrievalProfile: retrieval.RetrievalProfileSemantic,
+		MinFinalScore:    1,
+		MinFinalScoreSet: true,
+	})
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if len(resp.Results) != 0 || resp.EmptyReason != retrieval.EmptyReasonMinFinalScore {
+		t.Fatalf("Retrieve() = %d results, reason %q; want empty for min_final_score", len(resp.Results), resp.EmptyReason)
+	}
+	if last := layer.emptyReasons[len(layer.emptyReasons)-1]; last != retrieval.EmptyReasonMinFinalScore {
+		t.Fatalf("recorded empty reason = %q, want %q", last, retrieval.EmptyReasonMinFinalScore)
+	}
+}
+
+func TestExplain_ReportsRanksAndMissingReasons(t *testing.T) {
+	distance := 0.4
+	layer := &fakeLayer{
+		semantic: map[string][]retrieval.ScoredChunk{
+			"kb-1": {
+				{ChunkID: "top", Score: 0.8},
+				{ChunkID: "second", Score: 0.4},
+			},
+		},
+		explained: map[string]*retrieval.ChunkExplanation{
+			"second": {
+				ChunkID: "second", InKnowledgeBase: true, ActiveVersion: true, PassesFilters: true,
+				CosineDistance: &distance, TSRank: 0,
+			},
+			"stale": {ChunkID: "stale", InKnowledgeBase: true, PassesFilters: true},
+		},
+	}
+	svc := New(layer, fixedEmbedder{})
+
+	explanation, err := svc.Explain(context.Background(), retrieval.ExplainRequest{
+		Request: retrieval.Request{
+			KnowledgeBaseID:  "kb-1",
+			Query:            "rotate credentials",
+			RetrievalProfile: retrieval.RetrievalProfileSemantic,
+		},
+		ChunkID: "second",
+	})
+	if err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+	semantic := explanation.Semantic
+	if semantic.Rank == nil || *semantic.Rank != 2 || semantic.NormalizationDenominator != 0.8 {
+		t.Fatalf("Semantic = %+v, want rank 2 with denominator 0.8", semantic)
+	}
+	if semantic.NormalizedScore == nil || *semantic.NormalizedScore != 0.5 || semantic.CosineDistance == nil {
+		t.Fatalf("Semantic = %+v, want normalized 0.5 and a cosine distance", semantic)
+	}
+	if explanation.Lexical.NotCandidateReason != retrieval.NotCandidateNoLexicalMatch {
+		t.Fatalf("Lexical reason = %q, want %q", explanation.Lexical.NotCandidateReason, retrieval.NotCandidateNoLexicalMatch)
+	}
+	if !explanation.Candidate || explanation.FusedRank == nil || *explanation.FusedRank != 2 {
+		t.Fatalf("Explain() fused = %v rank %v, want candidate at rank 2", explanation.Candidate, explanation.FusedRank)
+	}
+
+	explanation, err = svc.Explain(context.Background(), retrieval.ExplainRequest{
+		Request: retrieval.Request{KnowledgeBaseID: "kb-1", Query: "rotate credentials"},
+		ChunkID: "stale",
+	})
+	if err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+	if explanation.Candidate || explanation.Semantic.NotCandidateReason != retrieval.NotCandidateInactiveVersion {
+		t.Fatalf("Explain() = candidate %v, reason %q; want inactive_version", explanation.Candidate, explanation.Semantic.NotCandidateReason)
+	}
+}
+
+func TestTopQueries_DefaultsRangeAndPaginates(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	layer := &fakeLayer{}
+	svc := New(layer, fixedEmbedder{})
+	svc.now = func() time.Time { return now }
+
+	page, err := svc.TopQueries(context.Background(), retrieval.AnalyticsQuery{
+		KnowledgeBaseID: "kb-1",
+		Limit:           2,
+		Offset:          4,
+	})
+	if err != nil {
+		t.Fatalf("TopQueries() error = %v", err)
+	}
+	asked := layer.analytics[0]
+	if !asked.To.Equal(now) || !asked.From.Equal(now.Add(-retrieval.DefaultAnalyticsWindow)) || asked.Limit != 3 {
+		t.Fatalf("store query = %+v, want default window and one lookahead row", asked)
+	}
+	if len(page.Items) != 2 || page.NextOffset == nil || *page.NextOffset != 6 {
+		t.Fatalf("TopQueries() = %d items, next %v; want 2 items and next offset 6", len(page.Items), page.NextOffset)
+	}
+
+	_, err = svc.TopQueries(context.Background(), retrieval.AnalyticsQuery{
+		KnowledgeBaseID: "kb-1",
+		From:            now,
+		To:              now.Add(-time.Hour),
+	})
+	if !errors.Is(err, retrieval.ErrInvalidAnalyticsRange) {
+		t.Fatalf("TopQueries() error = %v, want %v", err, retrieval.ErrInvalidAnalyticsRange)
+	}
+}
+
+func TestSubmitFeedback_NormalizesAndValidatesLabels(t *testing.T) {
+	layer := &fakeLayer{}
+	svc := New(layer, fixedEmbedder{})
+	grade := 3
+
+	stored, err := svc.SubmitFeedback(context.Background(), retrieval.FeedbackSubmission{
+		KnowledgeBaseID: "kb-1",
+		QueryID:         " query-1 ",
+		Labels: []retrieval.FeedbackLabel{
+			{ChunkID: "chunk-1", Thumbs: " UP ", Grade: &grade},
+			{ChunkID: "chunk-2", Cited: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SubmitFeedback() error = %v", err)
+	}
+	got := layer.feedback[0]
+	if stored != 2 || got.QueryID != "query-1" || got.Labels[0].Thumbs != retrieval.FeedbackThumbsUp {
+		t.Fatalf("SubmitFeedback() stored %d of %+v, want 2 normalized labels", stored, got)
+	}
+
+	invalid := 4
+	cases := map[error]retrieval.FeedbackLabel{
+		retrieval.ErrInvalidGrade:    {ChunkID: "chunk-1", Grade: &invalid},
+		retrieval.ErrInvalidThumbs:   {ChunkID: "chunk-1", Thumbs: "sideways"},
+		retrieval.ErrInvalidFeedback: {ChunkID: "chunk-1"},
+	}
+	for want, label := range cases {
+		_, err := svc.SubmitFeedback(context.Background(), retrieval.FeedbackSubmission{
+			KnowledgeBaseID: "kb-1",
+			QueryID:         "query-1",
+			Labels:          []retrieval.FeedbackLabel{label},
+		})
+		if !errors.Is(err, want) {
+			t.Fatalf("SubmitFeedback(%+v) error = %v, want %v", label, err, want)
+		}
+	}
+}
+
+func TestTuneWeights_FitsUnpinnedClassesFromLabels(t *testing.T) {
+	// Every labelled semantic-class query prefers the lexical match, which
+	// only outranks the pure semantic match below a weight of 0.625.
+	queries := make([]retrieval.TuningQuery, retrieval.MinTuningQueries)
+	for i := range queries {
+		queries[i] = retrieval.TuningQuery{
+			RequestID: fmt.Sprintf("request-%d", i),
+			Query:     "how does chunk activation preserve old active versions during failure",
+			Results: []retrieval.TuningResult{
+				{SemanticScore: 1, LexicalScore: 0, Relevance: 0},
+				{SemanticScore: 0.4, LexicalScore: 1, Relevance: 3},
+			},
+		}
+	}
+	layer := &fakeLayer{
+		tuning: queries,
+		metadata: map[string]map[string]any{"kb-1": {
+			retrieval.SettingTunedSemanticWeights: map[string]any{
+				retrieval.RetrievalProfileExact: map[string]any{"weight": 0.3, "pinned": true},
+			},
+		}},
+	}
+	svc := New(layer, fixedEmbedder{})
+
+	run, err := svc.TuneWeights(context.Background(), "kb-1", "")
+	if err != nil {
+		t.Fatalf("TuneWeights() error = %v", err)
+	}
+	tuned, ok := run.Tuned[retrieval.RetrievalProfileSemantic]
+	if !ok || tuned.Weight != 0.6 || tuned.Metric != retrieval.TuningMetricNDCG || tuned.Queries != len(queries) {
+		t.Fatalf("TuneWeights() semantic = %+v, want 0.6 (nearest optimum to the 0.8 default) by ndcg", tuned)
+	}
+	if *tuned.Score != 1 || *tuned.Baseline >= 1 {
+		t.Fatalf("TuneWeights() score %v baseline %v, want perfect score over a worse baseline", *tuned.Score, *tuned.Baseline)
+	}
+	if run.Skipped[retrieval.RetrievalProfileExact] != retrieval.TuningSkippedPinned ||
+		run.Skipped[retrieval.RetrievalProfileBalanced] != retrieval.TuningSkippedInsufficient {
+		t.Fatalf("TuneWeights() skipped = %v, want pinned exact and unlabelled balanced", run.Skipped)
+	}
+	if _, err := svc.TuneWeights(context.Background(), "kb-1", "precision"); !errors.Is(err, retrieval.ErrInvalidTuningMetric) {
+		t.Fatalf("TuneWeights(precision) error = %v, want ErrInvalidTuningMetric", err)
+	}
+
+	req := retrieval.Request{KnowledgeBaseID: "kb-1", Query: queries[0].Query}
+	if err := svc.applyKnowledgeBaseSettings(context.Background(), &req); err != nil {
+		t.Fatalf("applyKnowledgeBaseSettings() error = %v", err)
+	}
+	if req.TunedWeights[retrieval.RetrievalProfileExact] != 0.3 {
+		t.Fatalf("applyKnowledgeBaseSettings() tuned weights = %v, want pinned exact weight", req.TunedWeights)
+	}
+	rrf := retrieval.Request{KnowledgeBaseID: "kb-1", Query: queries[0].Query, Fusion: retrieval.FusionRRF}
+	if err := svc.applyKnowledgeBaseSettings(context.Background(), &rrf); err != nil {
+		t.Fatalf("applyKnowledgeBaseSettings(rrf) error = %v", err)
+	}
+	if rrf.TunedWeights != nil {
+		t.Fatalf("applyKnowledgeBaseSettings(rrf) tuned weights = %v, want none: they were fitted on linear fusion", rrf.TunedWeights)
+	}
+	// The fake layer does not write saved weights back into the metadata.
+	req.TunedWeights[retrieval.RetrievalProfileSemantic] = tuned.Weight
+	profile, weight, signals := resolveProfileAndWeight(req)
+	if profile != retrieval.RetrievalProfileSemantic || weight != 0.6 || !containsSignal(signals, "tuned_semantic_weight") {
+		t.Fatalf("resolveProfileAndWeight() = %q %v %v, want tuned semantic weight", profile, weight, signals)
+	}
+
+	req.SemanticWeight, req.SemanticWeightSet = 0.9, true
+	if _, weight, _ := resolveProfileAndWeight(req); weight != 0.9 {
+		t.Fatalf("resolveProfileAndWeight() weight = %v, want caller override 0.9", weight)
+	}
+}
+
+func TestPackContext_StitchesOverlappingChunksAndMapsCitations(t *testing.T) {
+	chunk := func(id, version, path string, seq int32, content string, start, end int) contextChunk {
+		return contextChunk{record: retrieval.ChunkRecord{
+			ChunkID:           id,
+			DocumentID:        "doc-" + version,
+			DocumentVersionID: version,
+			DocumentPath:      path,
+			SequenceNumber:    seq,
+			Content:           content,
+			Metadata:          map[string]any{"start_rune": float64(start), "end_rune": float64(end)},
+		}, retrieved: id == "a2"}
+	}
+	// Document a is "alpha beta gamma delta" split with overlap; document b
+	// records offsets per chunk, so its chunks are never stitched.
+	text, sources, citations := packContext(map[string]contextChunk{
+		"a1": chunk("a1", "va", "a.md", 1, "alpha beta", 0, 10),
+		"a2": chunk("a2", "va", "a.md", 2, "beta gamma", 6, 16),
+		"a3": chunk("a3", "va", "a.md", 3, "delta", 17, 22),
+		"b1": chunk("b1", "vb", "b.md", 1, "first", 0, 5),
+		"b2": chunk("b2", "vb", "b.md", 2, "second", 0, 6),
+	})
+
+	want := "[1] a.md\nalpha beta gamma\n\ndelta\n\n[2] b.md\nfirst\n\nsecond"
+	if text != want {
+		t.Fatalf("packContext() text = %q, want %q", text, want)
+	}
+	if len(sources) != 2 || sources[1].DocumentPath != "b.md" || sources[1].Index != 2 {
+		t.Fatalf("packContext() sources = %+v, want a.md then b.md", sources)
+	}
+	runes := []rune(text)
+	wantSpans := map[string]string{"a1": "alpha beta", "a2": "beta gamma", "a3": "delta", "b1": "first", "b2": "second"}
+	for _, citation := range citations {
+		if got := string(runes[citation.StartRune:citation.EndRune]); got != wantSpans[citation.ChunkID] {
+			t.Fatalf("citation %s spans %q, want %q", citation.ChunkID, got, wantSpans[citation.ChunkID])
+		}
+		if citation.Retrieved != (citation.ChunkID == "a2") {
+			t.Fatalf("citation %s retrieved = %v", citation.ChunkID, citation.Retrieved)
+		}
+	}
+}
+
+func TestHydrate_MergeStitchesOverlappingNeighbours(t *testing.T) {
+	record := func(id string, seq int32, content string, start int) retrieval.ChunkRecord {
+		return retrieval.ChunkRecord{
+			ChunkID:           id,
+			DocumentID:        "doc-1",
+			DocumentVersionID: "version-1",
+			DocumentPath:      "guide.md",
+			SequenceNumber:    seq,
+			Content:           content,
+			Metadata:          map[string]any{"start_rune": start, "end_rune": start + len([]rune(content))},
+		}
+	}
+	// "one two three four five" chunked with a four-rune overlap; chunk-4
+	// starts past chunk-3's end and opens a new span.
+	layer := &fakeLayer{chunks: map[string]retrieval.ChunkRecord{
+		"chunk-1": record("chunk-1", 1, "one two ", 0),
+		"chunk-2": record("chunk-2", 2, "two three", 4),
+		"chunk-3": record("chunk-3", 3, "three four", 8),
+		"chunk-4": record("chunk-4", 4, "five", 19),
+	}}
+	svc := New(layer, fixedEmbedder{})
+
+	res, err := svc.Hydrate(context.Background(), retrieval.HydrateRequest{
+		KnowledgeBaseID: "kb-1",
+		ChunkIDs:        []string{"chunk-2"},
+		AdjacentBefore:  1,
+		AdjacentAfter:   2,
+		Merge:           true,
+	})
+	if err != nil {
+		t.Fatalf("Hydrate() error = %v", err)
+	}
+	if res.ChunkCount != 4 || res.SpanCount == nil || *res.SpanCount != 2 {
+		t.Fatalf("Hydrate() = %d chunks in %v spans, want 4 chunks in 2 spans", res.ChunkCount, res.SpanCount)
+	}
+	first := res.Spans[0]
+	if first.Content != "one two three four" || strings.Join(first.ChunkIDs, ",") != "chunk-1,chunk-2,chunk-3" {
+		t.Fatalf("Hydrate() first span = %q %v, want stitched chunks 1-3", first.Content, first.ChunkIDs)
+	}
+	if *first.StartRune != 0 || *first.EndRune != 18 || first.RuneLength != 18 || first.EndSequence != 3 {
+		t.Fatalf("Hydrate() first span offsets = %d-%d (%d runes), want 0-18", *first.StartRune, *first.EndRune, first.RuneLength)
+	}
+	if res.Spans[1].Content != "five" || *res.Spans[1].StartRune != 19 {
+		t.Fatalf("Hydrate() second span = %+v, want chunk-4 alone", res.Spans[1])
+	}
+}
+
+func TestRetrieve_FreshnessBoostFromKnowledgeBaseSettings(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	layer := &fakeLayer{
+		chunks: map[string]retrieval.ChunkRecord{
+			"old":   {ChunkID: "old", VersionCreatedAt: now.AddDate(0, 0, -300)},
+			"new":   {ChunkID: "new", VersionCreatedAt: now.AddDate(0, 0, -300), SourceMetadata: map[string]any{"published_at": "2026-02-19"}},
+			"fresh": {ChunkID: "fresh", VersionCreatedAt: now.AddDate(0, 0, -1)},
+		},
+		semantic: map[string][]retrieval.ScoredChunk{
+			"kb-1": {
+				{ChunkID: "old", Score: 0.9},
+				{ChunkID: "new", Score: 0.88},
+				{ChunkID: "fresh", Score: 0.5},
+			},
+		},
+		metadata: map[string]map[string]any{
+			"kb-1": {retrieval.SettingFreshness: map[string]any{
+				"decay":          "exponential",
+				"half_life_days": 10.0,
+				"weight":         0.2,
+				"date_field":     "published_at",
+			}},
+		},
+	}
+	svc := New(layer, fixedEmbedder{})
+	svc.now = func() time.Time { return now }
+
+	resp, err := svc.Retrieve(context.Background(), retrieval.Request{
+		KnowledgeBaseID:  "kb-1",
+		Query:            "incident timeline",
+		RetrievalProfile: retrieval.RetrievalProfileSemantic,
+		Debug:            true,
+	})
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if len(resp.Results) != 3 || resp.Results[0].ChunkID != "new" || resp.Results[2].ChunkID != "fresh" {
+		t.Fatalf("Retrieve() results = %+v, want the recently published chunk first", resp.Results)
+	}
+	boost := resp.Results[0].Scores.Freshness
+	if boost == nil || *boost < 0.099 || *boost > 0.101 {
+		t.Fatalf("new freshness = %v, want half of 0.2 after one half-life", boost)
+	}
+	debug := resp.Debug.Freshness
+	if debug == nil || debug.Decay != retrieval.FreshnessDecayExponential || debug.Boosted != 3 || debug.DatedByField != 1 {
+		t.Fatalf("debug freshness = %+v, want exponential boost of 3 candidates, 1 dated by field", debug)
+	}
+
+	resp, err = svc.Retrieve(context.Background(), retrieval.Request{
+		KnowledgeBaseID:  "kb-1",
+		Query:            "incident timeline",
+		RetrievalProfile: retrieval.RetrievalProfileSemantic,
+		Freshness:        retrieval.FreshnessOptions{Decay: retrieval.FreshnessDecayNone},
+	})
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if resp.Results[0].ChunkID != "old" || resp.Results[0].Scores.Freshness != nil {
+		t.Fatalf("Retrieve() results = %+v, want the request to turn the boost off", resp.Results)
+	}
+}
+
+func TestRetrieve_FieldWeightsFromKnowledgeBaseSettings(t *testing.T) {
+	layer := &fakeLayer{
+		metadata: map[string]map[string]any{
+			"kb-1": {retrieval.SettingLexicalFieldWeights: map[string]any{"title": 0.8, "path": 0.0}},
+		},
+	}
+	svc := New(layer, fixedEmbedder{})
+
+	resp, err := svc.Retrieve(context.Background(), retrieval.Request{
+		KnowledgeBaseID: "kb-1",
+		Query:           "retry budget",
+		Debug:           true,
+	})
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	want := retrieval.FieldWeights{Title: 0.8, Breadcrumb: 0.4, Path: 0, Content: 0.1}
+	if got := layer.lexical[0].FieldWeights; got != want {
+		t.Fatalf("SearchLexical field weights = %+v, want KB weights over defaults %+v", got, want)
+	}
+	if resp.Debug.FieldWeights == nil || *resp.Debug.FieldWeights != want {
+		t.Fatalf("debug field weights = %+v, want %+v", resp.Debug.FieldWeights, want)
+	}
+
+	override := retrieval.FieldWeights{Content: 1}
+	resp, err = svc.Retrieve(context.Background(), retrieval.Request{
+		KnowledgeBaseID: "kb-1",
+		Query:           "retry budget",
+		FieldWeights:    override,
+		Debug:           true,
+	})
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if got := layer.lexical[1].FieldWeights; got != override || got.Enabled() {
+		t.Fatalf("SearchLexical field weights = %+v, want the content-only request override", got)
+	}
+	if resp.Debug.FieldWeights != nil {
+		t.Fatalf("debug field weights = %+v, want none for content-only ranking", *resp.Debug.FieldWeights)
+	}
+}
+
+// recordingEmbedder embeds like fixedEmbedder and records each call's texts.
+type recordingEmbedder struct {
+	calls [][]string
+}
+
+func (e *recordingEmbedder) EmbedTexts(ctx context.Context, texts []string) ([][]float32, int, error) {
+	e.calls = append(e.calls, texts)
+	return fixedEmbedder{}.EmbedTexts(ctx, texts)
+}
+
+func TestRetrieveBatch_EmbedsOnceAndIsolatesQueryErrors(t *testing.T) {
+	layer := &fakeLayer{
+		chunks: map[string]retrieval.ChunkRecord{"c1": {ChunkID: "c1"}},
+		semantic: map[string][]retrieval.ScoredChunk{
+			"kb-1": {{ChunkID: "c1", Score: 0.9}},
+		},
+	}
+	embedder := &recordingEmbedder{}
+	svc := New(layer, embedder, WithBatchConcurrency(1))
+
+	results, err := svc.RetrieveBatch(context.Background(), "kb-1", []retrieval.Request{
+		{Query: "rotate credentials"},
+		{Query: ""},
+		{Query: "rotate credentials", TopK: 1},
+		{Query: "connection pool -deprecated"},
+	})
+	if err != nil {
+		t.Fatalf("RetrieveBatch() error = %v", err)
+	}
+	if len(embedder.calls) != 1 {
+		t.Fatalf("EmbedTexts called %d times, want 1", len(embedder.calls))
+	}
+	if got := embedder.calls[0]; len(got) != 2 || got[0] != "rotate credentials" || got[1] != "connection pool" {
+		t.Fatalf("EmbedTexts texts = %q, want distinct query texts without excluded terms", got)
+	}
+	if len(results) != 4 {
+		t.Fatalf("RetrieveBatch() returned %d results, want 4", len(results))
+	}
+	if !errors.Is(results[1].Err, retrieval.ErrMissingQuery) || results[1].Response != nil {
+		t.Fatalf("results[1] = %+v, want a missing query error", results[1])
+	}
+	for _, i := range []int{0, 2, 3} {
+		if results[i].Err != nil || results[i].Response == nil || results[i].Response.KnowledgeBaseID != "kb-1" {
+			t.Fatalf("results[%d] = %+v, want a kb-1 response", i, results[i])
+		}
+	}
+	if len(layer.requests) != 3 {
+		t.Fatalf("logged %d retrieval requests, want one per valid query", len(layer.requests))
+	}
+
+	if _, err := svc.RetrieveBatch(context.Background(), "kb-1", nil); !errors.Is(err, retrieval.ErrMissingBatchQueries) {
+		t.Fatalf("RetrieveBatch(nil) error = %v, want ErrMissingBatchQueries", err)
+	}
+}
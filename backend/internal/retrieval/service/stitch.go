@@ -0,0 +1,108 @@
+package service
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+// runeSpan is a [start, end) rune range.
+type runeSpan struct {
+	start int
+	end   int
+}
+
+// stitchedSpan is a run of chunks stitched into one text. offsets holds each
+// chunk's range within content; document is the span's range in the document
+// when hasDocument is set.
+type stitchedSpan struct {
+	chunks      []retrieval.ChunkRecord
+	offsets     []runeSpan
+	content     string
+	document    runeSpan
+	hasDocument bool
+}
+
+// stitchChunks joins chunks, ordered by document version and sequence, into
+// spans. A chunk continues the previous span when both belong to the same
+// version and its start_rune/end_rune offsets begin after the previous
+// chunk's start and no later than the span's end; the overlapping runes are
+// dropped. Offsets only place chunks within a document when starts increase,
+// since chunkers that record per-chunk offsets start every chunk at 0.
+func stitchChunks(chunks []retrieval.ChunkRecord) []stitchedSpan {
+	var spans []stitchedSpan
+	var content strings.Builder
+	lastStart := 0
+	for _, chunk := range chunks {
+		document, hasDocument := chunkSpan(chunk)
+		var current *stitchedSpan
+		if len(spans) > 0 {
+			current = &spans[len(spans)-1]
+		}
+
+		if current != nil && current.hasDocument && hasDocument &&
+			current.chunks[0].DocumentVersionID == chunk.DocumentVersionID &&
+			document.start > lastStart && document.start <= current.document.end {
+			if document.end > current.document.end {
+				tail := string([]rune(chunk.Content)[current.document.end-document.start:])
+				content.WriteString(tail)
+				current.document.end = document.end
+			}
+			current.chunks = append(current.chunks, chunk)
+			current.offsets = append(current.offsets, runeSpan{
+				start: document.start - current.document.start,
+				end:   document.end - current.document.start,
+			})
+			current.content = content.String()
+			lastStart = document.start
+			continue
+		}
+
+		content.Reset()
+		content.WriteString(chunk.Content)
+		spans = append(spans, stitchedSpan{
+			chunks:      []retrieval.ChunkRecord{chunk},
+			offsets:     []runeSpan{{start: 0, end: utf8.RuneCountInString(chunk.Content)}},
+			content:     chunk.Content,
+			document:    document,
+			hasDocument: hasDocument,
+		})
+		lastStart = document.start
+	}
+	return spans
+}
+
+// chunkSpan reads the chunk's document offsets, reporting false when they are
+// missing or do not match its content length.
+func chunkSpan(record retrieval.ChunkRecord) (runeSpan, bool) {
+	start := extractInt(record.Metadata, "start_rune")
+	end := extractInt(record.Metadata, "end_rune")
+	if start == nil || end == nil || *start < 0 || *end-*start != utf8.RuneCountInString(record.Content) {
+		return runeSpan{}, false
+	}
+	return runeSpan{start: *start, end: *end}, true
+}
+
+func mergedSpan(span stitchedSpan) retrieval.MergedSpan {
+	first := span.chunks[0]
+	merged := retrieval.MergedSpan{
+		DocumentID:        first.DocumentID,
+		DocumentVersionID: first.DocumentVersionID,
+		DocumentPath:      first.DocumentPath,
+		DocumentTitle:     first.DocumentTitle,
+		ChunkIDs:          make([]string, 0, len(span.chunks)),
+		StartSequence:     first.SequenceNumber,
+		EndSequence:       span.chunks[len(span.chunks)-1].SequenceNumber,
+		Content:           span.content,
+		RuneLength:        utf8.RuneCountInString(span.content),
+	}
+	for _, chunk := range span.chunks {
+		merged.ChunkIDs = append(merged.ChunkIDs, chunk.ChunkID)
+	}
+	if span.hasDocument {
+		start, end := span.document.start, span.document.end
+		merged.StartRune, merged.EndRune = &start, &end
+	}
+	return merged
+}
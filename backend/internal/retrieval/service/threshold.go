@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+
+	"ragtime-backend/internal/retrieval"
+)
+
+// applyThresholds drops candidates below the request's minimum semantic
+// similarity, lexical hits and final score, in that order. It returns the
+// kept candidates, how many each threshold dropped and, when nothing is kept,
+// the reason: no candidates at all or the threshold that dropped the last one.
+// Content loaded for counting lexical hits is left in chunkMap for reuse.
+func (s *Service) applyThresholds(
+	ctx context.Context,
+	req retrieval.Request,
+	parsedQuery retrieval.ParsedQuery,
+	candidates candidateSet,
+	chunkMap map[string]retrieval.ChunkRecord,
+) ([]mergedScore, map[string]int, string, error) {
+	merged := candidates.merged
+	if len(merged) == 0 {
+		return merged, nil, retrieval.EmptyReasonNoCandidates, nil
+	}
+
+	dropped := map[string]int{}
+	reason := ""
+	keep := func(name string, pass func(mergedScore) bool) {
+		kept := merged[:0]
+		for _, item := range merged {
+			if pass(item) {
+				kept = append(kept, item)
+			}
+		}
+		if removed := len(merged) - len(kept); removed > 0 {
+			dropped[name] = removed
+			if len(kept) == 0 {
+				reason = name
+			}
+		}
+		merged = kept
+	}
+
+	if req.MinSemanticScoreSet {
+		keep(retrieval.EmptyReasonMinSemanticScore, func(item mergedScore) bool {
+			similarity, ok := candidates.similarity[item.ChunkID]
+			return ok && similarity >= req.MinSemanticScore
+		})
+	}
+	if req.MinLexicalHits > 0 && len(merged) > 0 {
+		hits, err := s.lexicalHits(ctx, req.KnowledgeBaseID, parsedQuery, merged, chunkMap)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		keep(retrieval.EmptyReasonMinLexicalHits, func(item mergedScore) bool {
+			return hits[item.ChunkID] >= req.MinLexicalHits
+		})
+	}
+	if req.MinFinalScoreSet {
+		keep(retrieval.EmptyReasonMinFinalScore, func(item mergedScore) bool {
+			return item.Score.Final >= req.MinFinalScore
+		})
+	}
+
+	if len(dropped) == 0 {
+		dropped = nil
+	}
+	return merged, dropped, reason, nil
+}
+
+// lexicalHits counts, per candidate, the distinct query lexemes its content
+// matches.
+func (s *Service) lexicalHits(
+	ctx context.Context,
+	knowledgeBaseID string,
+	parsedQuery retrieval.ParsedQuery,
+	merged []mergedScore,
+	chunkMap map[string]retrieval.ChunkRecord,
+) (map[string]int, error) {
+	chunkIDs := make([]string, 0, len(merged))
+	for _, item := range merged {
+		chunkIDs = append(chunkIDs, item.ChunkID)
+	}
+	if err := s.loadChunks(ctx, chunkIDs, chunkMap); err != nil {
+		return nil, err
+	}
+
+	contents := make([][]rune, len(chunkIDs))
+	for i, id := range chunkIDs {
+		contents[i] = []rune(chunkMap[id].Content)
+	}
+	matcher, err := s.newLexicalMatcher(ctx, knowledgeBaseID, parsedQuery, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make(map[string]int, len(chunkIDs))
+	for i, id := range chunkIDs {
+		_, hits[id] = matcher.match(contents[i])
+	}
+	return hits, nil
+}
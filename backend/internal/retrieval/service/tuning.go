@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"time"
+
+	"ragtime-backend/internal/logger"
+	"ragtime-backend/internal/retrieval"
+)
+
+// WeightTuning returns the default and tuned semantic weights of the
+// knowledge base's auto-profile classes.
+func (s *Service) WeightTuning(ctx context.Context, knowledgeBaseID string) (*retrieval.WeightTuning, error) {
+	if s.cache == nil {
+		return nil, retrieval.ErrNilRepository
+	}
+	if knowledgeBaseID == "" {
+		return nil, retrieval.ErrMissingKnowledgeBase
+	}
+
+	metadata, err := s.cache.GetKnowledgeBaseMetadata(ctx, knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	tuned := retrieval.ParseKnowledgeBaseSettings(metadata).TunedWeights
+	if tuned == nil {
+		tuned = map[string]retrieval.TunedWeight{}
+	}
+	return &retrieval.WeightTuning{
+		KnowledgeBaseID: knowledgeBaseID,
+		Defaults:        maps.Clone(retrieval.DefaultProfileWeights),
+		Tuned:           tuned,
+	}, nil
+}
+
+// PinWeight sets the semantic weight of an auto-profile class by hand. The
+// tuner leaves pinned weights alone until they are reset.
+func (s *Service) PinWeight(ctx context.Context, knowledgeBaseID string, profile string, weight *float64) (*retrieval.WeightTuning, error) {
+	if s.cache == nil {
+		return nil, retrieval.ErrNilRepository
+	}
+	if knowledgeBaseID == "" {
+		return nil, retrieval.ErrMissingKnowledgeBase
+	}
+	profile = strings.ToLower(strings.TrimSpace(profile))
+	if !retrieval.IsValidTuningProfile(profile) {
+		return nil, retrieval.ErrInvalidTuningProfile
+	}
+	if weight == nil || *weight < 0 || *weight > 1 {
+		return nil, retrieval.ErrInvalidTunedWeight
+	}
+
+	pinned := retrieval.TunedWeight{Weight: *weight, Pinned: true, TunedAt: s.now()}
+	if _, err := s.cache.SaveTunedWeight(ctx, knowledgeBaseID, profile, pinned, false); err != nil {
+		return nil, err
+	}
+	return s.WeightTuning(ctx, knowledgeBaseID)
+}
+
+// ResetWeights removes the tuned or pinned weight of profile, or of every
+// class when profile is empty, so the defaults apply again.
+func (s *Service) ResetWeights(ctx context.Context, knowledgeBaseID string, profile string) (*retrieval.WeightTuning, error) {
+	if s.cache == nil {
+		return nil, retrieval.ErrNilRepository
+	}
+	if knowledgeBaseID == "" {
+		return nil, retrieval.ErrMissingKnowledgeBase
+	}
+	profile = strings.ToLower(strings.TrimSpace(profile))
+	if profile != "" && !retrieval.IsValidTuningProfile(profile) {
+		return nil, retrieval.ErrInvalidTuningProfile
+	}
+
+	if err := s.cache.DeleteTunedWeights(ctx, knowledgeBaseID, profile); err != nil {
+		return nil, err
+	}
+	return s.WeightTuning(ctx, knowledgeBaseID)
+}
+
+// TuneWeights fits each auto-profile class's semantic weight to the relevance
+// labels of the knowledge base's recent queries, grouped by the class the
+// query text classifies as, and stores the weights that maximise metric.
+// Pinned classes and classes with too few labelled queries are skipped.
+func (s *Service) TuneWeights(ctx context.Context, knowledgeBaseID string, metric string) (*retrieval.WeightTuningRun, error) {
+	if s.cache == nil {
+		return nil, retrieval.ErrNilRepository
+	}
+	if knowledgeBaseID == "" {
+		return nil, retrieval.ErrMissingKnowledgeBase
+	}
+	metric = strings.ToLower(strings.TrimSpace(metric))
+	if metric == "" {
+		metric = retrieval.DefaultTuningMetric
+	}
+	if !retrieval.IsValidTuningMetric(metric) {
+		return nil, retrieval.ErrInvalidTuningMetric
+	}
+
+	metadata, err := s.cache.GetKnowledgeBaseMetadata(ctx, knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	current := retrieval.ParseKnowledgeBaseSettings(metadata).TunedWeights
+
+	now := s.now()
+	run := &retrieval.WeightTuningRun{
+		KnowledgeBaseID: knowledgeBaseID,
+		Metric:          metric,
+		Since:           now.Add(-retrieval.DefaultTuningWindow),
+		Tuned:           map[string]retrieval.TunedWeight{},
+		Skipped:         map[string]string{},
+	}
+	queries, err := s.cache.ListTuningQueries(ctx, knowledgeBaseID, run.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	byClass := make(map[string][]retrieval.TuningQuery, len(retrieval.DefaultProfileWeights))
+	for _, query := range queries {
+		class, _ := classifyAutoProfile(query.Query)
+		byClass[class] = append(byClass[class], query)
+	}
+
+	for class, fallback := range retrieval.DefaultProfileWeights {
+		if current[class].Pinned {
+			run.Skipped[class] = retrieval.TuningSkippedPinned
+			continue
+		}
+		weight, score, baseline, used, ok := retrieval.FitSemanticWeight(byClass[class], metric, fallback)
+		if !ok {
+			run.Skipped[class] = retrieval.TuningSkippedInsufficient
+			continue
+		}
+
+		tuned := retrieval.TunedWeight{
+			Weight:   weight,
+			Metric:   metric,
+			Score:    &score,
+			Baseline: &baseline,
+			Queries:  used,
+			TunedAt:  now,
+		}
+		stored, err := s.cache.SaveTunedWeight(ctx, knowledgeBaseID, class, tuned, true)
+		if err != nil {
+			return nil, err
+		}
+		if !stored {
+			run.Skipped[class] = retrieval.TuningSkippedPinned
+			continue
+		}
+		run.Tuned[class] = tuned
+	}
+	return run, nil
+}
+
+// RunWeightTuner tunes every knowledge base's weights each interval until
+// ctx is cancelled.
+func (s *Service) RunWeightTuner(ctx context.Context, interval time.Duration, metric string) {
+	if s.cache == nil || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			kbIDs, err := s.cache.ListKnowledgeBaseIDs(ctx)
+			if err != nil {
+				logger.Error("weight tuning failed", "error", err)
+				continue
+			}
+			for _, kbID := range kbIDs {
+				if _, err := s.TuneWeights(ctx, kbID, metric); err != nil {
+					logger.Error("weight tuning failed", "error", err, "kb_id", kbID)
+				}
+			}
+		}
+	}
+}
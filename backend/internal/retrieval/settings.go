@@ -0,0 +1,139 @@
+package retrieval
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Keys in knowledge_bases.metadata that hold per knowledge base retrieval defaults.
+const (
+	SettingLexicalScorer = "lexical_scorer"
+	SettingBM25K1        = "bm25_k1"
+	SettingBM25B         = "bm25_b"
+	// SettingTunedSemanticWeights maps auto-profile classes to TunedWeight
+	// objects written by the weight tuner or pinned by hand.
+	SettingTunedSemanticWeights = "tuned_semantic_weights"
+	// SettingFreshness holds a FreshnessOptions object.
+	SettingFreshness = "freshness"
+	// SettingLexicalFieldWeights holds a FieldWeights object; missing fields
+	// take DefaultFieldWeights.
+	SettingLexicalFieldWeights = "lexical_field_weights"
+)
+
+// KnowledgeBaseSettings are the retrieval defaults a knowledge base stores in
+// its metadata. Missing or out-of-range values are left unset so request
+// values and package defaults apply instead.
+type KnowledgeBaseSettings struct {
+	LexicalScorer string
+	BM25K1        *float64
+	BM25B         *float64
+	TunedWeights  map[string]TunedWeight
+	Freshness     *FreshnessOptions
+	FieldWeights  *FieldWeights
+}
+
+// ParseKnowledgeBaseSettings reads retrieval defaults from knowledge base metadata.
+func ParseKnowledgeBaseSettings(metadata map[string]any) KnowledgeBaseSettings {
+	var settings KnowledgeBaseSettings
+	if scorer, ok := metadata[SettingLexicalScorer].(string); ok && IsValidLexicalScorer(scorer) {
+		settings.LexicalScorer = strings.ToLower(strings.TrimSpace(scorer))
+	}
+	if k1, ok := metadataFloat(metadata, SettingBM25K1); ok && k1 >= 0 && k1 <= MaxBM25K1 {
+		settings.BM25K1 = &k1
+	}
+	if b, ok := metadataFloat(metadata, SettingBM25B); ok && b >= 0 && b <= 1 {
+		settings.BM25B = &b
+	}
+	settings.TunedWeights = parseTunedWeights(metadata[SettingTunedSemanticWeights])
+	settings.Freshness = parseFreshness(metadata[SettingFreshness])
+	settings.FieldWeights = parseFieldWeights(metadata[SettingLexicalFieldWeights])
+	return settings
+}
+
+// parseFieldWeights reads a field weights object over the defaults, leaving
+// it unset when any weight is invalid.
+func parseFieldWeights(value any) *FieldWeights {
+	entry, ok := value.(map[string]any)
+	if !ok {
+		return nil
+	}
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return nil
+	}
+	weights := DefaultFieldWeights
+	if err := json.Unmarshal(raw, &weights); err != nil {
+		return nil
+	}
+	if ValidateFieldWeights(weights) != nil {
+		return nil
+	}
+	return &weights
+}
+
+// parseFreshness reads a freshness object, leaving it unset when any of its
+// options is invalid.
+func parseFreshness(value any) *FreshnessOptions {
+	entry, ok := value.(map[string]any)
+	if !ok {
+		return nil
+	}
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return nil
+	}
+	var opts FreshnessOptions
+	if err := json.Unmarshal(raw, &opts); err != nil {
+		return nil
+	}
+	opts = NormalizeFreshness(opts)
+	if ValidateFreshness(opts) != nil {
+		return nil
+	}
+	return &opts
+}
+
+// parseTunedWeights keeps the entries of known classes whose weight is
+// within [0, 1].
+func parseTunedWeights(value any) map[string]TunedWeight {
+	entries, ok := value.(map[string]any)
+	if !ok {
+		return nil
+	}
+	raw, err := json.Marshal(entries)
+	if err != nil {
+		return nil
+	}
+	var decoded map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil
+	}
+
+	weights := map[string]TunedWeight{}
+	for class, entry := range decoded {
+		var tuned TunedWeight
+		if !IsValidTuningProfile(class) || json.Unmarshal(entry, &tuned) != nil {
+			continue
+		}
+		if tuned.Weight < 0 || tuned.Weight > 1 {
+			continue
+		}
+		weights[class] = tuned
+	}
+	if len(weights) == 0 {
+		return nil
+	}
+	return weights
+}
+
+func metadataFloat(metadata map[string]any, key string) (float64, bool) {
+	switch value := metadata[key].(type) {
+	case float64:
+		return value, true
+	case json.Number:
+		parsed, err := value.Float64()
+		return parsed, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,66 @@
+package retrieval
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseKnowledgeBaseSettings(t *testing.T) {
+	settings := ParseKnowledgeBaseSettings(map[string]any{
+		SettingLexicalScorer: " BM25 ",
+		SettingBM25K1:        json.Number("1.5"),
+		SettingBM25B:         4.0,
+	})
+	if settings.LexicalScorer != LexicalScorerBM25 {
+		t.Fatalf("LexicalScorer = %q, want bm25", settings.LexicalScorer)
+	}
+	if settings.BM25K1 == nil || *settings.BM25K1 != 1.5 {
+		t.Fatalf("BM25K1 = %v, want 1.5", settings.BM25K1)
+	}
+	if settings.BM25B != nil {
+		t.Fatalf("BM25B = %v, want out-of-range value ignored", *settings.BM25B)
+	}
+
+	if empty := ParseKnowledgeBaseSettings(nil); empty.LexicalScorer != "" || empty.BM25K1 != nil {
+		t.Fatalf("ParseKnowledgeBaseSettings(nil) = %+v, want zero value", empty)
+	}
+}
+
+func TestParseKnowledgeBaseSettings_TunedWeights(t *testing.T) {
+	settings := ParseKnowledgeBaseSettings(map[string]any{
+		SettingTunedSemanticWeights: map[string]any{
+			RetrievalProfileExact:    map[string]any{"weight": 0.35, "pinned": true},
+			RetrievalProfileSemantic: map[string]any{"weight": 1.4},
+			"unknown":                map[string]any{"weight": 0.5},
+		},
+	})
+	if len(settings.TunedWeights) != 1 {
+		t.Fatalf("TunedWeights = %+v, want only the valid exact entry", settings.TunedWeights)
+	}
+	exact := settings.TunedWeights[RetrievalProfileExact]
+	if exact.Weight != 0.35 || !exact.Pinned {
+		t.Fatalf("TunedWeights[exact] = %+v, want pinned 0.35", exact)
+	}
+}
+
+func TestParseKnowledgeBaseSettings_Freshness(t *testing.T) {
+	settings := ParseKnowledgeBaseSettings(map[string]any{
+		SettingFreshness: map[string]any{"decay": " Linear ", "date_field": "published_at"},
+	})
+	want := FreshnessOptions{
+		Decay:        FreshnessDecayLinear,
+		HalfLifeDays: DefaultFreshnessHalfLifeDays,
+		Weight:       DefaultFreshnessWeight,
+		DateField:    "published_at",
+	}
+	if settings.Freshness == nil || *settings.Freshness != want {
+		t.Fatalf("Freshness = %+v, want %+v", settings.Freshness, want)
+	}
+
+	invalid := ParseKnowledgeBaseSettings(map[string]any{
+		SettingFreshness: map[string]any{"decay": "exponential", "half_life_days": -1.0},
+	})
+	if invalid.Freshness != nil {
+		t.Fatalf("Freshness = %+v, want invalid half-life ignored", *invalid.Freshness)
+	}
+}
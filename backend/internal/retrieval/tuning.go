@@ -0,0 +1,204 @@
+package retrieval
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	TuningMetricNDCG     = "ndcg"
+	TuningMetricMRR      = "mrr"
+	DefaultTuningMetric  = TuningMetricNDCG
+	TuningCutoff         = 10
+	TuningWeightStep     = 0.05
+	MinTuningQueries     = 20
+	DefaultTuningWindow  = 90 * 24 * time.Hour
+	DefaultTunerInterval = time.Hour
+	// RelevantGrade is the lowest graded relevance MRR counts as a hit.
+	RelevantGrade = 2
+)
+
+var (
+	ErrInvalidTuningMetric  = errors.New("metric must be one of: ndcg, mrr")
+	ErrInvalidTuningProfile = errors.New("profile must be one of: exact, balanced, semantic")
+	ErrInvalidTunedWeight   = errors.New("semantic_weight is required and must be between 0 and 1")
+)
+
+// Reasons a tuning run left a class's weight unchanged.
+const (
+	TuningSkippedPinned       = "pinned"
+	TuningSkippedInsufficient = "insufficient_labels"
+)
+
+// DefaultProfileWeights are the semantic weights of the auto-profile classes
+// used until a knowledge base has tuned ones.
+var DefaultProfileWeights = map[string]float64{
+	RetrievalProfileExact:    0.2,
+	RetrievalProfileBalanced: 0.5,
+	RetrievalProfileSemantic: 0.8,
+}
+
+// TunedWeight is a knowledge base's semantic weight for one auto-profile
+// class. Pinned weights are set by hand and never replaced by the tuner.
+// Score and Baseline are the tuning metric at Weight and at the default
+// weight over the Queries it was fitted from.
+type TunedWeight struct {
+	Weight   float64   `json:"weight"`
+	Pinned   bool      `json:"pinned,omitempty"`
+	Metric   string    `json:"metric,omitempty"`
+	Score    *float64  `json:"score,omitempty"`
+	Baseline *float64  `json:"baseline,omitempty"`
+	Queries  int       `json:"queries,omitempty"`
+	TunedAt  time.Time `json:"tuned_at"`
+}
+
+// WeightTuning is the view of a knowledge base's auto-profile weights.
+type WeightTuning struct {
+	KnowledgeBaseID string                 `json:"kb_id"`
+	Defaults        map[string]float64     `json:"defaults"`
+	Tuned           map[string]TunedWeight `json:"tuned"`
+}
+
+// WeightTuningRun reports one tuning pass over the labels created since
+// Since: the weights it stored and why other classes were left unchanged.
+type WeightTuningRun struct {
+	KnowledgeBaseID string                 `json:"kb_id"`
+	Metric          string                 `json:"metric"`
+	Since           time.Time              `json:"since"`
+	Tuned           map[string]TunedWeight `json:"tuned"`
+	Skipped         map[string]string      `json:"skipped"`
+}
+
+// TuningResult is a labelled result of a logged query: its normalized
+// component scores and graded relevance from 0 to 3.
+type TuningResult struct {
+	SemanticScore float64
+	LexicalScore  float64
+	Relevance     float64
+}
+
+// TuningQuery holds the labelled results of one logged linear-fusion query.
+type TuningQuery struct {
+	RequestID string
+	Query     string
+	Results   []TuningResult
+}
+
+// IsValidTuningProfile reports whether profile names an auto-profile class.
+func IsValidTuningProfile(profile string) bool {
+	_, ok := DefaultProfileWeights[strings.ToLower(strings.TrimSpace(profile))]
+	return ok
+}
+
+func IsValidTuningMetric(metric string) bool {
+	switch strings.ToLower(strings.TrimSpace(metric)) {
+	case TuningMetricNDCG, TuningMetricMRR:
+		return true
+	default:
+		return false
+	}
+}
+
+// FitSemanticWeight searches weights from 0 to 1 in TuningWeightStep steps
+// for the one whose linear fusion of each query's labelled results scores
+// best on metric, averaged over queries. Ties go to the weight nearest
+// fallback. Queries whose labels are all equal cannot tell weights apart and
+// are skipped; ok is false when fewer than MinTuningQueries remain. Only the
+// logged results are reranked, so chunks the original weight kept out of the
+// results cannot be promoted.
+func FitSemanticWeight(queries []TuningQuery, metric string, fallback float64) (weight float64, score float64, baseline float64, used int, ok bool) {
+	informative := make([]TuningQuery, 0, len(queries))
+	for _, query := range queries {
+		if hasDistinctRelevance(query.Results) {
+			informative = append(informative, query)
+		}
+	}
+	if len(informative) < MinTuningQueries {
+		return fallback, 0, 0, len(informative), false
+	}
+
+	weight, score = fallback, math.Inf(-1)
+	steps := int(math.Round(1 / TuningWeightStep))
+	for i := 0; i <= steps; i++ {
+		candidate := float64(i) / float64(steps)
+		value := meanTuningMetric(informative, metric, candidate)
+		if value > score+1e-12 || (math.Abs(value-score) <= 1e-12 && math.Abs(candidate-fallback) < math.Abs(weight-fallback)) {
+			weight, score = candidate, value
+		}
+	}
+	return weight, score, meanTuningMetric(informative, metric, fallback), len(informative), true
+}
+
+func meanTuningMetric(queries []TuningQuery, metric string, weight float64) float64 {
+	var total float64
+	for _, query := range queries {
+		ranked := rankTuningResults(query.Results, weight)
+		if metric == TuningMetricMRR {
+			total += reciprocalRank(ranked)
+		} else {
+			total += ndcg(ranked, TuningCutoff)
+		}
+	}
+	return total / float64(len(queries))
+}
+
+// rankTuningResults orders relevances by fused score, breaking ties towards
+// the less relevant result so a weight is never credited for a lucky tie.
+func rankTuningResults(results []TuningResult, weight float64) []float64 {
+	ordered := append([]TuningResult(nil), results...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		left := weight*ordered[i].SemanticScore + (1-weight)*ordered[i].LexicalScore
+		right := weight*ordered[j].SemanticScore + (1-weight)*ordered[j].LexicalScore
+		if left != right {
+			return left > right
+		}
+		return ordered[i].Relevance < ordered[j].Relevance
+	})
+	relevances := make([]float64, len(ordered))
+	for i, result := range ordered {
+		relevances[i] = result.Relevance
+	}
+	return relevances
+}
+
+func ndcg(relevances []float64, cutoff int) float64 {
+	ideal := append([]float64(nil), relevances...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(ideal)))
+	best := dcg(ideal, cutoff)
+	if best == 0 {
+		return 0
+	}
+	return dcg(relevances, cutoff) / best
+}
+
+func dcg(relevances []float64, cutoff int) float64 {
+	var total float64
+	for i, relevance := range relevances {
+		if i == cutoff {
+			break
+		}
+		total += (math.Pow(2, relevance) - 1) / math.Log2(float64(i)+2)
+	}
+	return total
+}
+
+func reciprocalRank(relevances []float64) float64 {
+	for i, relevance := range relevances {
+		if relevance >= RelevantGrade {
+			return 1 / float64(i+1)
+		}
+	}
+	return 0
+}
+
+func hasDistinctRelevance(results []TuningResult) bool {
+	for _, result := range results[min(1, len(results)):] {
+		if result.Relevance != results[0].Relevance {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+-- name: ListTopQueries :many
+SELECT
+    lower(btrim(query)) AS normalized_query,
+    COUNT(*) AS request_count,
+    COUNT(*) FILTER (WHERE empty_result) AS empty_count,
+    CAST(AVG(result_count) AS double precision) AS avg_result_count,
+    CAST(AVG(latency_ms) AS double precision) AS avg_latency_ms,
+    CAST(MAX(created_at) AS timestamptz) AS last_seen_at
+FROM retrieval_requests
+WHERE kb_id = sqlc.arg('kb_id')
+  AND created_at >= sqlc.arg('start_time')
+  AND created_at < sqlc.arg('end_time')
+GROUP BY lower(btrim(query))
+ORDER BY request_count DESC, normalized_query
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: ListZeroResultQueries :many
+SELECT
+    lower(btrim(query)) AS normalized_query,
+    COUNT(*) AS request_count,
+    CAST(COALESCE(array_agg(DISTINCT empty_reason) FILTER (WHERE empty_reason IS NOT NULL), '{}') AS text[]) AS empty_reasons,
+    CAST(MAX(created_at) AS timestamptz) AS last_seen_at
+FROM retrieval_requests
+WHERE kb_id = sqlc.arg('kb_id')
+  AND empty_result = true
+  AND created_at >= sqlc.arg('start_time')
+  AND created_at < sqlc.arg('end_time')
+GROUP BY lower(btrim(query))
+ORDER BY request_count DESC, normalized_query
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: ListLatencyPercentiles :many
+-- Requests that fail before completing keep latency_ms = 0 and are skipped.
+SELECT
+    CAST(date_trunc(sqlc.arg('bucket')::text, created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS timestamptz) AS bucket_start,
+    COUNT(*) AS request_count,
+    CAST(percentile_cont(0.5) WITHIN GROUP (ORDER BY latency_ms) AS double precision) AS p50_ms,
+    CAST(percentile_cont(0.9) WITHIN GROUP (ORDER BY latency_ms) AS double precision) AS p90_ms,
+    CAST(percentile_cont(0.95) WITHIN GROUP (ORDER BY latency_ms) AS double precision) AS p95_ms,
+    CAST(percentile_cont(0.99) WITHIN GROUP (ORDER BY latency_ms) AS double precision) AS p99_ms,
+    CAST(MAX(latency_ms) AS bigint) AS max_ms
+FROM retrieval_requests
+WHERE kb_id = sqlc.arg('kb_id')
+  AND latency_ms > 0
+  AND created_at >= sqlc.arg('start_time')
+  AND created_at < sqlc.arg('end_time')
+GROUP BY bucket_start
+ORDER BY bucket_start
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: ListProfileDistribution :many
+SELECT
+    CAST(COALESCE(retrieval_profile, '') AS text) AS retrieval_profile,
+    COUNT(*) AS request_count,
+    CAST(AVG(hybrid_weight) AS double precision) AS avg_semantic_weight
+FROM retrieval_requests
+WHERE kb_id = sqlc.arg('kb_id')
+  AND created_at >= sqlc.arg('start_time')
+  AND created_at < sqlc.arg('end_time')
+GROUP BY COALESCE(retrieval_profile, '')
+ORDER BY request_count DESC, retrieval_profile;
+
+-- name: ListSemanticWeightDistribution :many
+SELECT
+    CAST(round(CAST(hybrid_weight AS numeric), 1) AS double precision) AS semantic_weight,
+    COUNT(*) AS request_count
+FROM retrieval_requests
+WHERE kb_id = sqlc.arg('kb_id')
+  AND created_at >= sqlc.arg('start_time')
+  AND created_at < sqlc.arg('end_time')
+GROUP BY round(CAST(hybrid_weight AS numeric), 1)
+ORDER BY semantic_weight;
+
+-- name: ListMostRetrievedDocuments :many
+SELECT
+    d.id AS document_id,
+    d.path AS document_path,
+    d.title AS document_title,
+    COUNT(DISTINCT rr.retrieval_request_id) AS request_count,
+    COUNT(*) AS result_count,
+    CAST(AVG(rr.rank) AS double precision) AS avg_rank,
+    CAST(MAX(rq.created_at) AS timestamptz) AS last_retrieved_at
+FROM retrieval_results rr
+JOIN retrieval_requests rq ON rq.id = rr.retrieval_request_id
+JOIN chunks c ON c.id = rr.chunk_id
+JOIN document_versions dv ON dv.id = c.document_version_id
+JOIN documents d ON d.id = dv.document_id
+WHERE rq.kb_id = sqlc.arg('kb_id')
+  AND rq.created_at >= sqlc.arg('start_time')
+  AND rq.created_at < sqlc.arg('end_time')
+GROUP BY d.id, d.path, d.title
+ORDER BY request_count DESC, d.path
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: ListNeverRetrievedDocuments :many
+-- Documents with an active version that no result of the range came from.
+SELECT
+    d.id AS document_id,
+    d.path AS document_path,
+    d.title AS document_title,
+    d.created_at
+FROM documents d
+WHERE d.kb_id = sqlc.arg('kb_id')
+  AND EXISTS (
+      SELECT 1
+      FROM document_versions active
+      WHERE active.document_id = d.id
+        AND active.is_active = true
+  )
+  AND NOT EXISTS (
+      SELECT 1
+      FROM retrieval_requests rq
+      JOIN retrieval_results rr ON rr.retrieval_request_id = rq.id
+      JOIN chunks c ON c.id = rr.chunk_id
+      JOIN document_versions dv ON dv.id = c.document_version_id
+      WHERE rq.kb_id = d.kb_id
+        AND dv.document_id = d.id
+        AND rq.created_at >= sqlc.arg('start_time')
+        AND rq.created_at < sqlc.arg('end_time')
+  )
+ORDER BY d.path
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
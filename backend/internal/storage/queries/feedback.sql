@@ -0,0 +1,72 @@
+-- name: InsertRetrievalFeedback :execrows
+-- Stores nothing when the chunk was not a result of the knowledge base's query.
+INSERT INTO retrieval_feedback (
+    id,
+    kb_id,
+    retrieval_result_id,
+    user_id,
+    thumbs,
+    grade,
+    clicked,
+    cited,
+    created_at
+)
+SELECT
+    sqlc.arg('id'),
+    rq.kb_id,
+    rr.id,
+    sqlc.narg('user_id'),
+    sqlc.narg('thumbs'),
+    sqlc.narg('grade'),
+    sqlc.arg('clicked'),
+    sqlc.arg('cited'),
+    sqlc.arg('created_at')
+FROM retrieval_results rr
+JOIN retrieval_requests rq ON rq.id = rr.retrieval_request_id
+WHERE rq.kb_id = sqlc.arg('kb_id')
+  AND rr.retrieval_request_id = sqlc.arg('retrieval_request_id')
+  AND rr.chunk_id = sqlc.arg('chunk_id')
+ORDER BY rr.rank
+LIMIT 1;
+
+-- name: GetFeedbackSummary :one
+SELECT
+    COUNT(*) AS label_count,
+    COUNT(DISTINCT rr.retrieval_request_id) AS query_count,
+    COUNT(*) FILTER (WHERE f.thumbs = 1) AS thumbs_up,
+    COUNT(*) FILTER (WHERE f.thumbs = -1) AS thumbs_down,
+    COUNT(f.grade) AS graded_count,
+    CAST(AVG(f.grade) AS double precision) AS avg_grade,
+    COUNT(*) FILTER (WHERE f.clicked) AS clicked_count,
+    COUNT(*) FILTER (WHERE f.cited) AS cited_count
+FROM retrieval_feedback f
+JOIN retrieval_results rr ON rr.id = f.retrieval_result_id
+WHERE f.kb_id = sqlc.arg('kb_id')
+  AND f.created_at >= sqlc.arg('start_time')
+  AND f.created_at < sqlc.arg('end_time');
+
+-- name: ListChunkFeedback :many
+SELECT
+    rr.chunk_id,
+    d.id AS document_id,
+    d.path AS document_path,
+    COUNT(*) AS label_count,
+    COUNT(*) FILTER (WHERE f.thumbs = 1) AS thumbs_up,
+    COUNT(*) FILTER (WHERE f.thumbs = -1) AS thumbs_down,
+    COUNT(f.grade) AS graded_count,
+    CAST(AVG(f.grade) AS double precision) AS avg_grade,
+    COUNT(*) FILTER (WHERE f.clicked) AS clicked_count,
+    COUNT(*) FILTER (WHERE f.cited) AS cited_count,
+    CAST(AVG(rr.rank) AS double precision) AS avg_rank
+FROM retrieval_feedback f
+JOIN retrieval_results rr ON rr.id = f.retrieval_result_id
+JOIN chunks c ON c.id = rr.chunk_id
+JOIN document_versions dv ON dv.id = c.document_version_id
+JOIN documents d ON d.id = dv.document_id
+WHERE f.kb_id = sqlc.arg('kb_id')
+  AND f.created_at >= sqlc.arg('start_time')
+  AND f.created_at < sqlc.arg('end_time')
+GROUP BY rr.chunk_id, d.id, d.path
+ORDER BY label_count DESC, rr.chunk_id
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
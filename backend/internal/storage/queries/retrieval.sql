@@ -9,7 +9,10 @@ INSERT INTO retrieval_requests (
     result_count,
     latency_ms,
     empty_result,
-    created_at
+    created_at,
+    federated_request_id,
+    fusion_method,
+    retrieval_profile
 ) VALUES (
     $1,
     $2,
@@ -20,7 +23,10 @@ INSERT INTO retrieval_requests (
     $7,
     $8,
     $9,
-    $10
+    $10,
+    $11,
+    $12,
+    $13
 )
 RETURNING *;
 
@@ -28,9 +34,28 @@ RETURNING *;
 UPDATE retrieval_requests
 SET result_count = $2,
     latency_ms = $3,
-    empty_result = $4
+    empty_result = $4,
+    empty_reason = $5
+WHERE id = $1;
+
+-- name: UpdateRetrievalRequestRanking :exec
+UPDATE retrieval_requests
+SET ranked_candidates = $2
 WHERE id = $1;
 
+-- name: GetRetrievalRequestRanking :one
+SELECT
+    id,
+    kb_id,
+    query,
+    top_k,
+    hybrid_weight,
+    created_at,
+    ranked_candidates
+FROM retrieval_requests
+WHERE id = $1
+  AND kb_id = $2;
+
 -- name: InsertRetrievalResult :exec
 INSERT INTO retrieval_results (
     id,
@@ -40,6 +65,9 @@ INSERT INTO retrieval_results (
     semantic_score,
     lexical_score,
     final_score,
+    rerank_score,
+    semantic_rank,
+    lexical_rank,
     created_at
 ) VALUES (
     $1,
@@ -49,50 +77,17 @@ INSERT INTO retrieval_results (
     $5,
     $6,
     $7,
-    $8
-);
-
--- name: SearchSemantic :many
-SELECT
-    c.id AS chunk_id,
-    CAST(1.0 - (e.embedding_vector <=> sqlc.arg('query_vector')::vector) AS double precision) AS semantic_score
-FROM chunks c
-JOIN embeddings e ON c.embedding_id = e.id
-JOIN document_versions dv ON c.document_version_id = dv.id
-JOIN documents d ON dv.document_id = d.id
-WHERE dv.is_active = true
-  AND c.kb_id = sqlc.arg('kb_id')
-  AND (sqlc.narg('document_type')::text IS NULL OR d.document_type = sqlc.narg('document_type'))
-  AND (sqlc.narg('path_prefix')::text IS NULL OR d.path LIKE sqlc.narg('path_prefix'))
-  AND (sqlc.narg('source')::text IS NULL OR d.source_metadata ->> 'source' = sqlc.narg('source'))
-  AND (sqlc.arg('tags')::jsonb = '{}'::jsonb OR d.source_metadata @> sqlc.arg('tags')::jsonb)
-  AND (sqlc.narg('created_after')::timestamptz IS NULL OR dv.created_at >= sqlc.narg('created_after'))
-  AND (sqlc.narg('created_before')::timestamptz IS NULL OR dv.created_at <= sqlc.narg('created_before'))
-ORDER BY e.embedding_vector <=> sqlc.arg('query_vector')::vector
-LIMIT sqlc.arg('limit');
-
--- name: SearchLexical :many
-SELECT
-    c.id AS chunk_id,
-    ts_rank(to_tsvector('english', c.content), plainto_tsquery('english', sqlc.arg('query'))) AS lexical_score
-FROM chunks c
-JOIN document_versions dv ON c.document_version_id = dv.id
-JOIN documents d ON dv.document_id = d.id
-WHERE dv.is_active = true
-  AND c.kb_id = sqlc.arg('kb_id')
-  AND to_tsvector('english', c.content) @@ plainto_tsquery('english', sqlc.arg('query'))
-  AND (sqlc.narg('document_type')::text IS NULL OR d.document_type = sqlc.narg('document_type'))
-  AND (sqlc.narg('path_prefix')::text IS NULL OR d.path LIKE sqlc.narg('path_prefix'))
-  AND (sqlc.narg('source')::text IS NULL OR d.source_metadata ->> 'source' = sqlc.narg('source'))
-  AND (sqlc.arg('tags')::jsonb = '{}'::jsonb OR d.source_metadata @> sqlc.arg('tags')::jsonb)
-  AND (sqlc.narg('created_after')::timestamptz IS NULL OR dv.created_at >= sqlc.narg('created_after'))
-  AND (sqlc.narg('created_before')::timestamptz IS NULL OR dv.created_at <= sqlc.narg('created_before'))
-ORDER BY ts_rank(to_tsvector('english', c.content), plainto_tsquery('english', sqlc.arg('query'))) DESC
-LIMIT sqlc.arg('limit');
+    $8,
+    $9,
+    $10,
+    $11
+)
+ON CONFLICT (retrieval_request_id, rank) DO NOTHING;
 
 -- name: GetChunksWithDocuments :many
 SELECT
     c.id AS chunk_id,
+    c.kb_id,
     c.document_version_id,
     c.sequence_number,
     c.content,
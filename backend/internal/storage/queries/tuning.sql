@@ -0,0 +1,72 @@
+-- name: ListTuningSamples :many
+-- Averages the relevance labels of each logged linear-fusion result. Grades
+-- are used as given; otherwise a thumbs down counts 0, a thumbs up or
+-- citation 2 and a click 1. Calibration judgments count 2 or 0.
+WITH labels AS (
+    SELECT
+        f.retrieval_result_id AS result_id,
+        CASE
+            WHEN f.grade IS NOT NULL THEN f.grade
+            WHEN f.thumbs = -1 THEN 0
+            WHEN f.thumbs = 1 OR f.cited THEN 2
+            ELSE 1
+        END AS relevance
+    FROM retrieval_feedback f
+    WHERE f.kb_id = sqlc.arg('kb_id')
+      AND f.created_at >= sqlc.arg('start_time')
+    UNION ALL
+    SELECT
+        rr.id AS result_id,
+        CASE WHEN j.relevant THEN 2 ELSE 0 END AS relevance
+    FROM calibration_judgments j
+    JOIN retrieval_results rr
+      ON rr.retrieval_request_id = j.retrieval_request_id
+     AND rr.chunk_id = j.chunk_id
+    WHERE j.kb_id = sqlc.arg('kb_id')
+      AND j.created_at >= sqlc.arg('start_time')
+)
+SELECT
+    rq.id AS retrieval_request_id,
+    rq.query,
+    rr.semantic_score,
+    rr.lexical_score,
+    CAST(AVG(l.relevance) AS double precision) AS relevance
+FROM labels l
+JOIN retrieval_results rr ON rr.id = l.result_id
+JOIN retrieval_requests rq ON rq.id = rr.retrieval_request_id
+WHERE rq.fusion_method = 'linear'
+  AND rq.federated_request_id IS NULL
+GROUP BY rq.id, rq.query, rr.id, rr.semantic_score, rr.lexical_score
+ORDER BY rq.id, rr.id;
+
+-- name: SetTunedSemanticWeight :execrows
+-- Replaces one class's entry in the knowledge base's tuned_semantic_weights
+-- metadata. With skip_pinned set, a pinned entry is left as it is.
+UPDATE knowledge_bases
+SET metadata = jsonb_set(
+        metadata,
+        '{tuned_semantic_weights}',
+        CASE
+            WHEN jsonb_typeof(metadata->'tuned_semantic_weights') = 'object'
+                THEN metadata->'tuned_semantic_weights'
+            ELSE '{}'::jsonb
+        END || jsonb_build_object(sqlc.arg('profile')::text, sqlc.arg('tuned')::jsonb)
+    ),
+    updated_at = sqlc.arg('updated_at')
+WHERE id = sqlc.arg('kb_id')
+  AND NOT (
+      sqlc.arg('skip_pinned')::boolean
+      AND COALESCE(metadata #>> ARRAY['tuned_semantic_weights', sqlc.arg('profile')::text, 'pinned'], 'false') = 'true'
+  );
+
+-- name: DeleteTunedSemanticWeight :execrows
+UPDATE knowledge_bases
+SET metadata = metadata #- ARRAY['tuned_semantic_weights', sqlc.arg('profile')::text],
+    updated_at = sqlc.arg('updated_at')
+WHERE id = sqlc.arg('kb_id');
+
+-- name: DeleteTunedSemanticWeights :execrows
+UPDATE knowledge_bases
+SET metadata = metadata - 'tuned_semantic_weights',
+    updated_at = sqlc.arg('updated_at')
+WHERE id = sqlc.arg('kb_id');
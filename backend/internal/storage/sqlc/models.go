@@ -128,26 +128,46 @@ type ProcessingMetric struct {
 	CreatedAt      time.Time      `json:"created_at"`
 }
 
+type RetrievalFeedback struct {
+	ID                uuid.UUID      `json:"id"`
+	KbID              uuid.UUID      `json:"kb_id"`
+	RetrievalResultID uuid.UUID      `json:"retrieval_result_id"`
+	UserID            sql.NullString `json:"user_id"`
+	Thumbs            sql.NullInt16  `json:"thumbs"`
+	Grade             sql.NullInt16  `json:"grade"`
+	Clicked           bool           `json:"clicked"`
+	Cited             bool           `json:"cited"`
+	CreatedAt         time.Time      `json:"created_at"`
+}
+
 type RetrievalRequest struct {
-	ID           uuid.UUID       `json:"id"`
-	KbID         uuid.UUID       `json:"kb_id"`
-	Query        string          `json:"query"`
-	Filters      json.RawMessage `json:"filters"`
-	TopK         int32           `json:"top_k"`
-	HybridWeight float64         `json:"hybrid_weight"`
-	ResultCount  int32           `json:"result_count"`
-	LatencyMs    int64           `json:"latency_ms"`
-	EmptyResult  bool            `json:"empty_result"`
-	CreatedAt    time.Time       `json:"created_at"`
+	ID                 uuid.UUID       `json:"id"`
+	KbID               uuid.UUID       `json:"kb_id"`
+	Query              string          `json:"query"`
+	Filters            json.RawMessage `json:"filters"`
+	TopK               int32           `json:"top_k"`
+	HybridWeight       float64         `json:"hybrid_weight"`
+	ResultCount        int32           `json:"result_count"`
+	LatencyMs          int64           `json:"latency_ms"`
+	EmptyResult        bool            `json:"empty_result"`
+	CreatedAt          time.Time       `json:"created_at"`
+	RankedCandidates   json.RawMessage `json:"ranked_candidates"`
+	FederatedRequestID uuid.NullUUID   `json:"federated_request_id"`
+	FusionMethod       sql.NullString  `json:"fusion_method"`
+	EmptyReason        sql.NullString  `json:"empty_reason"`
+	RetrievalProfile   sql.NullString  `json:"retrieval_profile"`
 }
 
 type RetrievalResult struct {
-	ID                 uuid.UUID `json:"id"`
-	RetrievalRequestID uuid.UUID `json:"retrieval_request_id"`
-	ChunkID            uuid.UUID `json:"chunk_id"`
-	Rank               int32     `json:"rank"`
-	SemanticScore      float64   `json:"semantic_score"`
-	LexicalScore       float64   `json:"lexical_score"`
-	FinalScore         float64   `json:"final_score"`
-	CreatedAt          time.Time `json:"created_at"`
+	ID                 uuid.UUID       `json:"id"`
+	RetrievalRequestID uuid.UUID       `json:"retrieval_request_id"`
+	ChunkID            uuid.UUID       `json:"chunk_id"`
+	Rank               int32           `json:"rank"`
+	SemanticScore      float64         `json:"semantic_score"`
+	LexicalScore       float64         `json:"lexical_score"`
+	FinalScore         float64         `json:"final_score"`
+	CreatedAt          time.Time       `json:"created_at"`
+	SemanticRank       sql.NullInt32   `json:"semantic_rank"`
+	LexicalRank        sql.NullInt32   `json:"lexical_rank"`
+	RerankScore        sql.NullFloat64 `json:"rerank_score"`
 }
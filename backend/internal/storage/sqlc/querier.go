@@ -13,24 +13,38 @@ import (
 type Querier interface {
 	ActivateDocumentVersion(ctx context.Context, id uuid.UUID) error
 	DeleteKnowledgeBase(ctx context.Context, id uuid.UUID) (int64, error)
+	DeleteTunedSemanticWeight(ctx context.Context, arg DeleteTunedSemanticWeightParams) (int64, error)
+	DeleteTunedSemanticWeights(ctx context.Context, arg DeleteTunedSemanticWeightsParams) (int64, error)
 	GetChunksWithDocuments(ctx context.Context, chunkIds []uuid.UUID) ([]GetChunksWithDocumentsRow, error)
 	GetDocumentByKBPath(ctx context.Context, arg GetDocumentByKBPathParams) (Document, error)
+	GetFeedbackSummary(ctx context.Context, arg GetFeedbackSummaryParams) (GetFeedbackSummaryRow, error)
 	GetKnowledgeBase(ctx context.Context, id uuid.UUID) (KnowledgeBasis, error)
+	GetRetrievalRequestRanking(ctx context.Context, arg GetRetrievalRequestRankingParams) (GetRetrievalRequestRankingRow, error)
 	HasEmbedding(ctx context.Context, arg HasEmbeddingParams) (int32, error)
 	InsertChunk(ctx context.Context, arg InsertChunkParams) (Chunk, error)
 	InsertDocument(ctx context.Context, arg InsertDocumentParams) (Document, error)
 	InsertDocumentVersion(ctx context.Context, arg InsertDocumentVersionParams) (DocumentVersion, error)
 	InsertEmbedding(ctx context.Context, arg InsertEmbeddingParams) error
 	InsertKnowledgeBase(ctx context.Context, arg InsertKnowledgeBaseParams) (KnowledgeBasis, error)
+	InsertRetrievalFeedback(ctx context.Context, arg InsertRetrievalFeedbackParams) (int64, error)
 	InsertRetrievalRequest(ctx context.Context, arg InsertRetrievalRequestParams) (RetrievalRequest, error)
 	InsertRetrievalResult(ctx context.Context, arg InsertRetrievalResultParams) error
+	ListChunkFeedback(ctx context.Context, arg ListChunkFeedbackParams) ([]ListChunkFeedbackRow, error)
 	ListKnowledgeBases(ctx context.Context) ([]KnowledgeBasis, error)
-	SearchLexical(ctx context.Context, arg SearchLexicalParams) ([]SearchLexicalRow, error)
-	SearchSemantic(ctx context.Context, arg SearchSemanticParams) ([]SearchSemanticRow, error)
+	ListLatencyPercentiles(ctx context.Context, arg ListLatencyPercentilesParams) ([]ListLatencyPercentilesRow, error)
+	ListMostRetrievedDocuments(ctx context.Context, arg ListMostRetrievedDocumentsParams) ([]ListMostRetrievedDocumentsRow, error)
+	ListNeverRetrievedDocuments(ctx context.Context, arg ListNeverRetrievedDocumentsParams) ([]ListNeverRetrievedDocumentsRow, error)
+	ListProfileDistribution(ctx context.Context, arg ListProfileDistributionParams) ([]ListProfileDistributionRow, error)
+	ListSemanticWeightDistribution(ctx context.Context, arg ListSemanticWeightDistributionParams) ([]ListSemanticWeightDistributionRow, error)
+	ListTopQueries(ctx context.Context, arg ListTopQueriesParams) ([]ListTopQueriesRow, error)
+	ListTuningSamples(ctx context.Context, arg ListTuningSamplesParams) ([]ListTuningSamplesRow, error)
+	ListZeroResultQueries(ctx context.Context, arg ListZeroResultQueriesParams) ([]ListZeroResultQueriesRow, error)
+	SetTunedSemanticWeight(ctx context.Context, arg SetTunedSemanticWeightParams) (int64, error)
 	UpdateDocument(ctx context.Context, arg UpdateDocumentParams) (Document, error)
 	UpdateDocumentVersionStatus(ctx context.Context, arg UpdateDocumentVersionStatusParams) error
 	UpdateKnowledgeBase(ctx context.Context, arg UpdateKnowledgeBaseParams) (KnowledgeBasis, error)
 	UpdateRetrievalRequest(ctx context.Context, arg UpdateRetrievalRequestParams) error
+	UpdateRetrievalRequestRanking(ctx context.Context, arg UpdateRetrievalRequestRankingParams) error
 }
 
 var _ Querier = (*Queries)(nil)
@@ -13,12 +13,12 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
-	pgvector "github.com/pgvector/pgvector-go"
 )
 
 const getChunksWithDocuments = `-- name: GetChunksWithDocuments :many
 SELECT
     c.id AS chunk_id,
+    c.kb_id,
     c.document_version_id,
     c.sequence_number,
     c.content,
@@ -38,6 +38,7 @@ WHERE c.id = ANY($1::uuid[])
 
 type GetChunksWithDocumentsRow struct {
 	ChunkID           uuid.UUID       `json:"chunk_id"`
+	KbID              uuid.UUID       `json:"kb_id"`
 	DocumentVersionID uuid.UUID       `json:"document_version_id"`
 	SequenceNumber    int32           `json:"sequence_number"`
 	Content           string          `json:"content"`
@@ -62,6 +63,7 @@ func (q *Queries) GetChunksWithDocuments(ctx context.Context, chunkIds []uuid.UU
 		var i GetChunksWithDocumentsRow
 		if err := rows.Scan(
 			&i.ChunkID,
+			&i.KbID,
 			&i.DocumentVersionID,
 			&i.SequenceNumber,
 			&i.Content,
@@ -87,6 +89,50 @@ func (q *Queries) GetChunksWithDocuments(ctx context.Context, chunkIds []uuid.UU
 	return items, nil
 }
 
+const getRetrievalRequestRanking = `-- name: GetRetrievalRequestRanking :one
+SELECT
+    id,
+    kb_id,
+    query,
+    top_k,
+    hybrid_weight,
+    created_at,
+    ranked_candidates
+FROM retrieval_requests
+WHERE id = $1
+  AND kb_id = $2
+`
+
+type GetRetrievalRequestRankingParams struct {
+	ID   uuid.UUID `json:"id"`
+	KbID uuid.UUID `json:"kb_id"`
+}
+
+type GetRetrievalRequestRankingRow struct {
+	ID               uuid.UUID       `json:"id"`
+	KbID             uuid.UUID       `json:"kb_id"`
+	Query            string          `json:"query"`
+	TopK             int32           `json:"top_k"`
+	HybridWeight     float64         `json:"hybrid_weight"`
+	CreatedAt        time.Time       `json:"created_at"`
+	RankedCandidates json.RawMessage `json:"ranked_candidates"`
+}
+
+func (q *Queries) GetRetrievalRequestRanking(ctx context.Context, arg GetRetrievalRequestRankingParams) (GetRetrievalRequestRankingRow, error) {
+	row := q.db.QueryRowContext(ctx, getRetrievalRequestRanking, arg.ID, arg.KbID)
+	var i GetRetrievalRequestRankingRow
+	err := row.Scan(
+		&i.ID,
+		&i.KbID,
+		&i.Query,
+		&i.TopK,
+		&i.HybridWeight,
+		&i.CreatedAt,
+		&i.RankedCandidates,
+	)
+	return i, err
+}
+
 const insertRetrievalRequest = `-- name: InsertRetrievalRequest :one
 INSERT INTO retrieval_requests (
     id,
@@ -98,7 +144,10 @@ INSERT INTO retrieval_requests (
     result_count,
     latency_ms,
     empty_result,
-    created_at
+    created_at,
+    federated_request_id,
+    fusion_method,
+    retrieval_profile
 ) VALUES (
     $1,
     $2,
@@ -109,22 +158,28 @@ INSERT INTO retrieval_requests (
     $7,
     $8,
     $9,
-    $10
+    $10,
+    $11,
+    $12,
+    $13
 )
-RETURNING id, kb_id, query, filters, top_k, hybrid_weight, result_count, latency_ms, empty_result, created_at
+RETURNING id, kb_id, query, filters, top_k, hybrid_weight, result_count, latency_ms, empty_result, created_at, ranked_candidates, federated_request_id, fusion_method, empty_reason, retrieval_profile
 `
 
 type InsertRetrievalRequestParams struct {
-	ID           uuid.UUID       `json:"id"`
-	KbID         uuid.UUID       `json:"kb_id"`
-	Query        string          `json:"query"`
-	Filters      json.RawMessage `json:"filters"`
-	TopK         int32           `json:"top_k"`
-	HybridWeight float64         `json:"hybrid_weight"`
-	ResultCount  int32           `json:"result_count"`
-	LatencyMs    int64           `json:"latency_ms"`
-	EmptyResult  bool            `json:"empty_result"`
-	CreatedAt    time.Time       `json:"created_at"`
+	ID                 uuid.UUID       `json:"id"`
+	KbID               uuid.UUID       `json:"kb_id"`
+	Query              string          `json:"query"`
+	Filters            json.RawMessage `json:"filters"`
+	TopK               int32           `json:"top_k"`
+	HybridWeight       float64         `json:"hybrid_weight"`
+	ResultCount        int32           `json:"result_count"`
+	LatencyMs          int64           `json:"latency_ms"`
+	EmptyResult        bool            `json:"empty_result"`
+	CreatedAt          time.Time       `json:"created_at"`
+	FederatedRequestID uuid.NullUUID   `json:"federated_request_id"`
+	FusionMethod       sql.NullString  `json:"fusion_method"`
+	RetrievalProfile   sql.NullString  `json:"retrieval_profile"`
 }
 
 func (q *Queries) InsertRetrievalRequest(ctx context.Context, arg InsertRetrievalRequestParams) (RetrievalRequest, error) {
@@ -139,6 +194,9 @@ func (q *Queries) InsertRetrievalRequest(ctx context.Context, arg InsertRetrieva
 		arg.LatencyMs,
 		arg.EmptyResult,
 		arg.CreatedAt,
+		arg.FederatedRequestID,
+		arg.FusionMethod,
+		arg.RetrievalProfile,
 	)
 	var i RetrievalRequest
 	err := row.Scan(
@@ -152,6 +210,11 @@ func (q *Queries) InsertRetrievalRequest(ctx context.Context, arg InsertRetrieva
 		&i.LatencyMs,
 		&i.EmptyResult,
 		&i.CreatedAt,
+		&i.RankedCandidates,
+		&i.FederatedRequestID,
+		&i.FusionMethod,
+		&i.EmptyReason,
+		&i.RetrievalProfile,
 	)
 	return i, err
 }
@@ -165,6 +228,9 @@ INSERT INTO retrieval_results (
     semantic_score,
     lexical_score,
     final_score,
+    rerank_score,
+    semantic_rank,
+    lexical_rank,
     created_at
 ) VALUES (
     $1,
@@ -174,19 +240,26 @@ INSERT INTO retrieval_results (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11
 )
+ON CONFLICT (retrieval_request_id, rank) DO NOTHING
 `
 
 type InsertRetrievalResultParams struct {
-	ID                 uuid.UUID `json:"id"`
-	RetrievalRequestID uuid.UUID `json:"retrieval_request_id"`
-	ChunkID            uuid.UUID `json:"chunk_id"`
-	Rank               int32     `json:"rank"`
-	SemanticScore      float64   `json:"semantic_score"`
-	LexicalScore       float64   `json:"lexical_score"`
-	FinalScore         float64   `json:"final_score"`
-	CreatedAt          time.Time `json:"created_at"`
+	ID                 uuid.UUID       `json:"id"`
+	RetrievalRequestID uuid.UUID       `json:"retrieval_request_id"`
+	ChunkID            uuid.UUID       `json:"chunk_id"`
+	Rank               int32           `json:"rank"`
+	SemanticScore      float64         `json:"semantic_score"`
+	LexicalScore       float64         `json:"lexical_score"`
+	FinalScore         float64         `json:"final_score"`
+	RerankScore        sql.NullFloat64 `json:"rerank_score"`
+	SemanticRank       sql.NullInt32   `json:"semantic_rank"`
+	LexicalRank        sql.NullInt32   `json:"lexical_rank"`
+	CreatedAt          time.Time       `json:"created_at"`
 }
 
 func (q *Queries) InsertRetrievalResult(ctx context.Context, arg InsertRetrievalResultParams) error {
@@ -198,164 +271,29 @@ func (q *Queries) InsertRetrievalResult(ctx context.Context, arg InsertRetrieval
 		arg.SemanticScore,
 		arg.LexicalScore,
 		arg.FinalScore,
+		arg.RerankScore,
+		arg.SemanticRank,
+		arg.LexicalRank,
 		arg.CreatedAt,
 	)
 	return err
 }
 
-const searchLexical = `-- name: SearchLexical :many
-SELECT
-    c.id AS chunk_id,
-    ts_rank(to_tsvector('english', c.content), plainto_tsquery('english', $1)) AS lexical_score
-FROM chunks c
-JOIN document_versions dv ON c.document_version_id = dv.id
-JOIN documents d ON dv.document_id = d.id
-WHERE dv.is_active = true
-  AND c.kb_id = $2
-  AND to_tsvector('english', c.content) @@ plainto_tsquery('english', $1)
-  AND ($3::text IS NULL OR d.document_type = $3)
-  AND ($4::text IS NULL OR d.path LIKE $4)
-  AND ($5::text IS NULL OR d.source_metadata ->> 'source' = $5)
-  AND ($6::jsonb = '{}'::jsonb OR d.source_metadata @> $6::jsonb)
-  AND ($7::timestamptz IS NULL OR dv.created_at >= $7)
-  AND ($8::timestamptz IS NULL OR dv.created_at <= $8)
-ORDER BY ts_rank(to_tsvector('english', c.content), plainto_tsquery('english', $1)) DESC
-LIMIT $9
-`
-
-type SearchLexicalParams struct {
-	Query         string          `json:"query"`
-	KbID          uuid.UUID       `json:"kb_id"`
-	DocumentType  sql.NullString  `json:"document_type"`
-	PathPrefix    sql.NullString  `json:"path_prefix"`
-	Source        sql.NullString  `json:"source"`
-	Tags          json.RawMessage `json:"tags"`
-	CreatedAfter  sql.NullTime    `json:"created_after"`
-	CreatedBefore sql.NullTime    `json:"created_before"`
-	Limit         int32           `json:"limit"`
-}
-
-type SearchLexicalRow struct {
-	ChunkID      uuid.UUID `json:"chunk_id"`
-	LexicalScore float32   `json:"lexical_score"`
-}
-
-func (q *Queries) SearchLexical(ctx context.Context, arg SearchLexicalParams) ([]SearchLexicalRow, error) {
-	rows, err := q.db.QueryContext(ctx, searchLexical,
-		arg.Query,
-		arg.KbID,
-		arg.DocumentType,
-		arg.PathPrefix,
-		arg.Source,
-		arg.Tags,
-		arg.CreatedAfter,
-		arg.CreatedBefore,
-		arg.Limit,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []SearchLexicalRow
-	for rows.Next() {
-		var i SearchLexicalRow
-		if err := rows.Scan(&i.ChunkID, &i.LexicalScore); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
-}
-
-const searchSemantic = `-- name: SearchSemantic :many
-SELECT
-    c.id AS chunk_id,
-    CAST(1.0 - (e.embedding_vector <=> $1::vector) AS double precision) AS semantic_score
-FROM chunks c
-JOIN embeddings e ON c.embedding_id = e.id
-JOIN document_versions dv ON c.document_version_id = dv.id
-JOIN documents d ON dv.document_id = d.id
-WHERE dv.is_active = true
-  AND c.kb_id = $2
-  AND ($3::text IS NULL OR d.document_type = $3)
-  AND ($4::text IS NULL OR d.path LIKE $4)
-  AND ($5::text IS NULL OR d.source_metadata ->> 'source' = $5)
-  AND ($6::jsonb = '{}'::jsonb OR d.source_metadata @> $6::jsonb)
-  AND ($7::timestamptz IS NULL OR dv.created_at >= $7)
-  AND ($8::timestamptz IS NULL OR dv.created_at <= $8)
-ORDER BY e.embedding_vector <=> $1::vector
-LIMIT $9
-`
-
-type SearchSemanticParams struct {
-	QueryVector   pgvector.Vector `json:"query_vector"`
-	KbID          uuid.UUID       `json:"kb_id"`
-	DocumentType  sql.NullString  `json:"document_type"`
-	PathPrefix    sql.NullString  `json:"path_prefix"`
-	Source        sql.NullString  `json:"source"`
-	Tags          json.RawMessage `json:"tags"`
-	CreatedAfter  sql.NullTime    `json:"created_after"`
-	CreatedBefore sql.NullTime    `json:"created_before"`
-	Limit         int32           `json:"limit"`
-}
-
-type SearchSemanticRow struct {
-	ChunkID       uuid.UUID `json:"chunk_id"`
-	SemanticScore float64   `json:"semantic_score"`
-}
-
-func (q *Queries) SearchSemantic(ctx context.Context, arg SearchSemanticParams) ([]SearchSemanticRow, error) {
-	rows, err := q.db.QueryContext(ctx, searchSemantic,
-		arg.QueryVector,
-		arg.KbID,
-		arg.DocumentType,
-		arg.PathPrefix,
-		arg.Source,
-		arg.Tags,
-		arg.CreatedAfter,
-		arg.CreatedBefore,
-		arg.Limit,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []SearchSemanticRow
-	for rows.Next() {
-		var i SearchSemanticRow
-		if err := rows.Scan(&i.ChunkID, &i.SemanticScore); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
-}
-
 const updateRetrievalRequest = `-- name: UpdateRetrievalRequest :exec
 UPDATE retrieval_requests
 SET result_count = $2,
     latency_ms = $3,
-    empty_result = $4
+    empty_result = $4,
+    empty_reason = $5
 WHERE id = $1
 `
 
 type UpdateRetrievalRequestParams struct {
-	ID          uuid.UUID `json:"id"`
-	ResultCount int32     `json:"result_count"`
-	LatencyMs   int64     `json:"latency_ms"`
-	EmptyResult bool      `json:"empty_result"`
+	ID          uuid.UUID      `json:"id"`
+	ResultCount int32          `json:"result_count"`
+	LatencyMs   int64          `json:"latency_ms"`
+	EmptyResult bool           `json:"empty_result"`
+	EmptyReason sql.NullString `json:"empty_reason"`
 }
 
 func (q *Queries) UpdateRetrievalRequest(ctx context.Context, arg UpdateRetrievalRequestParams) error {
@@ -364,6 +302,23 @@ func (q *Queries) UpdateRetrievalRequest(ctx context.Context, arg UpdateRetrieva
 		arg.ResultCount,
 		arg.LatencyMs,
 		arg.EmptyResult,
+		arg.EmptyReason,
 	)
 	return err
 }
+
+const updateRetrievalRequestRanking = `-- name: UpdateRetrievalRequestRanking :exec
+UPDATE retrieval_requests
+SET ranked_candidates = $2
+WHERE id = $1
+`
+
+type UpdateRetrievalRequestRankingParams struct {
+	ID               uuid.UUID       `json:"id"`
+	RankedCandidates json.RawMessage `json:"ranked_candidates"`
+}
+
+func (q *Queries) UpdateRetrievalRequestRanking(ctx context.Context, arg UpdateRetrievalRequestRankingParams) error {
+	_, err := q.db.ExecContext(ctx, updateRetrievalRequestRanking, arg.ID, arg.RankedCandidates)
+	return err
+}
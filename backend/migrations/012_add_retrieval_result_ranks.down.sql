@@ -0,0 +1,3 @@
+ALTER TABLE retrieval_results
+    DROP COLUMN IF EXISTS lexical_rank,
+    DROP COLUMN IF EXISTS semantic_rank;
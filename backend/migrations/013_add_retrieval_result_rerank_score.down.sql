@@ -0,0 +1,2 @@
+ALTER TABLE retrieval_results
+    DROP COLUMN IF EXISTS rerank_score;
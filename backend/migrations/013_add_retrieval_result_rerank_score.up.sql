@@ -0,0 +1,2 @@
+ALTER TABLE retrieval_results
+    ADD COLUMN rerank_score double precision;
@@ -0,0 +1,2 @@
+ALTER TABLE retrieval_requests
+    DROP COLUMN IF EXISTS ranked_candidates;
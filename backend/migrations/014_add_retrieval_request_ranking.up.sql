@@ -0,0 +1,2 @@
+ALTER TABLE retrieval_requests
+    ADD COLUMN ranked_candidates jsonb;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS retrieval_requests_federated_request_id_idx;
+
+ALTER TABLE retrieval_requests
+    DROP COLUMN IF EXISTS federated_request_id;
@@ -0,0 +1,6 @@
+ALTER TABLE retrieval_requests
+    ADD COLUMN federated_request_id uuid;
+
+CREATE INDEX retrieval_requests_federated_request_id_idx
+    ON retrieval_requests (federated_request_id)
+    WHERE federated_request_id IS NOT NULL;
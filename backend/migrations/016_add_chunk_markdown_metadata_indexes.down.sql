@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS chunks_breadcrumb_trgm_idx;
+DROP INDEX IF EXISTS chunks_breadcrumb_prefix_idx;
+DROP INDEX IF EXISTS chunks_frontmatter_gin_idx;
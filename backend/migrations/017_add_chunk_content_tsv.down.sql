@@ -0,0 +1,15 @@
+DROP TRIGGER IF EXISTS knowledge_bases_rebuild_search_config ON knowledge_bases;
+DROP FUNCTION IF EXISTS rebuild_chunk_search_config();
+
+DROP TRIGGER IF EXISTS chunks_set_search_config ON chunks;
+DROP FUNCTION IF EXISTS set_chunk_search_config();
+
+DROP INDEX IF EXISTS chunks_content_tsv_idx;
+
+ALTER TABLE chunks
+    DROP COLUMN IF EXISTS content_tsv,
+    DROP COLUMN IF EXISTS search_config;
+
+CREATE INDEX chunks_content_tsv_idx ON chunks USING GIN (to_tsvector('english', content));
+
+DROP FUNCTION IF EXISTS kb_text_search_config(uuid);
@@ -0,0 +1,23 @@
+CREATE OR REPLACE FUNCTION rebuild_chunk_search_config()
+RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    config regconfig := kb_text_search_config(NEW.id);
+BEGIN
+    UPDATE chunks
+    SET search_config = config
+    WHERE kb_id = NEW.id
+      AND search_config <> config;
+
+    RETURN NEW;
+END;
+$$;
+
+DROP FUNCTION IF EXISTS rebuild_kb_lexical_stats(uuid);
+DROP FUNCTION IF EXISTS apply_chunk_lexical_stats(uuid[], integer);
+
+DROP TABLE IF EXISTS kb_term_stats;
+DROP TABLE IF EXISTS kb_lexical_stats;
+
+DROP FUNCTION IF EXISTS tsvector_token_count(tsvector);
@@ -0,0 +1,99 @@
+-- Number of tokens in a tsvector, counting each position of each lexeme.
+-- Lexemes stripped of positions count once.
+CREATE OR REPLACE FUNCTION tsvector_token_count(vector tsvector)
+RETURNS integer
+LANGUAGE sql
+IMMUTABLE
+AS $$
+    SELECT COALESCE(sum(COALESCE(array_length(positions, 1), 1)), 0)::integer
+    FROM unnest(vector);
+$$;
+
+-- Per knowledge base corpus statistics for BM25 scoring.
+CREATE TABLE kb_lexical_stats (
+    kb_id uuid PRIMARY KEY REFERENCES knowledge_bases(id) ON DELETE CASCADE,
+    chunk_count bigint NOT NULL DEFAULT 0,
+    total_length bigint NOT NULL DEFAULT 0,
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Number of chunks in a knowledge base containing each lexeme.
+CREATE TABLE kb_term_stats (
+    kb_id uuid NOT NULL REFERENCES knowledge_bases(id) ON DELETE CASCADE,
+    term text NOT NULL,
+    doc_freq bigint NOT NULL,
+    PRIMARY KEY (kb_id, term)
+);
+
+-- Adds (direction = 1) or removes (direction = -1) the given chunks from
+-- their knowledge bases' lexical statistics. Removal must run before the
+-- chunks are deleted.
+CREATE OR REPLACE FUNCTION apply_chunk_lexical_stats(chunk_ids uuid[], direction integer)
+RETURNS void
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    INSERT INTO kb_lexical_stats AS s (kb_id, chunk_count, total_length, updated_at)
+    SELECT c.kb_id,
+           direction * count(*),
+           direction * COALESCE(sum(tsvector_token_count(c.content_tsv)), 0),
+           now()
+    FROM chunks c
+    WHERE c.id = ANY(chunk_ids)
+    GROUP BY c.kb_id
+    ON CONFLICT (kb_id) DO UPDATE
+    SET chunk_count = GREATEST(s.chunk_count + EXCLUDED.chunk_count, 0),
+        total_length = GREATEST(s.total_length + EXCLUDED.total_length, 0),
+        updated_at = EXCLUDED.updated_at;
+
+    INSERT INTO kb_term_stats AS t (kb_id, term, doc_freq)
+    SELECT c.kb_id, l.lexeme, direction * count(*)
+    FROM chunks c
+    CROSS JOIN LATERAL unnest(c.content_tsv) AS l
+    WHERE c.id = ANY(chunk_ids)
+    GROUP BY c.kb_id, l.lexeme
+    ON CONFLICT (kb_id, term) DO UPDATE
+    SET doc_freq = t.doc_freq + EXCLUDED.doc_freq;
+
+    IF direction < 0 THEN
+        DELETE FROM kb_term_stats t
+        USING (SELECT DISTINCT kb_id FROM chunks WHERE id = ANY(chunk_ids)) affected
+        WHERE t.kb_id = affected.kb_id
+          AND t.doc_freq <= 0;
+    END IF;
+END;
+$$;
+
+-- Recomputes a knowledge base's lexical statistics from its chunks.
+CREATE OR REPLACE FUNCTION rebuild_kb_lexical_stats(kb uuid)
+RETURNS void
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    DELETE FROM kb_term_stats WHERE kb_id = kb;
+    DELETE FROM kb_lexical_stats WHERE kb_id = kb;
+    PERFORM apply_chunk_lexical_stats(ARRAY(SELECT id FROM chunks WHERE kb_id = kb), 1);
+END;
+$$;
+
+SELECT rebuild_kb_lexical_stats(id) FROM knowledge_bases;
+
+-- A language change re-tokenizes every chunk, so the statistics are rebuilt
+-- along with the vectors.
+CREATE OR REPLACE FUNCTION rebuild_chunk_search_config()
+RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    config regconfig := kb_text_search_config(NEW.id);
+BEGIN
+    UPDATE chunks
+    SET search_config = config
+    WHERE kb_id = NEW.id
+      AND search_config <> config;
+
+    PERFORM rebuild_kb_lexical_stats(NEW.id);
+
+    RETURN NEW;
+END;
+$$;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS kb_score_calibrations;
+DROP TABLE IF EXISTS calibration_judgments;
+
+ALTER TABLE retrieval_requests
+    DROP COLUMN IF EXISTS empty_reason,
+    DROP COLUMN IF EXISTS fusion_method;
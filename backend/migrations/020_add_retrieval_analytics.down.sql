@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS retrieval_results_request_id_chunk_id_idx;
+DROP INDEX IF EXISTS retrieval_requests_kb_id_empty_created_at_idx;
+DROP INDEX IF EXISTS retrieval_requests_kb_id_created_at_idx;
+
+ALTER TABLE retrieval_requests
+    DROP COLUMN IF EXISTS retrieval_profile;
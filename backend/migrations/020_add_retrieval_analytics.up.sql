@@ -0,0 +1,13 @@
+ALTER TABLE retrieval_requests
+    ADD COLUMN retrieval_profile text;
+
+-- Analytics read one knowledge base's requests over a time range.
+CREATE INDEX retrieval_requests_kb_id_created_at_idx
+    ON retrieval_requests (kb_id, created_at);
+
+CREATE INDEX retrieval_requests_kb_id_empty_created_at_idx
+    ON retrieval_requests (kb_id, created_at)
+    WHERE empty_result = true;
+
+CREATE INDEX retrieval_results_request_id_chunk_id_idx
+    ON retrieval_results (retrieval_request_id, chunk_id);
@@ -0,0 +1,21 @@
+-- Relevance labels users give to logged results: thumbs up (1) or down (-1),
+-- a 0-3 grade, and whether the result was clicked or cited.
+CREATE TABLE retrieval_feedback (
+    id uuid PRIMARY KEY,
+    kb_id uuid NOT NULL REFERENCES knowledge_bases(id) ON DELETE CASCADE,
+    retrieval_result_id uuid NOT NULL REFERENCES retrieval_results(id) ON DELETE CASCADE,
+    user_id text,
+    thumbs smallint,
+    grade smallint,
+    clicked boolean NOT NULL DEFAULT false,
+    cited boolean NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT retrieval_feedback_thumbs_check CHECK (thumbs IN (-1, 1)),
+    CONSTRAINT retrieval_feedback_grade_check CHECK (grade BETWEEN 0 AND 3)
+);
+
+CREATE INDEX retrieval_feedback_kb_id_created_at_idx
+    ON retrieval_feedback (kb_id, created_at);
+
+CREATE INDEX retrieval_feedback_result_id_idx
+    ON retrieval_feedback (retrieval_result_id);
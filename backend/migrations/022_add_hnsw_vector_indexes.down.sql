@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS embeddings_384_vector_hnsw_idx;
+DROP INDEX IF EXISTS embeddings_1536_vector_hnsw_idx;
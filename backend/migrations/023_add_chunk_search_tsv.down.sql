@@ -0,0 +1,13 @@
+DROP TRIGGER IF EXISTS documents_rebuild_search_tsv ON documents;
+DROP FUNCTION IF EXISTS rebuild_document_search_tsv();
+
+DROP TRIGGER IF EXISTS chunks_set_search_tsv ON chunks;
+DROP FUNCTION IF EXISTS set_chunk_search_tsv();
+
+DROP INDEX IF EXISTS chunks_search_tsv_idx;
+
+ALTER TABLE chunks
+    DROP COLUMN IF EXISTS search_tsv;
+
+DROP FUNCTION IF EXISTS chunk_heading_text(jsonb);
+DROP FUNCTION IF EXISTS chunk_search_tsv(regconfig, text, text, text, text);
@@ -0,0 +1,82 @@
+-- Weighted tsvector over a chunk's document title (A), markdown breadcrumb or
+-- section title (B), document path (C) and content (D). Lexical search ranks
+-- with it when a request or knowledge base sets field weights. Path
+-- separators become spaces so each path segment is its own lexeme.
+CREATE OR REPLACE FUNCTION chunk_search_tsv(
+    config regconfig,
+    title text,
+    breadcrumb text,
+    path text,
+    content text
+)
+RETURNS tsvector
+LANGUAGE sql
+IMMUTABLE
+AS $$
+    SELECT setweight(to_tsvector(config, COALESCE(title, '')), 'A')
+        || setweight(to_tsvector(config, COALESCE(breadcrumb, '')), 'B')
+        || setweight(to_tsvector(config, regexp_replace(COALESCE(path, ''), '[/._-]+', ' ', 'g')), 'C')
+        || setweight(to_tsvector(config, content), 'D');
+$$;
+
+CREATE OR REPLACE FUNCTION chunk_heading_text(metadata jsonb)
+RETURNS text
+LANGUAGE sql
+IMMUTABLE
+AS $$
+    SELECT COALESCE(NULLIF(metadata ->> 'breadcrumb', ''), metadata ->> 'section_title');
+$$;
+
+ALTER TABLE chunks
+    ADD COLUMN search_tsv tsvector;
+
+UPDATE chunks c
+SET search_tsv = chunk_search_tsv(c.search_config, d.title, chunk_heading_text(c.metadata), d.path, c.content)
+FROM document_versions dv
+JOIN documents d ON dv.document_id = d.id
+WHERE c.document_version_id = dv.id;
+
+CREATE INDEX chunks_search_tsv_idx ON chunks USING GIN (search_tsv);
+
+-- Runs after chunks_set_search_config, since triggers on the same event fire
+-- in name order, so search_config is already resolved.
+CREATE OR REPLACE FUNCTION set_chunk_search_tsv()
+RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    SELECT chunk_search_tsv(NEW.search_config, d.title, chunk_heading_text(NEW.metadata), d.path, NEW.content)
+    INTO NEW.search_tsv
+    FROM document_versions dv
+    JOIN documents d ON dv.document_id = d.id
+    WHERE dv.id = NEW.document_version_id;
+
+    RETURN NEW;
+END;
+$$;
+
+CREATE TRIGGER chunks_set_search_tsv
+BEFORE INSERT OR UPDATE OF content, metadata, search_config, document_version_id ON chunks
+FOR EACH ROW
+EXECUTE FUNCTION set_chunk_search_tsv();
+
+CREATE OR REPLACE FUNCTION rebuild_document_search_tsv()
+RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    UPDATE chunks c
+    SET search_tsv = chunk_search_tsv(c.search_config, NEW.title, chunk_heading_text(c.metadata), NEW.path, c.content)
+    FROM document_versions dv
+    WHERE c.document_version_id = dv.id
+      AND dv.document_id = NEW.id;
+
+    RETURN NEW;
+END;
+$$;
+
+CREATE TRIGGER documents_rebuild_search_tsv
+AFTER UPDATE OF title, path ON documents
+FOR EACH ROW
+WHEN (OLD.title IS DISTINCT FROM NEW.title OR OLD.path IS DISTINCT FROM NEW.path)
+EXECUTE FUNCTION rebuild_document_search_tsv();
@@ -3,7 +3,7 @@ from __future__ import annotations
 from typing import List, Optional
 from fastapi import FastAPI
 from pydantic import BaseModel, Field
-from sentence_transformers import SentenceTransformer
+from sentence_transformers import CrossEncoder, SentenceTransformer
 import os
 
 MODEL_NAME = os.getenv("EMBED_MODEL", "sentence-transformers/all-MiniLM-L6-v2")
@@ -60,3 +60,32 @@ def embed(req: EmbedRequest):
     dim = len(embeddings[0]) if embeddings else 0
 
     return {"model": MODEL_NAME, "dim": dim, "embeddings": embeddings}
+
+
+RERANK_MODEL_NAME = os.getenv("RERANK_MODEL", "cross-encoder/ms-marco-MiniLM-L-6-v2")
+
+# Loaded on first use so embedding-only deployments don't pay for it.
+reranker: Optional[CrossEncoder] = None
+
+
+class RerankRequest(BaseModel):
+    query: str = Field(..., min_length=1, description="Query to score documents against")
+    documents: List[str] = Field(..., min_items=1, description="Candidate passages to score")
+
+
+class RerankResponse(BaseModel):
+    model: str
+    scores: List[float]
+
+
+@app.post("/rerank", response_model=RerankResponse)
+def rerank(req: RerankRequest):
+    global reranker
+    if reranker is None:
+        reranker = CrossEncoder(RERANK_MODEL_NAME)
+
+    # Scores stay aligned with the request order, including empty documents.
+    pairs = [(req.query, doc or "") for doc in req.documents]
+    scores = reranker.predict(pairs, batch_size=32, show_progress_bar=False)
+
+    return {"model": RERANK_MODEL_NAME, "scores": [float(s) for s in scores]}
@@ -0,0 +1,3 @@
+ALTER TABLE retrieval_results
+    ADD COLUMN semantic_rank integer,
+    ADD COLUMN lexical_rank integer;
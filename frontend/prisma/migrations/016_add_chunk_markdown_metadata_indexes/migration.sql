@@ -0,0 +1,15 @@
+-- Indexes for the section_path_prefix, frontmatter and breadcrumb_contains
+-- filters. Chunking copies a markdown chunk's breadcrumb and frontmatter into
+-- chunks.metadata only from this release on, and the frontmatter cannot be
+-- rebuilt in SQL, so chunks stored earlier have neither key and these filters
+-- skip them until their documents are uploaded again.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX chunks_frontmatter_gin_idx
+    ON chunks USING GIN ((metadata -> 'frontmatter') jsonb_path_ops);
+
+CREATE INDEX chunks_breadcrumb_prefix_idx
+    ON chunks ((metadata ->> 'breadcrumb') text_pattern_ops);
+
+CREATE INDEX chunks_breadcrumb_trgm_idx
+    ON chunks USING GIN ((metadata ->> 'breadcrumb') gin_trgm_ops);
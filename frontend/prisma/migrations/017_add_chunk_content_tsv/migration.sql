@@ -0,0 +1,72 @@
+-- Resolves a knowledge base's text search configuration from
+-- knowledge_bases.metadata ->> 'text_search_config', falling back to english
+-- when the key is missing or names an unknown configuration.
+CREATE OR REPLACE FUNCTION kb_text_search_config(kb uuid)
+RETURNS regconfig
+LANGUAGE sql
+STABLE
+AS $$
+    SELECT COALESCE(
+        (
+            SELECT cfg.oid::regconfig
+            FROM knowledge_bases k
+            JOIN pg_ts_config cfg ON cfg.cfgname = k.metadata ->> 'text_search_config'
+            WHERE k.id = kb
+            LIMIT 1
+        ),
+        'english'::regconfig
+    );
+$$;
+
+ALTER TABLE chunks
+    ADD COLUMN search_config regconfig NOT NULL DEFAULT 'english'::regconfig;
+
+UPDATE chunks
+SET search_config = kb_text_search_config(kb_id)
+WHERE kb_text_search_config(kb_id) <> 'english'::regconfig;
+
+ALTER TABLE chunks
+    ADD COLUMN content_tsv tsvector
+    GENERATED ALWAYS AS (to_tsvector(search_config, content)) STORED;
+
+DROP INDEX IF EXISTS chunks_content_tsv_idx;
+CREATE INDEX chunks_content_tsv_idx ON chunks USING GIN (content_tsv);
+
+CREATE OR REPLACE FUNCTION set_chunk_search_config()
+RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    NEW.search_config := kb_text_search_config(NEW.kb_id);
+    RETURN NEW;
+END;
+$$;
+
+CREATE TRIGGER chunks_set_search_config
+BEFORE INSERT ON chunks
+FOR EACH ROW
+EXECUTE FUNCTION set_chunk_search_config();
+
+-- Changing a knowledge base's language rewrites search_config on its chunks,
+-- which regenerates content_tsv.
+CREATE OR REPLACE FUNCTION rebuild_chunk_search_config()
+RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    config regconfig := kb_text_search_config(NEW.id);
+BEGIN
+    UPDATE chunks
+    SET search_config = config
+    WHERE kb_id = NEW.id
+      AND search_config <> config;
+
+    RETURN NEW;
+END;
+$$;
+
+CREATE TRIGGER knowledge_bases_rebuild_search_config
+AFTER UPDATE OF metadata ON knowledge_bases
+FOR EACH ROW
+WHEN (OLD.metadata ->> 'text_search_config' IS DISTINCT FROM NEW.metadata ->> 'text_search_config')
+EXECUTE FUNCTION rebuild_chunk_search_config();
@@ -0,0 +1,28 @@
+ALTER TABLE retrieval_requests
+    ADD COLUMN fusion_method text,
+    ADD COLUMN empty_reason text;
+
+-- Labelled relevance of logged results, uploaded to calibrate confidence.
+CREATE TABLE calibration_judgments (
+    id uuid PRIMARY KEY,
+    kb_id uuid NOT NULL REFERENCES knowledge_bases(id) ON DELETE CASCADE,
+    retrieval_request_id uuid NOT NULL REFERENCES retrieval_requests(id) ON DELETE CASCADE,
+    chunk_id uuid NOT NULL REFERENCES chunks(id) ON DELETE CASCADE,
+    relevant boolean NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT calibration_judgments_result_unique UNIQUE (retrieval_request_id, chunk_id)
+);
+
+CREATE INDEX calibration_judgments_kb_id_idx ON calibration_judgments (kb_id);
+
+-- Platt scaling parameters mapping a fused final score to a confidence,
+-- fitted per knowledge base and fusion method.
+CREATE TABLE kb_score_calibrations (
+    kb_id uuid NOT NULL REFERENCES knowledge_bases(id) ON DELETE CASCADE,
+    fusion_method text NOT NULL,
+    slope double precision NOT NULL,
+    intercept double precision NOT NULL,
+    sample_count integer NOT NULL,
+    fitted_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (kb_id, fusion_method)
+);
@@ -0,0 +1,12 @@
+-- HNSW indexes give good recall without training. The ivfflat indexes stay in
+-- place beside them so the planner can still choose ivfflat, where
+-- ivfflat.probes applies; rebuild them with lists sized to the loaded data.
+CREATE INDEX IF NOT EXISTS embeddings_384_vector_hnsw_idx
+    ON embeddings_384
+    USING hnsw (embedding_vector vector_cosine_ops)
+    WITH (m = 16, ef_construction = 64);
+
+CREATE INDEX IF NOT EXISTS embeddings_1536_vector_hnsw_idx
+    ON embeddings_1536
+    USING hnsw (embedding_vector vector_cosine_ops)
+    WITH (m = 16, ef_construction = 64);